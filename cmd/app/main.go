@@ -1,45 +1,236 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
+	"time"
 
+	"github.com/Salam4nder/inventory/internal/authn"
 	"github.com/Salam4nder/inventory/internal/cache"
 	"github.com/Salam4nder/inventory/internal/config"
 	"github.com/Salam4nder/inventory/internal/http"
+	"github.com/Salam4nder/inventory/internal/metrics"
 	"github.com/Salam4nder/inventory/internal/persistence"
+	"github.com/Salam4nder/inventory/internal/rpc"
+	"github.com/Salam4nder/inventory/internal/tracing"
+	"github.com/Salam4nder/inventory/pkg/auth"
 	"github.com/Salam4nder/inventory/pkg/logger"
 
 	"github.com/stimtech/go-migration"
+	"go.uber.org/zap"
 )
 
 func main() {
 	cfg, err := config.New()
 	panicOnError(err)
+	panicOnError(checkAdmin(cfg.HTTP))
 
 	logger, err := logger.New("")
 	panicOnError(err)
 
+	shutdownTracing, err := tracing.Setup(
+		context.Background(), cfg.Tracing)
+	panicOnError(err)
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			logger.Error("tracing shutdown", zap.Error(err))
+		}
+	}()
+
 	store, err := persistence.New(
 		cfg.DB, persistence.PostgresDriver)
 	panicOnError(err)
 	defer store.DB.Close()
 	logger.Info("PSQL connection established...")
 
-	migration := migration.New(
-		store.DB, logger)
-	if err := migration.Migrate(); err != nil {
-		panicOnError(err)
-	}
 	cache, err := cache.New(cfg.Cache)
 	if err != nil {
 		panicOnError(err)
 	}
 	logger.Info("Redis connection established...")
 
-	server := http.New(cfg.HTTP, store, cache, logger)
+	metrics := metrics.New()
+	panicOnError(metrics.RegisterDB(store.DB, cfg.DB.Name))
+	panicOnError(metrics.RegisterItems(store,
+		cfg.HTTP.LowStockThreshold, cfg.HTTP.ItemStatsInterval))
+	cache.Metrics = metrics
+
+	keys, err := keySet(cfg.HTTP)
+	panicOnError(err)
+	logger.Info("JWT signing key loaded...",
+		zap.String("kid", keys.SigningKey().ID),
+		zap.String("alg", keys.SigningKey().Method.Alg()))
+
+	var oidc *auth.OIDCVerifier
+	if cfg.HTTP.OIDCIssuer != "" {
+		oidc, err = oidcVerifier(cfg.HTTP)
+		panicOnError(err)
+		logger.Info("OIDC issuer discovered...",
+			zap.String("issuer", cfg.HTTP.OIDCIssuer))
+	}
+
+	authenticator := authn.New(keys, oidc, store, cache)
+	authenticator.FailOpen = cfg.HTTP.DenylistFailOpen
+
+	server := http.New(
+		cfg.HTTP, store, cache, keys, authenticator, metrics, logger)
+
+	var rpcServer *rpc.Server
+	if cfg.HTTP.GRPCPort != "" {
+		rpcServer = rpc.New(
+			cfg.HTTP, store, cache, authenticator, logger)
+	}
+
+	// Only the probes are served while the database is
+	// migrated, the service reports itself ready once it is
+	// done. Failed migrations are reported by readiness.
+	go func() {
+		if err := migrate(store, cfg.HTTP, logger); err != nil {
+			server.MigrationsFailed(err)
+			return
+		}
+		server.MigrationsDone()
+		if rpcServer != nil {
+			rpcServer.MigrationsDone()
+		}
+		logger.Info("Database migrations finished...")
+	}()
+
+	if rpcServer != nil {
+		defer shutdownRPC(rpcServer)
+
+		go func() {
+			if err := rpcServer.ListenAndServe(); err != nil {
+				logger.Fatal("grpc listen: ", zap.Error(err))
+			}
+		}()
+		logger.Info("gRPC server listening...",
+			zap.String("addr", cfg.HTTP.GRPCAddr()))
+	}
+
 	server.Start()
 }
 
+// shutdownRPC stops the gRPC server. Watch streams
+// are cancelled if they do not end in time.
+func shutdownRPC(server *rpc.Server) {
+	ctx, cancel := context.WithTimeout(
+		context.Background(), 5*time.Second)
+	defer cancel()
+
+	server.Shutdown(ctx)
+}
+
+// migrate migrates the database, configures row-level
+// security and creates the admin user.
+func migrate(
+	store *persistence.SQLDatabase,
+	cfg config.Server,
+	logger *zap.Logger) error {
+	migration := migration.New(
+		store.DB, logger)
+	if err := migration.Migrate(); err != nil {
+		return err
+	}
+
+	if err := store.ConfigureRowLevelSecurity(
+		context.Background()); err != nil {
+		return err
+	}
+
+	if cfg.AdminUsername != "" {
+		return bootstrapAdmin(store, cfg)
+	}
+
+	return nil
+}
+
+// checkAdmin refuses an admin that would be created with
+// an empty or otherwise invalid password.
+func checkAdmin(cfg config.Server) error {
+	if cfg.AdminUsername == "" {
+		return nil
+	}
+
+	if err := auth.ValidatePassword(cfg.AdminPassword); err != nil {
+		return fmt.Errorf("SERVER_ADMIN_PASSWORD: %w", err)
+	}
+
+	return nil
+}
+
+// bootstrapAdmin creates the configured admin user
+// unless a user with the same name already exists.
+func bootstrapAdmin(
+	store persistence.Storage, cfg config.Server) error {
+	ctx, cancel := context.WithTimeout(
+		context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := store.ReadUserByUsername(ctx, cfg.AdminUsername)
+	if !errors.Is(err, persistence.ErrNotFound) {
+		return err
+	}
+
+	hash, err := auth.HashPassword(cfg.AdminPassword)
+	if err != nil {
+		return err
+	}
+
+	_, err = store.CreateUser(ctx, persistence.User{
+		Username:     cfg.AdminUsername,
+		PasswordHash: hash,
+		Role:         string(auth.RoleSuperAdmin),
+	})
+
+	return err
+}
+
+// keySet returns the keys access tokens are signed with, the
+// PEM keys of the key directory if one is set and the shared
+// secret otherwise.
+func keySet(cfg config.Server) (*auth.KeySet, error) {
+	var (
+		keys *auth.KeySet
+		err  error
+	)
+
+	if cfg.JWTKeyDir != "" {
+		keys, err = auth.LoadKeySet(cfg.JWTKeyDir, cfg.JWTSigningKey)
+	} else {
+		keys, err = auth.NewHMACKeySet(cfg.JWTSecret)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	keys.SetAudience(cfg.JWTIssuer, cfg.JWTAudience)
+
+	return keys, nil
+}
+
+// oidcVerifier discovers the configured OIDC issuer.
+func oidcVerifier(cfg config.Server) (*auth.OIDCVerifier, error) {
+	groupRoles, err := auth.ParseGroupRoles(cfg.OIDCGroupRoles)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(), 10*time.Second)
+	defer cancel()
+
+	return auth.NewOIDCVerifier(ctx, auth.OIDCConfig{
+		Issuer:      cfg.OIDCIssuer,
+		Audience:    cfg.OIDCAudience,
+		GroupsClaim: cfg.OIDCGroupsClaim,
+		TenantClaim: cfg.OIDCTenantClaim,
+		GroupRoles:  groupRoles,
+	})
+}
+
 func panicOnError(err error) {
 	if err != nil {
 		log.Panic(err)
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS reservations (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    item_id uuid NOT NULL REFERENCES inventory (id) ON DELETE CASCADE,
+    quantity double precision NOT NULL CHECK (quantity > 0),
+    owner varchar(255) NOT NULL,
+    status varchar(16) NOT NULL DEFAULT 'active',
+    created_at timestamptz NOT NULL DEFAULT now(),
+    expires_at timestamptz NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS reservations_active_item_idx
+    ON reservations (item_id, expires_at) WHERE status = 'active';
@@ -0,0 +1,3 @@
+ALTER TABLE inventory ADD COLUMN IF NOT EXISTS sku varchar(64);
+
+CREATE UNIQUE INDEX IF NOT EXISTS inventory_sku_idx ON inventory (sku);
@@ -0,0 +1,7 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS inventory_name_fts_idx
+    ON inventory USING gin (to_tsvector('english', name));
+
+CREATE INDEX IF NOT EXISTS inventory_name_trgm_idx
+    ON inventory USING gin (name gin_trgm_ops);
@@ -0,0 +1,5 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role varchar(16) NOT NULL DEFAULT 'viewer';
+
+UPDATE users SET role = 'admin' WHERE admin;
+
+ALTER TABLE users DROP COLUMN IF EXISTS admin;
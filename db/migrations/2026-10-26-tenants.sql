@@ -0,0 +1,29 @@
+ALTER TABLE inventory ADD COLUMN IF NOT EXISTS tenant_id varchar(64) NOT NULL DEFAULT 'default';
+ALTER TABLE reservations ADD COLUMN IF NOT EXISTS tenant_id varchar(64) NOT NULL DEFAULT 'default';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS tenant_id varchar(64) NOT NULL DEFAULT 'default';
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS tenant_id varchar(64) NOT NULL DEFAULT 'default';
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS tenant_id varchar(64) NOT NULL DEFAULT 'default';
+
+-- SKUs are unique within a tenant only.
+DROP INDEX IF EXISTS inventory_sku_idx;
+CREATE UNIQUE INDEX IF NOT EXISTS inventory_tenant_sku_idx
+    ON inventory (tenant_id, sku);
+
+CREATE INDEX IF NOT EXISTS inventory_tenant_name_idx
+    ON inventory (tenant_id, name);
+CREATE INDEX IF NOT EXISTS reservations_tenant_idx
+    ON reservations (tenant_id);
+CREATE INDEX IF NOT EXISTS users_tenant_idx ON users (tenant_id);
+CREATE INDEX IF NOT EXISTS api_keys_tenant_idx ON api_keys (tenant_id);
+
+-- The policies only apply once row-level security is enabled,
+-- see SQLDatabase.ConfigureRowLevelSecurity.
+DROP POLICY IF EXISTS tenant_isolation ON inventory;
+CREATE POLICY tenant_isolation ON inventory
+    USING (tenant_id = current_setting('app.tenant_id', true)
+        OR current_setting('app.all_tenants', true) = 'on');
+
+DROP POLICY IF EXISTS tenant_isolation ON reservations;
+CREATE POLICY tenant_isolation ON reservations
+    USING (tenant_id = current_setting('app.tenant_id', true)
+        OR current_setting('app.all_tenants', true) = 'on');
@@ -0,0 +1,4 @@
+-- Admins of the default tenant used to manage every tenant,
+-- they keep doing so as super admins.
+UPDATE users SET role = 'superadmin'
+    WHERE role = 'admin' AND tenant_id = 'default';
@@ -0,0 +1,206 @@
+package authn
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Salam4nder/inventory/internal/cache"
+	"github.com/Salam4nder/inventory/internal/persistence"
+	"github.com/Salam4nder/inventory/pkg/auth"
+	"github.com/Salam4nder/inventory/pkg/logger"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrInvalidAPIKey is returned for unknown and
+	// expired API keys.
+	ErrInvalidAPIKey = errors.New("API key is invalid")
+	// ErrTokenRevoked is returned for access tokens
+	// on the denylist.
+	ErrTokenRevoked = errors.New("token is revoked")
+	// ErrDenylistUnavailable is returned for access tokens
+	// that cannot be checked against the denylist.
+	ErrDenylistUnavailable = errors.New("token denylist is unavailable")
+)
+
+// Storage is where the Authenticator reads API keys and
+// the users who created them from.
+type Storage interface {
+	persistence.APIKeyStorage
+	ReadUserByUsername(ctx context.Context, username string) (
+		persistence.User, error)
+}
+
+// Authenticator verifies the credentials of callers. The HTTP
+// and gRPC servers share it, so both accept the same access
+// tokens and API keys.
+type Authenticator struct {
+	// FailOpen accepts tokens while the denylist cannot be
+	// reached, which keeps the API available while the cache
+	// is down but lets revoked tokens through meanwhile.
+	FailOpen bool
+
+	keys     *auth.KeySet
+	oidc     *auth.OIDCVerifier
+	apiKeys  Storage
+	denylist cache.Denylist
+}
+
+// New returns an Authenticator that validates access tokens
+// with the keys, or with the OIDC provider if it issued them.
+// The OIDC verifier is optional.
+func New(
+	keys *auth.KeySet,
+	oidc *auth.OIDCVerifier,
+	apiKeys Storage,
+	denylist cache.Denylist) *Authenticator {
+	return &Authenticator{
+		keys:     keys,
+		oidc:     oidc,
+		apiKeys:  apiKeys,
+		denylist: denylist,
+	}
+}
+
+// Token returns the claims of a valid access token. It returns
+// ErrTokenRevoked if the token is on the denylist of its tenant,
+// ErrDenylistUnavailable if the denylist cannot be reached and
+// the validation error otherwise.
+func (a *Authenticator) Token(
+	ctx context.Context, token string) (*auth.Claims, error) {
+	var (
+		claims *auth.Claims
+		err    error
+	)
+
+	if a.oidc != nil && a.oidc.Issued(token) {
+		claims, err = a.oidc.ValidateJWT(ctx, token)
+	} else {
+		claims, err = a.keys.ValidateJWT(token)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var issuedAt time.Time
+	if claims.IssuedAt != nil {
+		issuedAt = claims.IssuedAt.Time
+	}
+
+	if err := a.checkDenylist(ctx, claims.Tenant,
+		claims.ID, claims.Subject, issuedAt); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// checkDenylist checks the denylist of the tenant for the
+// token or its subject. Unless the Authenticator fails open,
+// tokens are rejected if the denylist cannot be reached.
+func (a *Authenticator) checkDenylist(
+	ctx context.Context,
+	tenant, tokenID, subject string,
+	issuedAt time.Time) error {
+	ctx, cancel := context.WithTimeout(
+		persistence.WithTenant(ctx, tenant), time.Second)
+	defer cancel()
+
+	revoked, err := a.denylist.TokenRevoked(
+		ctx, tokenID, subject, issuedAt)
+	switch {
+	case err != nil && a.FailOpen:
+		logger.FromContext(ctx).Error(err.Error(), zap.Error(err))
+		return nil
+	case err != nil:
+		logger.FromContext(ctx).Error(err.Error(), zap.Error(err))
+		return ErrDenylistUnavailable
+	case revoked:
+		return ErrTokenRevoked
+	}
+
+	return nil
+}
+
+// APIKey returns the claims of the caller holding the given
+// API key. The key ID is the subject and its scopes are the
+// permissions. It returns ErrInvalidAPIKey for unknown keys.
+//
+// A key never grants more than the user who created it has
+// now. Keys of disabled users are invalid, keys of demoted
+// users lose the scopes the new role lacks, and keys created
+// before the tokens of the user were revoked are revoked too.
+// Keys created by users of the OIDC provider are not limited.
+func (a *Authenticator) APIKey(
+	ctx context.Context, key string) (*auth.Claims, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	apiKey, err := a.apiKeys.UseAPIKey(ctx, auth.HashAPIKey(key))
+	switch {
+	case errors.Is(err, persistence.ErrNotFound):
+		return nil, ErrInvalidAPIKey
+	case err != nil:
+		return nil, err
+	}
+
+	creator, err := a.apiKeys.ReadUserByUsername(ctx, apiKey.CreatedBy)
+	switch {
+	case errors.Is(err, persistence.ErrNotFound):
+		creator = persistence.User{}
+	case err != nil:
+		return nil, err
+	case creator.Disabled:
+		return nil, ErrInvalidAPIKey
+	default:
+		if err := a.checkDenylist(ctx, creator.TenantID, "",
+			creator.ID.String(), apiKey.CreatedAt); err != nil {
+			return nil, err
+		}
+	}
+
+	claims := &auth.Claims{
+		Username: apiKey.Name,
+		Tenant:   apiKey.TenantID,
+	}
+	claims.Subject = apiKey.ID.String()
+	if apiKey.ExpiresAt != nil {
+		claims.ExpiresAt = jwt.NewNumericDate(*apiKey.ExpiresAt)
+	}
+	for _, scope := range apiKey.Scopes {
+		permission := auth.Permission(scope)
+		if creator.ID != uuid.Nil &&
+			!auth.Role(creator.Role).Grants(permission) {
+			continue
+		}
+		claims.Permissions = append(claims.Permissions, permission)
+	}
+
+	return claims, nil
+}
+
+// Describe tells why a token was rejected.
+// Signature and key errors are not detailed.
+func Describe(err error) string {
+	switch {
+	case errors.Is(err, ErrTokenRevoked),
+		errors.Is(err, auth.ErrNoTenant):
+		return err.Error()
+	case errors.Is(err, jwt.ErrTokenMalformed):
+		return "token is malformed"
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return "token is expired"
+	case errors.Is(err, jwt.ErrTokenNotValidYet):
+		return "token is not valid yet"
+	case errors.Is(err, jwt.ErrTokenInvalidAudience):
+		return "token has invalid audience"
+	case errors.Is(err, jwt.ErrTokenInvalidIssuer):
+		return "token has invalid issuer"
+	default:
+		return "token is invalid"
+	}
+}
@@ -0,0 +1,248 @@
+package authn
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Salam4nder/inventory/internal/persistence"
+	"github.com/Salam4nder/inventory/pkg/auth"
+
+	"github.com/google/uuid"
+)
+
+// denylist keeps the revoked subjects per tenant, or fails
+// with err if it is set.
+type denylist struct {
+	err      error
+	subjects map[string]time.Time
+}
+
+func (d *denylist) RevokeToken(
+	context.Context, string, time.Duration) error {
+	return nil
+}
+
+func (d *denylist) RevokeSubject(
+	ctx context.Context,
+	subject string,
+	at time.Time,
+	expiration time.Duration) error {
+	d.subjects[persistence.TenantFrom(ctx)+"/"+subject] = at
+
+	return nil
+}
+
+func (d *denylist) TokenRevoked(
+	ctx context.Context,
+	tokenID, subject string,
+	issuedAt time.Time) (bool, error) {
+	if d.err != nil {
+		return false, d.err
+	}
+
+	at, ok := d.subjects[persistence.TenantFrom(ctx)+"/"+subject]
+
+	return ok && !issuedAt.After(at), nil
+}
+
+func token(t *testing.T, keys *auth.KeySet, tenant string) string {
+	t.Helper()
+
+	claims := auth.Claims{Username: "user", Tenant: tenant}
+	claims.Subject = "subject"
+
+	token, err := keys.NewJWT(time.Minute, claims)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	return token
+}
+
+func TestToken_Denylist(t *testing.T) {
+	keys, err := auth.NewHMACKeySet("secret")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	denied := &denylist{subjects: make(map[string]time.Time)}
+	a := New(keys, nil, nil, denied)
+
+	ctx := persistence.WithTenant(context.Background(), "acme")
+	if err := denied.RevokeSubject(
+		ctx, "subject", time.Now().Add(time.Second), time.Minute); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := a.Token(
+		context.Background(), token(t, keys, "acme")); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("unexpected error: %v, want %v", err, ErrTokenRevoked)
+	}
+
+	// The same subject in another tenant is someone else.
+	if _, err := a.Token(
+		context.Background(), token(t, keys, "other")); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestToken_Denylist_Unavailable(t *testing.T) {
+	keys, err := auth.NewHMACKeySet("secret")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	a := New(keys, nil, nil, &denylist{err: errors.New("connection refused")})
+
+	if _, err := a.Token(context.Background(),
+		token(t, keys, "acme")); !errors.Is(err, ErrDenylistUnavailable) {
+		t.Errorf("unexpected error: %v, want %v", err, ErrDenylistUnavailable)
+	}
+
+	a.FailOpen = true
+
+	if _, err := a.Token(
+		context.Background(), token(t, keys, "acme")); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestToken_Issued_After_Revocation(t *testing.T) {
+	keys, err := auth.NewHMACKeySet("secret")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	denied := &denylist{subjects: make(map[string]time.Time)}
+	a := New(keys, nil, nil, denied)
+
+	ctx := persistence.WithTenant(context.Background(), "acme")
+	if err := denied.RevokeSubject(
+		ctx, "subject", time.Now(), time.Minute); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// A token issued within the same second as the
+	// revocation is a new one.
+	time.Sleep(2 * time.Millisecond)
+
+	if _, err := a.Token(
+		context.Background(), token(t, keys, "acme")); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+// storage holds a single API key and the users.
+type storage struct {
+	Storage
+
+	key   persistence.APIKey
+	users map[string]persistence.User
+}
+
+func (s *storage) UseAPIKey(
+	ctx context.Context, keyHash string) (persistence.APIKey, error) {
+	if keyHash != auth.HashAPIKey("inv_key") {
+		return persistence.APIKey{}, persistence.ErrNotFound
+	}
+
+	return s.key, nil
+}
+
+func (s *storage) ReadUserByUsername(
+	ctx context.Context, username string) (persistence.User, error) {
+	user, ok := s.users[username]
+	if !ok {
+		return persistence.User{}, persistence.ErrNotFound
+	}
+
+	return user, nil
+}
+
+func TestAPIKey_Creator(t *testing.T) {
+	created := time.Now().Add(-time.Hour)
+	scopes := []string{
+		string(auth.PermissionItemsRead),
+		string(auth.PermissionItemsDelete),
+	}
+
+	tests := []struct {
+		name        string
+		creator     *persistence.User
+		revoked     time.Time
+		err         error
+		permissions int
+	}{
+		{
+			name:        "admin",
+			creator:     &persistence.User{Role: string(auth.RoleAdmin)},
+			permissions: 2,
+		},
+		{
+			name:        "user of the OIDC provider",
+			permissions: 2,
+		},
+		{
+			name:        "demoted",
+			creator:     &persistence.User{Role: string(auth.RoleViewer)},
+			permissions: 1,
+		},
+		{
+			name: "disabled",
+			creator: &persistence.User{
+				Role: string(auth.RoleAdmin), Disabled: true},
+			err: ErrInvalidAPIKey,
+		},
+		{
+			name:    "revoked",
+			creator: &persistence.User{Role: string(auth.RoleAdmin)},
+			revoked: time.Now(),
+			err:     ErrTokenRevoked,
+		},
+		{
+			name:        "revoked before the key was created",
+			creator:     &persistence.User{Role: string(auth.RoleAdmin)},
+			revoked:     created.Add(-time.Minute),
+			permissions: 2,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := &storage{
+				key: persistence.APIKey{
+					ID:        uuid.New(),
+					TenantID:  "acme",
+					Scopes:    scopes,
+					CreatedBy: "admin",
+					CreatedAt: created,
+				},
+				users: make(map[string]persistence.User),
+			}
+			denied := &denylist{subjects: make(map[string]time.Time)}
+
+			if test.creator != nil {
+				creator := *test.creator
+				creator.ID = uuid.New()
+				creator.TenantID = "acme"
+				store.users["admin"] = creator
+
+				if !test.revoked.IsZero() {
+					denied.subjects["acme/"+creator.ID.String()] = test.revoked
+				}
+			}
+
+			claims, err := New(nil, nil, store, denied).APIKey(
+				context.Background(), "inv_key")
+			if !errors.Is(err, test.err) {
+				t.Fatalf("unexpected error: %v, want %v", err, test.err)
+			}
+
+			if err == nil && len(claims.Permissions) != test.permissions {
+				t.Errorf("unexpected permissions: %v", claims.Permissions)
+			}
+		})
+	}
+}
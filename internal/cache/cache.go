@@ -8,13 +8,23 @@ import (
 	"time"
 
 	"github.com/Salam4nder/inventory/internal/config"
+	"github.com/Salam4nder/inventory/internal/metrics"
 	"github.com/Salam4nder/inventory/internal/persistence"
+	"github.com/Salam4nder/inventory/internal/tracing"
 
 	"github.com/go-redis/redis/v8"
 )
 
 // Service is an abstract interface for a caching service.
+// It also keeps the denylist of revoked tokens, the rate
+// limits of the clients and the responses to idempotent
+// requests, and passes on the changes of items.
 type Service interface {
+	Denylist
+	RateLimiter
+	IdempotencyStore
+	ItemEvents
+
 	Get(ctx context.Context, uuid string) (
 		persistence.Item, error)
 	Set(ctx context.Context, key string,
@@ -26,9 +36,12 @@ type Service interface {
 // Redis is an implementation of the cache.Service interface.
 type Redis struct {
 	Client *redis.Client
+	// Metrics counts the hits and misses of Get, if set.
+	Metrics *metrics.Metrics
 }
 
-// New returns a new instance of Redis.
+// New returns a new instance of Redis whose
+// commands are traced.
 func New(cfg config.Cache) (*Redis, error) {
 	redis := &Redis{
 		Client: redis.NewClient(&redis.Options{
@@ -37,6 +50,7 @@ func New(cfg config.Cache) (*Redis, error) {
 			DB:       0,
 		}),
 	}
+	redis.Client.AddHook(tracing.RedisHook{})
 
 	if err := redis.Client.Ping(
 		context.Background()).Err(); err != nil {
@@ -46,26 +60,39 @@ func New(cfg config.Cache) (*Redis, error) {
 	return redis, nil
 }
 
+// itemKey namespaces the key of an item by the tenant
+// of ctx, so tenants never read each other's items.
+func itemKey(ctx context.Context, uuid string) string {
+	return "tenant:" + persistence.TenantFrom(ctx) + ":item:" + uuid
+}
+
 // Ping checks if Redis is available.
 func (r *Redis) Ping(ctx context.Context) error {
 	return r.Client.Ping(ctx).Err()
 }
 
 // Get checks if a persistence.Item with the given uuid
-// exists in the cache. If the key does not exist or if
+// exists in the cache of the tenant of ctx. If the key does not exist or if
 // there is an error, an empty item and an error are returned.
 func (r *Redis) Get(
 	ctx context.Context, uuid string) (persistence.Item, error) {
-	cmd := r.Client.Get(ctx, uuid)
+	cmd := r.Client.Get(ctx, itemKey(ctx, uuid))
 
 	// Bytes() will not attempt to convert the command to
 	// bytes if there was an error with the GET command,
 	// e.g. the key does not exist, or connection error.
 	cmdBytes, err := cmd.Bytes()
-	if err != nil {
+	switch {
+	case errors.Is(err, redis.Nil):
+		r.Metrics.CacheResult(metrics.CacheMiss)
+		return persistence.Item{}, err
+	case err != nil:
+		r.Metrics.CacheResult(metrics.CacheError)
 		return persistence.Item{}, err
 	}
 
+	r.Metrics.CacheResult(metrics.CacheHit)
+
 	bReader := bytes.NewReader(cmdBytes)
 
 	var item persistence.Item
@@ -77,7 +104,8 @@ func (r *Redis) Get(
 	return item, nil
 }
 
-// Set caches the given item with the given uuid as the key.
+// Set caches the given item with the given uuid as the key,
+// in the namespace of the tenant of ctx.
 // If the key already exists, it will be overwritten.
 // If there is an error, it will be returned.
 func (r *Redis) Set(
@@ -92,13 +120,13 @@ func (r *Redis) Set(
 	}
 
 	return r.Client.Set(
-		ctx, key, buffer.Bytes(), expiration).Err()
+		ctx, itemKey(ctx, key), buffer.Bytes(), expiration).Err()
 }
 
 // Delete removes the item with the given uuid from the cache.
 // If the key does not exist, an error is returned.
 func (r *Redis) Delete(ctx context.Context, uuid string) error {
-	res := r.Client.Del(ctx, uuid).Val()
+	res := r.Client.Del(ctx, itemKey(ctx, uuid)).Val()
 	if res == 0 {
 		return errors.New("error deleting key")
 	}
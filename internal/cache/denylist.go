@@ -0,0 +1,86 @@
+package cache
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/Salam4nder/inventory/internal/persistence"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Denylist keeps track of access tokens that were revoked
+// before they expire. Entries belong to the tenant of ctx
+// and only deny tokens of that tenant.
+type Denylist interface {
+	RevokeToken(ctx context.Context, tokenID string,
+		expiration time.Duration) error
+	RevokeSubject(ctx context.Context, subject string,
+		at time.Time, expiration time.Duration) error
+	TokenRevoked(ctx context.Context, tokenID, subject string,
+		issuedAt time.Time) (bool, error)
+}
+
+// RevokeToken denies the token with the given ID. The entry
+// expires after expiration, which must not be shorter than
+// the remaining lifetime of the token.
+func (r *Redis) RevokeToken(
+	ctx context.Context, tokenID string, expiration time.Duration) error {
+	return r.Client.Set(
+		ctx, denylistTokenKey(ctx, tokenID), 1, expiration).Err()
+}
+
+// RevokeSubject denies every token of the subject issued
+// at or before the given time, to the millisecond. The entry
+// expires after expiration, the lifetime of the tokens.
+func (r *Redis) RevokeSubject(
+	ctx context.Context,
+	subject string,
+	at time.Time,
+	expiration time.Duration) error {
+	return r.Client.Set(
+		ctx, denylistSubjectKey(ctx, subject), at.UnixMilli(), expiration).Err()
+}
+
+// TokenRevoked reports whether the token with the given ID,
+// or all tokens of its subject, were revoked. Tokens without
+// an ID are only checked by subject.
+func (r *Redis) TokenRevoked(
+	ctx context.Context,
+	tokenID, subject string,
+	issuedAt time.Time) (bool, error) {
+	values, err := r.Client.MGet(ctx,
+		denylistTokenKey(ctx, tokenID),
+		denylistSubjectKey(ctx, subject)).Result()
+	if err != nil && err != redis.Nil {
+		return false, err
+	}
+
+	if tokenID != "" && values[0] != nil {
+		return true, nil
+	}
+
+	if revokedAt, ok := values[1].(string); ok {
+		milli, err := strconv.ParseInt(revokedAt, 10, 64)
+		if err != nil {
+			return false, err
+		}
+
+		return !issuedAt.After(time.UnixMilli(milli)), nil
+	}
+
+	return false, nil
+}
+
+// denylistTokenKey returns the denylist key of a token ID
+// in the tenant of ctx.
+func denylistTokenKey(ctx context.Context, tokenID string) string {
+	return "tenant:" + persistence.TenantFrom(ctx) + ":denylist:jti:" + tokenID
+}
+
+// denylistSubjectKey returns the denylist key of a subject
+// in the tenant of ctx.
+func denylistSubjectKey(ctx context.Context, subject string) string {
+	return "tenant:" + persistence.TenantFrom(ctx) + ":denylist:sub:" + subject
+}
@@ -0,0 +1,105 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+
+	"github.com/Salam4nder/inventory/internal/persistence"
+)
+
+// ItemEventType is the kind of change of an item.
+type ItemEventType string
+
+// Item event types.
+const (
+	ItemCreated ItemEventType = "created"
+	ItemUpdated ItemEventType = "updated"
+	ItemDeleted ItemEventType = "deleted"
+)
+
+// ItemEvent is a change of an item. The item is the state
+// after the change, deleted items only carry their ID.
+type ItemEvent struct {
+	Type ItemEventType
+	Item persistence.Item
+}
+
+// ItemEvents passes the changes of items on to the
+// watchers of the same tenant. Events are not stored,
+// watchers only receive the events published while
+// they are watching.
+type ItemEvents interface {
+	PublishItemEvent(ctx context.Context, event ItemEvent) error
+	WatchItemEvents(ctx context.Context) (<-chan ItemEvent, error)
+}
+
+// itemEventsChannel is the channel of the item events
+// of the tenant of ctx.
+func itemEventsChannel(ctx context.Context) string {
+	return "tenant:" + persistence.TenantFrom(ctx) + ":items"
+}
+
+// PublishItemEvent publishes the event to the watchers
+// of the tenant of ctx.
+func (r *Redis) PublishItemEvent(
+	ctx context.Context, event ItemEvent) error {
+	var buffer bytes.Buffer
+
+	if err := gob.NewEncoder(&buffer).Encode(event); err != nil {
+		return err
+	}
+
+	return r.Client.Publish(
+		ctx, itemEventsChannel(ctx), buffer.Bytes()).Err()
+}
+
+// WatchItemEvents subscribes to the item events of the tenant
+// of ctx. The returned channel is closed once ctx is done or
+// the subscription ends.
+func (r *Redis) WatchItemEvents(
+	ctx context.Context) (<-chan ItemEvent, error) {
+	subscription := r.Client.Subscribe(ctx, itemEventsChannel(ctx))
+
+	// Wait for the subscription to be confirmed, so no event
+	// published after WatchItemEvents returns is missed.
+	if _, err := subscription.Receive(ctx); err != nil {
+		subscription.Close()
+		return nil, err
+	}
+
+	events := make(chan ItemEvent)
+
+	go func() {
+		defer close(events)
+		defer subscription.Close()
+
+		messages := subscription.Channel()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case message, ok := <-messages:
+				if !ok {
+					return
+				}
+
+				var event ItemEvent
+				if err := gob.NewDecoder(
+					bytes.NewBufferString(message.Payload)).
+					Decode(&event); err != nil {
+					continue
+				}
+
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return events, nil
+}
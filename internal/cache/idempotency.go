@@ -0,0 +1,104 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// idempotencyPrefix is the key prefix of stored responses.
+const idempotencyPrefix = "idempotency:"
+
+// IdempotencyStore keeps the responses of requests sent
+// with an Idempotency-Key, so retries can be replayed.
+type IdempotencyStore interface {
+	ReserveIdempotencyKey(ctx context.Context, key, fingerprint string,
+		expiration time.Duration) (IdempotentResponse, bool, error)
+	StoreIdempotentResponse(ctx context.Context, key string,
+		response IdempotentResponse, expiration time.Duration) error
+	ReleaseIdempotencyKey(ctx context.Context, key string) error
+}
+
+// IdempotentResponse is the response to the first request
+// sent with an idempotency key. Status is zero while the
+// first request is still being handled.
+type IdempotentResponse struct {
+	// Fingerprint identifies the payload of the request.
+	Fingerprint string
+	Status      int
+	ContentType string
+	Body        []byte
+}
+
+// ReserveIdempotencyKey claims key for a request with the given
+// fingerprint. It reports true if the key was claimed, otherwise
+// it returns what is stored for the key.
+func (r *Redis) ReserveIdempotencyKey(
+	ctx context.Context,
+	key, fingerprint string,
+	expiration time.Duration) (IdempotentResponse, bool, error) {
+	pending, err := encodeResponse(
+		IdempotentResponse{Fingerprint: fingerprint})
+	if err != nil {
+		return IdempotentResponse{}, false, err
+	}
+
+	claimed, err := r.Client.SetNX(
+		ctx, idempotencyPrefix+key, pending, expiration).Result()
+	if err != nil || claimed {
+		return IdempotentResponse{}, claimed, err
+	}
+
+	stored, err := r.Client.Get(ctx, idempotencyPrefix+key).Bytes()
+	if err == redis.Nil {
+		// The key expired in between, try again.
+		return r.ReserveIdempotencyKey(ctx, key, fingerprint, expiration)
+	}
+	if err != nil {
+		return IdempotentResponse{}, false, err
+	}
+
+	var response IdempotentResponse
+
+	if err := gob.NewDecoder(
+		bytes.NewReader(stored)).Decode(&response); err != nil {
+		return IdempotentResponse{}, false, err
+	}
+
+	return response, false, nil
+}
+
+// StoreIdempotentResponse stores the response of the request
+// that claimed key, replacing its claim.
+func (r *Redis) StoreIdempotentResponse(
+	ctx context.Context,
+	key string,
+	response IdempotentResponse,
+	expiration time.Duration) error {
+	encoded, err := encodeResponse(response)
+	if err != nil {
+		return err
+	}
+
+	return r.Client.Set(
+		ctx, idempotencyPrefix+key, encoded, expiration).Err()
+}
+
+// ReleaseIdempotencyKey gives up the claim on key, so the
+// request can be sent again with the same key.
+func (r *Redis) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	return r.Client.Del(ctx, idempotencyPrefix+key).Err()
+}
+
+func encodeResponse(response IdempotentResponse) ([]byte, error) {
+	var buffer bytes.Buffer
+
+	if err := gob.NewEncoder(&buffer).Encode(response); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
@@ -0,0 +1,115 @@
+package cache
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/Salam4nder/inventory/internal/config"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// rateLimitPrefix is the key prefix of the token buckets.
+const rateLimitPrefix = "ratelimit:"
+
+// RateLimiter limits how often a client may call the API.
+type RateLimiter interface {
+	Allow(ctx context.Context, key string,
+		limit config.RateLimit) (RateLimitResult, error)
+}
+
+// RateLimitResult is the state of a token bucket after
+// a request was counted against it.
+type RateLimitResult struct {
+	// Allowed reports whether the request may proceed.
+	Allowed bool
+	// Remaining is the number of requests left right now.
+	Remaining int
+	// Reset is how long it takes to refill the bucket.
+	Reset time.Duration
+	// RetryAfter is how long a denied client has to wait
+	// for the next request to be allowed.
+	RetryAfter time.Duration
+}
+
+// tokenBucket takes a token from the bucket KEYS[1] if one is
+// left. The bucket holds ARGV[1] tokens and is refilled within
+// ARGV[2] milliseconds. The clock of Redis is used, so every
+// instance of the API sees the same time.
+var tokenBucket = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local period = tonumber(ARGV[2])
+local clock = redis.call('TIME')
+local now = tonumber(clock[1]) * 1000 + math.floor(tonumber(clock[2]) / 1000)
+
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(bucket[1])
+local ts = tonumber(bucket[2])
+if tokens == nil or ts == nil then
+	tokens = capacity
+	ts = now
+end
+
+tokens = math.min(capacity, tokens + math.max(0, now - ts) * capacity / period)
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], period)
+
+return {allowed, tostring(tokens)}
+`)
+
+// Allow counts a request against the token bucket of key.
+// Buckets are shared by every instance of the API and
+// expire once they are full again.
+func (r *Redis) Allow(
+	ctx context.Context,
+	key string,
+	limit config.RateLimit) (RateLimitResult, error) {
+	values, err := tokenBucket.Run(ctx, r.Client,
+		[]string{rateLimitPrefix + key},
+		limit.Requests, limit.Period.Milliseconds()).Slice()
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+
+	allowed, _ := values[0].(int64)
+	left, _ := values[1].(string)
+
+	tokens, err := strconv.ParseFloat(left, 64)
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+
+	return rateLimitResult(allowed == 1, tokens, limit), nil
+}
+
+// rateLimitResult describes a bucket of the limit with the
+// given tokens left after a request was counted against it.
+func rateLimitResult(
+	allowed bool,
+	tokens float64,
+	limit config.RateLimit) RateLimitResult {
+	// refill is the time it takes to refill a single token.
+	refill := limit.Period / time.Duration(limit.Requests)
+
+	result := RateLimitResult{
+		Allowed:   allowed,
+		Remaining: int(math.Floor(tokens)),
+		Reset: time.Duration(
+			(float64(limit.Requests) - tokens) * float64(refill)),
+	}
+
+	if !result.Allowed {
+		result.RetryAfter = time.Duration((1 - tokens) * float64(refill))
+	}
+
+	return result
+}
@@ -0,0 +1,56 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Salam4nder/inventory/internal/config"
+)
+
+func Test_rateLimitResult(t *testing.T) {
+	limit := config.RateLimit{Requests: 10, Period: time.Minute}
+
+	tests := []struct {
+		name    string
+		allowed bool
+		tokens  float64
+		want    RateLimitResult
+	}{
+		{"first request", true, 9, RateLimitResult{
+			Allowed:   true,
+			Remaining: 9,
+			Reset:     6 * time.Second,
+		}},
+		{"last token", true, 0, RateLimitResult{
+			Allowed:   true,
+			Remaining: 0,
+			Reset:     time.Minute,
+		}},
+		{"partial token", true, 2.5, RateLimitResult{
+			Allowed:   true,
+			Remaining: 2,
+			Reset:     45 * time.Second,
+		}},
+		{"denied", false, 0, RateLimitResult{
+			Allowed:    false,
+			Remaining:  0,
+			Reset:      time.Minute,
+			RetryAfter: 6 * time.Second,
+		}},
+		{"denied while refilling", false, 0.75, RateLimitResult{
+			Allowed:    false,
+			Remaining:  0,
+			Reset:      55500 * time.Millisecond,
+			RetryAfter: 1500 * time.Millisecond,
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := rateLimitResult(test.allowed, test.tokens, limit)
+			if got != test.want {
+				t.Errorf("unexpected result: %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
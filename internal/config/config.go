@@ -2,6 +2,8 @@ package config
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/plaid/go-envvar/envvar"
@@ -9,9 +11,10 @@ import (
 
 // Application is the main configuration structure for the application.
 type Application struct {
-	DB    Database `envvar:"POSTGRES_"`
-	HTTP  Server   `envvar:"SERVER_"`
-	Cache Cache    `envvar:"REDIS_"`
+	DB      Database `envvar:"POSTGRES_"`
+	HTTP    Server   `envvar:"SERVER_"`
+	Cache   Cache    `envvar:"REDIS_"`
+	Tracing Tracing  `envvar:"TRACING_"`
 }
 
 // Database is the database configuration.
@@ -21,15 +24,144 @@ type Database struct {
 	User     string `envvar:"USER"`
 	Name     string `envvar:"DB"`
 	Password string `envvar:"PASSWORD"`
+	// RowLevelSecurity enforces the tenant of every
+	// query with Postgres row-level security.
+	RowLevelSecurity bool `envvar:"ROW_LEVEL_SECURITY" default:"false"`
 }
 
 // Server is the HTTP server configuration.
 type Server struct {
 	Host                  string        `envvar:"HOST"`
 	Port                  string        `envvar:"PORT"`
-	JWTSecret             string        `envvar:"JWT"`
 	AccessTokenExpiration time.Duration `envvar:"ACCESS_TOKEN_EXPIRES"`
 	LogFile               string        `envvar:"LOG_FILE"`
+	// JWTSecret signs access tokens with HS256. It is only
+	// used if JWTKeyDir is not set.
+	JWTSecret string `envvar:"JWT" default:""`
+	// JWTKeyDir is a directory of PEM encoded RSA or Ed25519
+	// keys, named after their kid. Tokens are signed with the
+	// JWTSigningKey key, or the greatest kid if it is not set.
+	JWTKeyDir     string `envvar:"JWT_KEY_DIR" default:""`
+	JWTSigningKey string `envvar:"JWT_SIGNING_KEY" default:""`
+	// JWTIssuer and JWTAudience are set in the access tokens
+	// and checked on validation, unless they are empty.
+	JWTIssuer   string `envvar:"JWT_ISSUER" default:""`
+	JWTAudience string `envvar:"JWT_AUDIENCE" default:""`
+	// OIDCIssuer enables access tokens of an external OpenID
+	// Connect provider. Its tokens must be issued for the
+	// OIDCAudience, the groups listed in the OIDCGroupsClaim
+	// are mapped to roles by OIDCGroupRoles, a comma separated
+	// list of group=role pairs. The OIDCTenantClaim names
+	// the tenant of the user, tokens without it are rejected.
+	OIDCIssuer      string `envvar:"OIDC_ISSUER" default:""`
+	OIDCAudience    string `envvar:"OIDC_AUDIENCE" default:""`
+	OIDCGroupsClaim string `envvar:"OIDC_GROUPS_CLAIM" default:"groups"`
+	OIDCGroupRoles  string `envvar:"OIDC_GROUP_ROLES" default:""`
+	OIDCTenantClaim string `envvar:"OIDC_TENANT_CLAIM" default:"tenant"`
+	// RefreshTokenExpiration is how long a refresh
+	// token can be exchanged for a new access token.
+	RefreshTokenExpiration time.Duration `envvar:"REFRESH_TOKEN_EXPIRES" default:"720h"`
+	// ReservationSweepInterval is how often expired
+	// stock reservations are released.
+	ReservationSweepInterval time.Duration `envvar:"RESERVATION_SWEEP_INTERVAL" default:"1m"`
+	// AdminUsername and AdminPassword create the first admin
+	// user on startup if it does not exist yet. The service
+	// does not start if the password is not 8 to 72 characters long.
+	AdminUsername string `envvar:"ADMIN_USERNAME" default:""`
+	AdminPassword string `envvar:"ADMIN_PASSWORD" default:""`
+	// RateLimitAuth, RateLimitAPI and RateLimitAdmin limit
+	// the requests of every API key, user or IP address to
+	// the /auth, /api and /admin routes. Calls of the gRPC
+	// API share the limit of /api. Empty limits are not
+	// enforced.
+	RateLimitAuth  RateLimit `envvar:"RATE_LIMIT_AUTH" default:"20/1m"`
+	RateLimitAPI   RateLimit `envvar:"RATE_LIMIT_API" default:"600/1m"`
+	RateLimitAdmin RateLimit `envvar:"RATE_LIMIT_ADMIN" default:"60/1m"`
+	// IdempotencyTTL is how long the response to a request
+	// with an Idempotency-Key is replayed to retries.
+	IdempotencyTTL time.Duration `envvar:"IDEMPOTENCY_TTL" default:"24h"`
+	// LowStockThreshold is the amount below which an item
+	// is counted as low on stock in the metrics.
+	LowStockThreshold float64 `envvar:"LOW_STOCK_THRESHOLD" default:"10"`
+	// ItemStatsInterval is how often the items are counted
+	// for the metrics. Scrapes in between get the last count.
+	ItemStatsInterval time.Duration `envvar:"ITEM_STATS_INTERVAL" default:"1m"`
+	// MetricsPort is the port the metrics are served on, on
+	// the same host as the HTTP API. It is meant for the
+	// monitoring network only and not exposed to clients.
+	// An empty port disables it.
+	MetricsPort string `envvar:"METRICS_PORT" default:"9100"`
+	// ReadinessRequiresCache fails the readiness probe while
+	// Redis is down. Otherwise the service reports itself as
+	// degraded and keeps receiving traffic.
+	ReadinessRequiresCache bool `envvar:"READINESS_REQUIRES_CACHE" default:"false"`
+	// DenylistFailOpen accepts access tokens while the token
+	// denylist in Redis cannot be reached. Otherwise they are
+	// rejected until Redis is back.
+	DenylistFailOpen bool `envvar:"DENYLIST_FAIL_OPEN" default:"false"`
+	// GRPCPort is the port of the gRPC API, served on the
+	// same host as the HTTP API. An empty port disables it.
+	GRPCPort string `envvar:"GRPC_PORT" default:"9090"`
+	// GraphQLComplexityLimit is the highest complexity of a
+	// GraphQL query, where every field costs one and the
+	// fields of a list cost once per requested element.
+	GraphQLComplexityLimit int `envvar:"GRAPHQL_COMPLEXITY_LIMIT" default:"1000"`
+}
+
+// RateLimit allows Requests requests per Period, written
+// as requests/period such as "100/1m". Unused requests
+// accumulate up to Requests, so a client may burst after
+// a quiet period.
+type RateLimit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// Enabled reports whether the limit is enforced.
+func (l RateLimit) Enabled() bool {
+	return l.Requests > 0 && l.Period > 0
+}
+
+// String returns the limit as requests/period.
+func (l RateLimit) String() string {
+	if !l.Enabled() {
+		return ""
+	}
+
+	return strconv.Itoa(l.Requests) + "/" + l.Period.String()
+}
+
+// UnmarshalText parses a limit written as requests/period.
+func (l *RateLimit) UnmarshalText(text []byte) error {
+	value := strings.TrimSpace(string(text))
+	if value == "" {
+		*l = RateLimit{}
+		return nil
+	}
+
+	requests, period, ok := strings.Cut(value, "/")
+	if !ok {
+		return fmt.Errorf("invalid rate limit %q", value)
+	}
+
+	var (
+		limit RateLimit
+		err   error
+	)
+
+	if limit.Requests, err = strconv.Atoi(requests); err != nil ||
+		limit.Requests <= 0 {
+		return fmt.Errorf("invalid rate limit %q", value)
+	}
+
+	if limit.Period, err = time.ParseDuration(period); err != nil ||
+		limit.Period <= 0 {
+		return fmt.Errorf("invalid rate limit %q", value)
+	}
+
+	*l = limit
+
+	return nil
 }
 
 // Cache is the cache configuration.
@@ -39,6 +171,20 @@ type Cache struct {
 	Password string `envvar:"PASSWORD"`
 }
 
+// Tracing is the OpenTelemetry tracing configuration.
+type Tracing struct {
+	// Endpoint is the host:port of the OTLP/HTTP collector
+	// spans are exported to. Tracing is off if it is empty.
+	Endpoint string `envvar:"ENDPOINT" default:""`
+	// Insecure exports spans over plain HTTP.
+	Insecure    bool   `envvar:"INSECURE" default:"false"`
+	ServiceName string `envvar:"SERVICE_NAME" default:"inventory"`
+	// SampleRatio is the share of new traces that are
+	// sampled. Requests that are part of a trace follow
+	// the sampling decision of their parent.
+	SampleRatio float64 `envvar:"SAMPLE_RATIO" default:"1"`
+}
+
 // New parses the environment variables and returns a new Config.
 // It returns an error if any env variables are unset.
 func New() (*Application, error) {
@@ -65,6 +211,16 @@ func (srvCfg *Server) Addr() string {
 	return fmt.Sprintf("%s:%s", srvCfg.Host, srvCfg.Port)
 }
 
+// GRPCAddr returns the configured gRPC server address.
+func (srvCfg *Server) GRPCAddr() string {
+	return fmt.Sprintf("%s:%s", srvCfg.Host, srvCfg.GRPCPort)
+}
+
+// MetricsAddr returns the configured metrics server address.
+func (srvCfg *Server) MetricsAddr() string {
+	return fmt.Sprintf("%s:%s", srvCfg.Host, srvCfg.MetricsPort)
+}
+
 // Addr returns the configured cache address.
 func (cacheCfg *Cache) Addr() string {
 	return fmt.Sprintf("%s:%s", cacheCfg.Host, cacheCfg.Port)
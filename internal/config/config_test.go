@@ -0,0 +1,66 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_RateLimit_UnmarshalText(t *testing.T) {
+	tests := []struct {
+		text    string
+		want    RateLimit
+		wantErr bool
+	}{
+		{"100/1m", RateLimit{Requests: 100, Period: time.Minute}, false},
+		{" 5/30s ", RateLimit{Requests: 5, Period: 30 * time.Second}, false},
+		{"", RateLimit{}, false},
+		{"   ", RateLimit{}, false},
+		{"100", RateLimit{}, true},
+		{"100/", RateLimit{}, true},
+		{"/1m", RateLimit{}, true},
+		{"many/1m", RateLimit{}, true},
+		{"100/minute", RateLimit{}, true},
+		{"100/1m/1h", RateLimit{}, true},
+		{"0/1m", RateLimit{}, true},
+		{"-1/1m", RateLimit{}, true},
+		{"100/0s", RateLimit{}, true},
+		{"100/-1m", RateLimit{}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			limit := RateLimit{Requests: 1, Period: time.Second}
+
+			err := limit.UnmarshalText([]byte(test.text))
+			if (err != nil) != test.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			// Invalid limits leave the previous value alone.
+			if test.wantErr {
+				test.want = RateLimit{Requests: 1, Period: time.Second}
+			}
+
+			if limit != test.want {
+				t.Errorf("unexpected limit: %+v, want %+v", limit, test.want)
+			}
+		})
+	}
+}
+
+func Test_RateLimit_String(t *testing.T) {
+	tests := []struct {
+		limit RateLimit
+		want  string
+	}{
+		{RateLimit{Requests: 100, Period: time.Minute}, "100/1m0s"},
+		{RateLimit{}, ""},
+		{RateLimit{Requests: 100}, ""},
+	}
+
+	for _, test := range tests {
+		if got := test.limit.String(); got != test.want {
+			t.Errorf("%+v.String() = %q, want %q", test.limit, got, test.want)
+		}
+	}
+}
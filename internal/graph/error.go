@@ -0,0 +1,127 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Salam4nder/inventory/internal/persistence"
+	"github.com/Salam4nder/inventory/pkg/logger"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"go.uber.org/zap"
+)
+
+// Codes of the errors, set as the code extension.
+const (
+	codeBadUserInput        = "BAD_USER_INPUT"
+	codeForbidden           = "FORBIDDEN"
+	codeNotFound            = "NOT_FOUND"
+	codeInsufficientStock   = "INSUFFICIENT_STOCK"
+	codeReservationInactive = "RESERVATION_INACTIVE"
+	codeTimeout             = "TIMEOUT"
+	codeCanceled            = "CANCELED"
+	codeInternal            = "INTERNAL"
+)
+
+// errorCode maps an error of the storage to an error code.
+type errorCode struct {
+	err  error
+	code string
+}
+
+// errorCodes maps the errors of the storage to error codes,
+// like the problems of the REST API.
+var errorCodes = []errorCode{
+	{persistence.ErrNotFound, codeNotFound},
+	{persistence.ErrInsufficientStock, codeInsufficientStock},
+	{persistence.ErrReservationInactive, codeReservationInactive},
+	{context.DeadlineExceeded, codeTimeout},
+	{context.Canceled, codeCanceled},
+}
+
+// codedError is an error of a resolver that is sent to the
+// client with its code.
+type codedError struct {
+	code    string
+	message string
+	fields  map[string]string
+}
+
+func (e *codedError) Error() string {
+	return e.message
+}
+
+// forbidden returns the error of a caller that lacks the
+// permission.
+func forbidden(permission string) error {
+	return &codedError{
+		code:    codeForbidden,
+		message: fmt.Sprintf("missing permission %q", permission),
+	}
+}
+
+// violations collects the invalid arguments of a field.
+type violations map[string]string
+
+// add records that the argument is invalid.
+func (v violations) add(argument, description string) {
+	v[argument] = description
+}
+
+// err returns the error of the violations, or nil if
+// there are none.
+func (v violations) err() error {
+	if len(v) == 0 {
+		return nil
+	}
+
+	return &codedError{
+		code:    codeBadUserInput,
+		message: "invalid arguments",
+		fields:  v,
+	}
+}
+
+// fail returns the coded error err maps to. Errors that do
+// not map to a code are internal errors, they are logged and
+// their message is not sent to the client.
+func fail(ctx context.Context, err error) error {
+	for _, mapping := range errorCodes {
+		if errors.Is(err, mapping.err) {
+			return &codedError{code: mapping.code, message: err.Error()}
+		}
+	}
+
+	logger.FromContext(ctx).Error(err.Error(), zap.Error(err))
+
+	return &codedError{code: codeInternal, message: "internal error"}
+}
+
+// presentError sets the code extension of the errors of
+// the resolvers. Errors of gqlgen, such as arguments that
+// cannot be converted, are reported as they are.
+func presentError(ctx context.Context, err error) *gqlerror.Error {
+	presented := graphql.DefaultErrorPresenter(ctx, err)
+
+	var coded *codedError
+	if errors.As(err, &coded) {
+		presented.Message = coded.message
+		presented.Extensions = map[string]interface{}{"code": coded.code}
+		if len(coded.fields) > 0 {
+			presented.Extensions["fields"] = coded.fields
+		}
+	}
+
+	return presented
+}
+
+// recoverPanic logs a panic of a resolver and reports it
+// as an internal error.
+func recoverPanic(ctx context.Context, p interface{}) error {
+	logger.FromContext(ctx).Error("panic recovered",
+		zap.Any("panic", p), zap.Stack("stack"))
+
+	return &codedError{code: codeInternal, message: "internal error"}
+}
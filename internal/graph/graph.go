@@ -0,0 +1,130 @@
+// Package graph serves the items, their stock and their
+// reservations as a GraphQL API. The executable schema is
+// generated by gqlgen from schema.graphqls, the resolvers
+// are written by hand.
+package graph
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Salam4nder/inventory/internal/cache"
+	"github.com/Salam4nder/inventory/internal/config"
+	"github.com/Salam4nder/inventory/internal/persistence"
+	"github.com/Salam4nder/inventory/pkg/auth"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+)
+
+const (
+	// maxPageSize is the largest page of items.
+	maxPageSize = 100
+	// reservationsPerItem is the number of active reservations
+	// of an item assumed when the complexity of a query is
+	// computed.
+	reservationsPerItem = 10
+)
+
+// Resolver resolves the queries and mutations of the schema.
+type Resolver struct {
+	storage persistence.Storage
+	cache   cache.Service
+}
+
+// Item implements ResolverRoot.
+func (r *Resolver) Item() ItemResolver { return &itemResolver{r} }
+
+// Mutation implements ResolverRoot.
+func (r *Resolver) Mutation() MutationResolver { return &mutationResolver{r} }
+
+// Query implements ResolverRoot.
+func (r *Resolver) Query() QueryResolver { return &queryResolver{r} }
+
+// Reservation implements ResolverRoot.
+func (r *Resolver) Reservation() ReservationResolver {
+	return &reservationResolver{r}
+}
+
+type itemResolver struct{ *Resolver }
+
+type mutationResolver struct{ *Resolver }
+
+type queryResolver struct{ *Resolver }
+
+type reservationResolver struct{ *Resolver }
+
+// NewHandler returns the handler of the GraphQL endpoint.
+// Requests must carry the claims of the caller and the
+// tenant in their context, and queries more complex than
+// the configured limit are rejected before they run.
+func NewHandler(
+	cfg config.Server,
+	store persistence.Storage,
+	cache cache.Service) http.Handler {
+	server := handler.New(NewExecutableSchema(Config{
+		Resolvers: &Resolver{
+			storage: store,
+			cache:   cache,
+		},
+		Directives: DirectiveRoot{HasPermission: hasPermission},
+		Complexity: complexity(),
+	}))
+
+	server.AddTransport(transport.POST{})
+	server.SetQueryCache(lru.New(1000))
+	server.Use(extension.Introspection{})
+	server.Use(extension.FixedComplexityLimit(cfg.GraphQLComplexityLimit))
+	server.SetErrorPresenter(presentError)
+	server.SetRecoverFunc(recoverPanic)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.ServeHTTP(w, r.WithContext(
+			withLoaders(r.Context(), store)))
+	})
+}
+
+// hasPermission implements the @hasPermission directive.
+func hasPermission(
+	ctx context.Context,
+	_ interface{},
+	next graphql.Resolver,
+	permission string) (interface{}, error) {
+	claims, ok := auth.FromContext(ctx)
+	if !ok || !claims.HasPermission(auth.Permission(permission)) {
+		return nil, forbidden(permission)
+	}
+
+	return next(ctx)
+}
+
+// complexity counts every field once, except that the
+// fields of a page of items count once per requested item
+// and the fields of the reservations of an item count once
+// per assumed reservation.
+func complexity() ComplexityRoot {
+	var root ComplexityRoot
+
+	root.Query.Items = func(
+		childComplexity int,
+		_ *ItemFilter,
+		first int,
+		_ *string) int {
+		// Out of range sizes are rejected by the resolver,
+		// they must not lower the complexity meanwhile.
+		if first < 1 || first > maxPageSize {
+			first = maxPageSize
+		}
+
+		return 1 + first*childComplexity
+	}
+
+	root.Item.Reservations = func(childComplexity int) int {
+		return 1 + reservationsPerItem*childComplexity
+	}
+
+	return root
+}
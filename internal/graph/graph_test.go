@@ -0,0 +1,461 @@
+package graph
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Salam4nder/inventory/internal/cache"
+	"github.com/Salam4nder/inventory/internal/config"
+	"github.com/Salam4nder/inventory/internal/persistence"
+	"github.com/Salam4nder/inventory/pkg/auth"
+
+	"github.com/google/uuid"
+)
+
+// storage keeps items and reservations in memory and
+// counts the batched reads, which run concurrently.
+type storage struct {
+	persistence.Storage
+
+	mu           sync.Mutex
+	items        map[uuid.UUID]persistence.Item
+	reservations []persistence.Reservation
+	calls        map[string]int
+}
+
+func (s *storage) count(method string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls[method]++
+}
+
+func (s *storage) Create(
+	ctx context.Context, item persistence.Item) (uuid.UUID, error) {
+	item.ID = uuid.New()
+	s.items[item.ID] = item
+
+	return item.ID, nil
+}
+
+func (s *storage) ReadMany(
+	ctx context.Context, ids []string) ([]persistence.Item, error) {
+	s.count("ReadMany")
+
+	var items []persistence.Item
+	for _, id := range ids {
+		if item, ok := s.items[uuid.MustParse(id)]; ok {
+			items = append(items, item)
+		}
+	}
+
+	return items, nil
+}
+
+func (s *storage) ReadPage(
+	ctx context.Context,
+	filter persistence.ItemFilter,
+	after string,
+	limit int) ([]persistence.Item, error) {
+	var items []persistence.Item
+	for _, item := range s.items {
+		if filter.Name != "" && filter.Name != item.Name {
+			continue
+		}
+		if item.ID.String() > after {
+			items = append(items, item)
+		}
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].ID.String() < items[j].ID.String()
+	})
+
+	if len(items) > limit {
+		items = items[:limit]
+	}
+
+	return items, nil
+}
+
+func (s *storage) AdjustStock(
+	ctx context.Context, id string, delta float64) (persistence.Item, error) {
+	item, ok := s.items[uuid.MustParse(id)]
+	if !ok {
+		return persistence.Item{}, persistence.ErrNotFound
+	}
+
+	if item.Amount+delta < 0 {
+		return persistence.Item{}, persistence.ErrInsufficientStock
+	}
+
+	item.Amount += delta
+	s.items[item.ID] = item
+
+	return item, nil
+}
+
+func (s *storage) Stocks(
+	ctx context.Context, ids []string) ([]persistence.Stock, error) {
+	s.count("Stocks")
+
+	var stocks []persistence.Stock
+	for _, id := range ids {
+		item := s.items[uuid.MustParse(id)]
+		stock := persistence.Stock{ItemID: item.ID, Amount: item.Amount}
+		for _, reservation := range s.reservations {
+			if reservation.ItemID == item.ID {
+				stock.Reserved += reservation.Quantity
+			}
+		}
+		stock.Available = stock.Amount - stock.Reserved
+		stocks = append(stocks, stock)
+	}
+
+	return stocks, nil
+}
+
+func (s *storage) ActiveReservations(
+	ctx context.Context,
+	ids []string) ([]persistence.Reservation, error) {
+	s.count("ActiveReservations")
+
+	return s.reservations, nil
+}
+
+// missed is a cache that is always missed.
+type missed struct {
+	cache.Service
+}
+
+func (missed) Get(context.Context, string) (persistence.Item, error) {
+	return persistence.Item{}, errors.New("cache miss")
+}
+
+func (missed) Set(context.Context, string,
+	persistence.Item, time.Duration) error {
+	return nil
+}
+
+func (missed) PublishItemEvent(context.Context, cache.ItemEvent) error {
+	return nil
+}
+
+// response is the body of a GraphQL response.
+type response struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string                 `json:"message"`
+		Extensions map[string]interface{} `json:"extensions"`
+	} `json:"errors"`
+}
+
+type fixture struct {
+	handler http.Handler
+	storage *storage
+}
+
+func setup(t *testing.T, items int) *fixture {
+	t.Helper()
+
+	store := &storage{
+		items: make(map[uuid.UUID]persistence.Item),
+		calls: make(map[string]int),
+	}
+
+	for idx := 0; idx < items; idx++ {
+		item := persistence.Item{
+			ID:     uuid.New(),
+			Name:   "flour",
+			Unit:   "kg",
+			Amount: 10,
+		}
+		store.items[item.ID] = item
+		store.reservations = append(store.reservations,
+			persistence.Reservation{
+				ID:       uuid.New(),
+				ItemID:   item.ID,
+				Quantity: 2,
+				Owner:    "order",
+				Status:   persistence.ReservationActive,
+			})
+	}
+
+	return &fixture{
+		handler: NewHandler(config.Server{
+			GraphQLComplexityLimit: 1000,
+		}, store, missed{}),
+		storage: store,
+	}
+}
+
+// do runs the query as a user of the acme tenant with the
+// given role and decodes the data into data.
+func (f *fixture) do(
+	t *testing.T,
+	role auth.Role,
+	query string,
+	variables map[string]interface{},
+	data interface{}) response {
+	t.Helper()
+
+	body, err := json.Marshal(map[string]interface{}{
+		"query":     query,
+		"variables": variables,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx := persistence.WithTenant(auth.NewContext(context.Background(),
+		&auth.Claims{
+			Username:    "user",
+			Tenant:      "acme",
+			Roles:       []auth.Role{role},
+			Permissions: role.Permissions(),
+		}), "acme")
+
+	req := httptest.NewRequest(http.MethodPost, "/graphql",
+		bytes.NewReader(body)).WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	f.handler.ServeHTTP(w, req)
+
+	var resp response
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if data != nil && len(resp.Data) > 0 {
+		if err := json.Unmarshal(resp.Data, data); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	return resp
+}
+
+func expectCode(t *testing.T, resp response, code string) {
+	t.Helper()
+
+	if len(resp.Errors) == 0 {
+		t.Fatalf("expected error with code %s", code)
+	}
+
+	if got := resp.Errors[0].Extensions["code"]; got != code {
+		t.Fatalf("unexpected code %v, want %s: %s",
+			got, code, resp.Errors[0].Message)
+	}
+}
+
+type page struct {
+	Items struct {
+		Edges []struct {
+			Node struct {
+				ID    string `json:"id"`
+				Stock struct {
+					Available float64 `json:"available"`
+				} `json:"stock"`
+				Reservations []struct {
+					Item struct {
+						ID string `json:"id"`
+					} `json:"item"`
+				} `json:"reservations"`
+			} `json:"node"`
+		} `json:"edges"`
+		PageInfo struct {
+			EndCursor   string `json:"endCursor"`
+			HasNextPage bool   `json:"hasNextPage"`
+		} `json:"pageInfo"`
+	} `json:"items"`
+}
+
+const itemsQuery = `query($first: Int!, $after: String) {
+	items(filter: {name: "flour"}, first: $first, after: $after) {
+		edges {
+			node {
+				id
+				stock { available }
+				reservations { item { id } }
+			}
+		}
+		pageInfo { endCursor hasNextPage }
+	}
+}`
+
+func TestItems_Batching(t *testing.T) {
+	f := setup(t, 5)
+
+	var data page
+	resp := f.do(t, auth.RoleViewer, itemsQuery,
+		map[string]interface{}{"first": 10}, &data)
+	if len(resp.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", resp.Errors)
+	}
+
+	if len(data.Items.Edges) != 5 {
+		t.Fatalf("unexpected number of items: %d", len(data.Items.Edges))
+	}
+
+	for _, edge := range data.Items.Edges {
+		if edge.Node.Stock.Available != 8 {
+			t.Errorf("unexpected stock: %v", edge.Node.Stock)
+		}
+		if len(edge.Node.Reservations) != 1 ||
+			edge.Node.Reservations[0].Item.ID != edge.Node.ID {
+			t.Errorf("unexpected reservations: %v", edge.Node.Reservations)
+		}
+	}
+
+	for _, method := range []string{"Stocks", "ActiveReservations", "ReadMany"} {
+		if f.storage.calls[method] != 1 {
+			t.Errorf("%s called %d times, want once",
+				method, f.storage.calls[method])
+		}
+	}
+}
+
+func TestItems_Pagination(t *testing.T) {
+	f := setup(t, 3)
+
+	var first page
+	f.do(t, auth.RoleViewer, itemsQuery,
+		map[string]interface{}{"first": 2}, &first)
+
+	if len(first.Items.Edges) != 2 || !first.Items.PageInfo.HasNextPage {
+		t.Fatalf("unexpected first page: %+v", first.Items)
+	}
+
+	var second page
+	f.do(t, auth.RoleViewer, itemsQuery, map[string]interface{}{
+		"first": 2,
+		"after": first.Items.PageInfo.EndCursor,
+	}, &second)
+
+	if len(second.Items.Edges) != 1 || second.Items.PageInfo.HasNextPage {
+		t.Fatalf("unexpected second page: %+v", second.Items)
+	}
+
+	if second.Items.Edges[0].Node.ID <= first.Items.Edges[1].Node.ID {
+		t.Errorf("second page does not continue after the first")
+	}
+
+	resp := f.do(t, auth.RoleViewer, itemsQuery, map[string]interface{}{
+		"first": 2,
+		"after": "invalid",
+	}, nil)
+	expectCode(t, resp, codeBadUserInput)
+}
+
+func TestItems_ComplexityLimit(t *testing.T) {
+	f := setup(t, 1)
+
+	resp := f.do(t, auth.RoleViewer, itemsQuery,
+		map[string]interface{}{"first": 100}, nil)
+	expectCode(t, resp, "COMPLEXITY_LIMIT_EXCEEDED")
+
+	if f.storage.calls["Stocks"] != 0 {
+		t.Errorf("query ran despite its complexity")
+	}
+
+	// Out of range page sizes count as the largest page.
+	resp = f.do(t, auth.RoleViewer, itemsQuery,
+		map[string]interface{}{"first": -100}, nil)
+	expectCode(t, resp, "COMPLEXITY_LIMIT_EXCEEDED")
+}
+
+const createItem = `mutation($input: ItemInput!) {
+	createItem(input: $input) { id name }
+}`
+
+func TestCreateItem(t *testing.T) {
+	f := setup(t, 0)
+
+	input := map[string]interface{}{
+		"input": map[string]interface{}{
+			"name":      "flour",
+			"unit":      "kg",
+			"amount":    10,
+			"expiresAt": time.Now().Add(time.Hour).Format(time.RFC3339),
+		},
+	}
+
+	resp := f.do(t, auth.RoleViewer, createItem, input, nil)
+	expectCode(t, resp, codeForbidden)
+
+	var data struct {
+		CreateItem struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"createItem"`
+	}
+	resp = f.do(t, auth.RoleOperator, createItem, input, &data)
+	if len(resp.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", resp.Errors)
+	}
+
+	if _, ok := f.storage.items[uuid.MustParse(data.CreateItem.ID)]; !ok {
+		t.Errorf("item was not created: %+v", data.CreateItem)
+	}
+}
+
+func TestCreateItem_Invalid(t *testing.T) {
+	f := setup(t, 0)
+
+	resp := f.do(t, auth.RoleAdmin, createItem, map[string]interface{}{
+		"input": map[string]interface{}{
+			"name":      "",
+			"unit":      "kg",
+			"amount":    -1,
+			"expiresAt": time.Now().Format(time.RFC3339),
+		},
+	}, nil)
+	expectCode(t, resp, codeBadUserInput)
+
+	fields, _ := resp.Errors[0].Extensions["fields"].(map[string]interface{})
+	if len(fields) != 2 ||
+		fields["input.name"] == nil || fields["input.amount"] == nil {
+		t.Errorf("unexpected fields: %v", fields)
+	}
+
+	resp = f.do(t, auth.RoleAdmin, createItem, map[string]interface{}{
+		"input": map[string]interface{}{
+			"name":      "flour",
+			"unit":      "kg",
+			"amount":    0,
+			"expiresAt": time.Time{}.Format(time.RFC3339),
+			"sku":       strings.Repeat("a", persistence.MaxSKULength+1),
+		},
+	}, nil)
+	expectCode(t, resp, codeBadUserInput)
+
+	fields, _ = resp.Errors[0].Extensions["fields"].(map[string]interface{})
+	if len(fields) != 2 ||
+		fields["input.expiresAt"] == nil || fields["input.sku"] == nil {
+		t.Errorf("unexpected fields: %v", fields)
+	}
+}
+
+func TestAdjustStock_Insufficient(t *testing.T) {
+	f := setup(t, 1)
+
+	var id string
+	for itemID := range f.storage.items {
+		id = itemID.String()
+	}
+
+	resp := f.do(t, auth.RoleOperator,
+		`mutation($id: ID!) { adjustStock(id: $id, delta: -11) { amount } }`,
+		map[string]interface{}{"id": id}, nil)
+	expectCode(t, resp, codeInsufficientStock)
+}
@@ -0,0 +1,353 @@
+package graph
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"time"
+
+	"github.com/Salam4nder/inventory/internal/cache"
+	"github.com/Salam4nder/inventory/internal/persistence"
+	"github.com/Salam4nder/inventory/pkg/logger"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Item implements QueryResolver.
+func (r *queryResolver) Item(
+	ctx context.Context, id string) (*persistence.Item, error) {
+	v := violations{}
+	v.checkID("id", id)
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	cachedItem, err := r.cache.Get(ctx, id)
+	if err == nil {
+		return &cachedItem, nil
+	}
+
+	item, err := r.storage.Read(ctx, id)
+	if errors.Is(err, persistence.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fail(ctx, err)
+	}
+
+	return &item, nil
+}
+
+// Items implements QueryResolver. The pages are ordered
+// by ID and the cursor of an item is its encoded ID.
+func (r *queryResolver) Items(
+	ctx context.Context,
+	filter *ItemFilter,
+	first int,
+	after *string) (*ItemConnection, error) {
+	v := violations{}
+	if first < 1 || first > maxPageSize {
+		v.add("first", "must be between 1 and 100")
+	}
+	var afterID string
+	if after != nil {
+		id, err := decodeCursor(*after)
+		if err != nil {
+			v.add("after", "must be a cursor of an item")
+		}
+		afterID = id
+	}
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	// One more item than asked for tells whether there
+	// is a next page.
+	items, err := r.storage.ReadPage(
+		ctx, filterFromInput(filter), afterID, first+1)
+	if err != nil {
+		return nil, fail(ctx, err)
+	}
+
+	connection := &ItemConnection{
+		Edges:    make([]ItemEdge, 0, first),
+		PageInfo: &PageInfo{HasNextPage: len(items) > first},
+	}
+
+	for idx := range items {
+		if idx == first {
+			break
+		}
+
+		cursor := encodeCursor(items[idx].ID)
+		connection.Edges = append(connection.Edges, ItemEdge{
+			Cursor: cursor,
+			Node:   &items[idx],
+		})
+		connection.PageInfo.EndCursor = &cursor
+	}
+
+	return connection, nil
+}
+
+// CreateItem implements MutationResolver.
+func (r *mutationResolver) CreateItem(
+	ctx context.Context, input ItemInput) (*persistence.Item, error) {
+	item := itemFromInput(input)
+
+	v := violations{}
+	v.checkItem(item)
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	id, err := r.storage.Create(ctx, item)
+	if err != nil {
+		return nil, fail(ctx, err)
+	}
+	item.ID = id
+
+	r.cacheItem(ctx, item)
+	r.publish(ctx, cache.ItemCreated, item)
+
+	return &item, nil
+}
+
+// UpdateItem implements MutationResolver.
+func (r *mutationResolver) UpdateItem(
+	ctx context.Context,
+	id string,
+	input ItemInput) (*persistence.Item, error) {
+	item := itemFromInput(input)
+
+	v := violations{}
+	v.checkID("id", id)
+	v.checkItem(item)
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	item.ID = uuid.MustParse(id)
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	updatedItem, err := r.storage.Update(ctx, item)
+	if err != nil {
+		return nil, fail(ctx, err)
+	}
+
+	r.cacheItem(ctx, updatedItem)
+	r.publish(ctx, cache.ItemUpdated, updatedItem)
+
+	return &updatedItem, nil
+}
+
+// DeleteItem implements MutationResolver.
+func (r *mutationResolver) DeleteItem(
+	ctx context.Context, id string) (string, error) {
+	v := violations{}
+	v.checkID("id", id)
+	if err := v.err(); err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if err := r.storage.Delete(ctx, id); err != nil {
+		return "", fail(ctx, err)
+	}
+
+	if err := r.cache.Delete(ctx, id); err != nil {
+		logger.FromContext(ctx).Info(err.Error(), zap.Error(err))
+	}
+
+	r.publish(ctx, cache.ItemDeleted,
+		persistence.Item{ID: uuid.MustParse(id)})
+
+	logger.FromContext(ctx).Info("item deleted", zap.String("item", id))
+
+	return id, nil
+}
+
+// AdjustStock implements MutationResolver.
+func (r *mutationResolver) AdjustStock(
+	ctx context.Context,
+	id string,
+	delta float64) (*persistence.Item, error) {
+	v := violations{}
+	v.checkID("id", id)
+	if delta == 0 {
+		v.add("delta", "must not be zero")
+	}
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	item, err := r.storage.AdjustStock(ctx, id, delta)
+	if err != nil {
+		return nil, fail(ctx, err)
+	}
+
+	r.cacheItem(ctx, item)
+	r.publish(ctx, cache.ItemUpdated, item)
+
+	return &item, nil
+}
+
+// Stock implements ItemResolver. The stocks of all items
+// of a query are read at once.
+func (r *itemResolver) Stock(
+	ctx context.Context, obj *persistence.Item) (*persistence.Stock, error) {
+	stock, err := loadersFrom(ctx).stocks.Load(ctx, obj.ID.String())()
+	if err != nil {
+		return nil, fail(ctx, err)
+	}
+
+	return &stock, nil
+}
+
+// Reservations implements ItemResolver. The active
+// reservations of all items of a query are read at once.
+func (r *itemResolver) Reservations(
+	ctx context.Context,
+	obj *persistence.Item) ([]persistence.Reservation, error) {
+	reservations, err := loadersFrom(ctx).reservations.Load(
+		ctx, obj.ID.String())()
+	if err != nil {
+		return nil, fail(ctx, err)
+	}
+
+	return reservations, nil
+}
+
+// cacheItem caches the item for the item query and the
+// REST API. Failures are logged, the cache is optional.
+func (r *Resolver) cacheItem(ctx context.Context, item persistence.Item) {
+	if err := r.cache.Set(
+		ctx,
+		item.ID.String(),
+		item,
+		time.Minute*20); err != nil {
+		logger.FromContext(ctx).Error(err.Error(), zap.Error(err))
+	}
+}
+
+// publish passes the change of the item on to the watchers
+// of the gRPC API. Failures are logged, the change was made
+// regardless.
+func (r *Resolver) publish(
+	ctx context.Context,
+	eventType cache.ItemEventType,
+	item persistence.Item) {
+	if err := r.cache.PublishItemEvent(ctx, cache.ItemEvent{
+		Type: eventType,
+		Item: item,
+	}); err != nil {
+		logger.FromContext(ctx).Error(err.Error(), zap.Error(err))
+	}
+}
+
+// checkID records an invalid ID argument.
+func (v violations) checkID(argument, id string) {
+	if _, err := uuid.Parse(id); err != nil {
+		v.add(argument, "must be a UUID")
+	}
+}
+
+// inputFields maps the item fields of persistence.Violations
+// to the fields of ItemInput that are named differently.
+var inputFields = map[string]string{
+	"expires_at": "expiresAt",
+}
+
+// checkItem records the invalid fields of an item input
+// with the rules of persistence.ValidateItem.
+func (v violations) checkItem(item persistence.Item) {
+	var itemViolations persistence.Violations
+	if errors.As(persistence.ValidateItem(item), &itemViolations) {
+		for _, violation := range itemViolations {
+			field := violation.Field
+			if inputField, ok := inputFields[field]; ok {
+				field = inputField
+			}
+			v.add("input."+field, violation.Description)
+		}
+	}
+}
+
+// itemFromInput converts an ItemInput to a persistence.Item.
+func itemFromInput(input ItemInput) persistence.Item {
+	item := persistence.Item{
+		Name:      input.Name,
+		Unit:      input.Unit,
+		Amount:    input.Amount,
+		ExpiresAt: input.ExpiresAt,
+	}
+	if input.Sku != nil {
+		item.SKU = *input.Sku
+	}
+
+	return item
+}
+
+// filterFromInput converts an ItemFilter to a
+// persistence.ItemFilter. Unset fields do not filter.
+func filterFromInput(filter *ItemFilter) persistence.ItemFilter {
+	var converted persistence.ItemFilter
+	if filter == nil {
+		return converted
+	}
+
+	if filter.Name != nil {
+		converted.Name = *filter.Name
+	}
+	if filter.Unit != nil {
+		converted.Unit = *filter.Unit
+	}
+	if filter.Amount != nil {
+		converted.Amount = *filter.Amount
+	}
+	if filter.ExpiresAt != nil {
+		converted.ExpiresAt = *filter.ExpiresAt
+	}
+	if filter.Sku != nil {
+		converted.SKU = *filter.Sku
+	}
+
+	return converted
+}
+
+// encodeCursor returns the cursor of the item with the ID.
+func encodeCursor(id uuid.UUID) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(id.String()))
+}
+
+// decodeCursor returns the ID of the item of the cursor.
+func decodeCursor(cursor string) (string, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", err
+	}
+
+	id, err := uuid.ParseBytes(decoded)
+	if err != nil {
+		return "", err
+	}
+
+	return id.String(), nil
+}
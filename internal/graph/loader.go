@@ -0,0 +1,96 @@
+package graph
+
+import (
+	"context"
+
+	"github.com/Salam4nder/inventory/internal/persistence"
+
+	"github.com/graph-gophers/dataloader/v7"
+)
+
+type loadersKey struct{}
+
+// loaders batch the reads of the fields resolved per item
+// or reservation, so that a page of items costs a query per
+// field instead of a query per item and field. They cache
+// what they read for the duration of a request.
+type loaders struct {
+	items        *dataloader.Loader[string, persistence.Item]
+	stocks       *dataloader.Loader[string, persistence.Stock]
+	reservations *dataloader.Loader[string, []persistence.Reservation]
+}
+
+// withLoaders returns a copy of ctx with new loaders that
+// read from the storage.
+func withLoaders(
+	ctx context.Context,
+	store persistence.Storage) context.Context {
+	return context.WithValue(ctx, loadersKey{}, &loaders{
+		items: dataloader.NewBatchedLoader(
+			func(ctx context.Context,
+				ids []string) []*dataloader.Result[persistence.Item] {
+				items, err := store.ReadMany(ctx, ids)
+				found := make(map[string]persistence.Item, len(items))
+				for _, item := range items {
+					found[item.ID.String()] = item
+				}
+
+				return results(ids, found, err, true)
+			}),
+		stocks: dataloader.NewBatchedLoader(
+			func(ctx context.Context,
+				ids []string) []*dataloader.Result[persistence.Stock] {
+				stocks, err := store.Stocks(ctx, ids)
+				found := make(map[string]persistence.Stock, len(stocks))
+				for _, stock := range stocks {
+					found[stock.ItemID.String()] = stock
+				}
+
+				return results(ids, found, err, true)
+			}),
+		reservations: dataloader.NewBatchedLoader(
+			func(ctx context.Context,
+				ids []string) []*dataloader.Result[[]persistence.Reservation] {
+				reservations, err := store.ActiveReservations(ctx, ids)
+				found := make(map[string][]persistence.Reservation)
+				for _, reservation := range reservations {
+					id := reservation.ItemID.String()
+					found[id] = append(found[id], reservation)
+				}
+
+				return results(ids, found, err, false)
+			}),
+	})
+}
+
+// loadersFrom returns the loaders of the request.
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
+
+// results orders the values found for the keys of a batch
+// like the keys. Missing values are ErrNotFound if they are
+// required and zero otherwise. If the batch failed, every
+// key fails with its error.
+func results[V any](
+	keys []string,
+	found map[string]V,
+	err error,
+	required bool) []*dataloader.Result[V] {
+	results := make([]*dataloader.Result[V], len(keys))
+
+	for idx, key := range keys {
+		value, ok := found[key]
+
+		switch {
+		case err != nil:
+			results[idx] = &dataloader.Result[V]{Error: err}
+		case !ok && required:
+			results[idx] = &dataloader.Result[V]{Error: persistence.ErrNotFound}
+		default:
+			results[idx] = &dataloader.Result[V]{Data: value}
+		}
+	}
+
+	return results
+}
@@ -0,0 +1,94 @@
+// Code generated by github.com/99designs/gqlgen, DO NOT EDIT.
+
+package graph
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/Salam4nder/inventory/internal/persistence"
+)
+
+type ItemConnection struct {
+	Edges    []ItemEdge `json:"edges"`
+	PageInfo *PageInfo  `json:"pageInfo"`
+}
+
+type ItemEdge struct {
+	Cursor string            `json:"cursor"`
+	Node   *persistence.Item `json:"node"`
+}
+
+// Matches the items equal to all of the set fields.
+type ItemFilter struct {
+	Name      *string    `json:"name"`
+	Unit      *string    `json:"unit"`
+	Amount    *float64   `json:"amount"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+	Sku       *string    `json:"sku"`
+}
+
+type ItemInput struct {
+	Name      string    `json:"name"`
+	Unit      string    `json:"unit"`
+	Amount    float64   `json:"amount"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	Sku       *string   `json:"sku"`
+}
+
+type PageInfo struct {
+	// The cursor of the last item, null if there are none.
+	EndCursor   *string `json:"endCursor"`
+	HasNextPage bool    `json:"hasNextPage"`
+}
+
+type ReservationInput struct {
+	Quantity   float64 `json:"quantity"`
+	Owner      string  `json:"owner"`
+	TTLSeconds int     `json:"ttlSeconds"`
+}
+
+type ReservationStatus string
+
+const (
+	ReservationStatusActive    ReservationStatus = "ACTIVE"
+	ReservationStatusCommitted ReservationStatus = "COMMITTED"
+	ReservationStatusReleased  ReservationStatus = "RELEASED"
+)
+
+var AllReservationStatus = []ReservationStatus{
+	ReservationStatusActive,
+	ReservationStatusCommitted,
+	ReservationStatusReleased,
+}
+
+func (e ReservationStatus) IsValid() bool {
+	switch e {
+	case ReservationStatusActive, ReservationStatusCommitted, ReservationStatusReleased:
+		return true
+	}
+	return false
+}
+
+func (e ReservationStatus) String() string {
+	return string(e)
+}
+
+func (e *ReservationStatus) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ReservationStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ReservationStatus", str)
+	}
+	return nil
+}
+
+func (e ReservationStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
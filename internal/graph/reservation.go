@@ -0,0 +1,143 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Salam4nder/inventory/internal/persistence"
+	"github.com/Salam4nder/inventory/pkg/logger"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Reservation implements QueryResolver.
+func (r *queryResolver) Reservation(
+	ctx context.Context, id string) (*persistence.Reservation, error) {
+	v := violations{}
+	v.checkID("id", id)
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	reservation, err := r.storage.ReadReservation(ctx, id)
+	if errors.Is(err, persistence.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fail(ctx, err)
+	}
+
+	return &reservation, nil
+}
+
+// ReserveItem implements MutationResolver.
+func (r *mutationResolver) ReserveItem(
+	ctx context.Context,
+	id string,
+	input ReservationInput) (*persistence.Reservation, error) {
+	v := violations{}
+	v.checkID("id", id)
+	if input.Quantity <= 0 {
+		v.add("input.quantity", "must be positive")
+	}
+	if input.Owner == "" {
+		v.add("input.owner", "is required")
+	}
+	if input.TTLSeconds <= 0 {
+		v.add("input.ttlSeconds", "must be positive")
+	}
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	reservation, err := r.storage.Reserve(ctx, persistence.Reservation{
+		ItemID:   uuid.MustParse(id),
+		Quantity: input.Quantity,
+		Owner:    input.Owner,
+	}, time.Duration(input.TTLSeconds)*time.Second)
+	if err != nil {
+		return nil, fail(ctx, err)
+	}
+
+	return &reservation, nil
+}
+
+// CommitReservation implements MutationResolver.
+func (r *mutationResolver) CommitReservation(
+	ctx context.Context, id string) (*persistence.Reservation, error) {
+	v := violations{}
+	v.checkID("id", id)
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	reservation, err := r.storage.CommitReservation(ctx, id)
+	if err != nil {
+		return nil, fail(ctx, err)
+	}
+
+	// The amount of the item changed, so the cached
+	// copy is stale.
+	if err := r.cache.Delete(
+		ctx, reservation.ItemID.String()); err != nil {
+		logger.FromContext(ctx).Info(err.Error(), zap.Error(err))
+	}
+
+	return &reservation, nil
+}
+
+// ReleaseReservation implements MutationResolver.
+func (r *mutationResolver) ReleaseReservation(
+	ctx context.Context, id string) (*persistence.Reservation, error) {
+	v := violations{}
+	v.checkID("id", id)
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	reservation, err := r.storage.ReleaseReservation(ctx, id)
+	if err != nil {
+		return nil, fail(ctx, err)
+	}
+
+	return &reservation, nil
+}
+
+// Item implements ReservationResolver. The items of all
+// reservations of a query are read at once. It is null if
+// the item was deleted meanwhile.
+func (r *reservationResolver) Item(
+	ctx context.Context,
+	obj *persistence.Reservation) (*persistence.Item, error) {
+	item, err := loadersFrom(ctx).items.Load(ctx, obj.ItemID.String())()
+	if errors.Is(err, persistence.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fail(ctx, err)
+	}
+
+	return &item, nil
+}
+
+// Status implements ReservationResolver.
+func (r *reservationResolver) Status(
+	ctx context.Context,
+	obj *persistence.Reservation) (ReservationStatus, error) {
+	return ReservationStatus(strings.ToUpper(string(obj.Status))), nil
+}
@@ -0,0 +1,27 @@
+package graph
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/google/uuid"
+)
+
+// MarshalUUID writes the ID of a stored object.
+func MarshalUUID(id uuid.UUID) graphql.Marshaler {
+	return graphql.WriterFunc(func(w io.Writer) {
+		io.WriteString(w, strconv.Quote(id.String()))
+	})
+}
+
+// UnmarshalUUID reads the ID of a stored object.
+func UnmarshalUUID(v interface{}) (uuid.UUID, error) {
+	s, ok := v.(string)
+	if !ok {
+		return uuid.Nil, fmt.Errorf("%T is not an ID", v)
+	}
+
+	return uuid.Parse(s)
+}
@@ -0,0 +1,144 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/Salam4nder/inventory/internal/persistence"
+	"github.com/Salam4nder/inventory/pkg/auth"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// APIKeyResponse is the response of a created API key.
+// The key is only ever returned here.
+type APIKeyResponse struct {
+	persistence.APIKey
+	Key string `json:"key"`
+}
+
+func (s *Server) readAPIKeys(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(
+		c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	keys, err := s.storage.ReadAPIKeys(ctx)
+	if err != nil {
+		s.fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, keys)
+}
+
+func (s *Server) readAPIKey(c *gin.Context) {
+	uuid, found := c.Params.Get("uuid")
+	if !found {
+		abortWithProblem(c, http.StatusBadRequest, "uuid not found")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(
+		c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	key, err := s.storage.ReadAPIKey(ctx, uuid)
+	if err != nil {
+		s.fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, key)
+}
+
+func (s *Server) createAPIKey(c *gin.Context) {
+	var keyRequest APIKeyRequest
+
+	if err := c.ShouldBindJSON(&keyRequest); err != nil {
+		s.invalid(c, err)
+		return
+	}
+
+	secret, prefix, hash, err := auth.NewAPIKey()
+	if err != nil {
+		s.fail(c, err)
+		return
+	}
+
+	apiKey := keyRequest.ToPersistenceAPIKey()
+	apiKey.Prefix = prefix
+	apiKey.KeyHash = hash
+	if claims := claimsFrom(c); claims != nil {
+		apiKey.CreatedBy = claims.Username
+	}
+
+	ctx, cancel := context.WithTimeout(
+		c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	created, err := s.storage.CreateAPIKey(ctx, apiKey)
+	if err != nil {
+		s.fail(c, err)
+		return
+	}
+
+	s.log(c).Info("api key created", append(
+		callerFields(c), zap.String("api_key", created.ID.String()))...)
+
+	c.JSON(http.StatusCreated, APIKeyResponse{APIKey: created, Key: secret})
+}
+
+func (s *Server) updateAPIKey(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("uuid"))
+	if err != nil {
+		abortWithProblem(c, http.StatusBadRequest, "invalid uuid")
+		return
+	}
+
+	var keyRequest APIKeyRequest
+
+	if err := c.ShouldBindJSON(&keyRequest); err != nil {
+		s.invalid(c, err)
+		return
+	}
+
+	apiKey := keyRequest.ToPersistenceAPIKey()
+	apiKey.ID = id
+
+	ctx, cancel := context.WithTimeout(
+		c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	updated, err := s.storage.UpdateAPIKey(ctx, apiKey)
+	if err != nil {
+		s.fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+func (s *Server) deleteAPIKey(c *gin.Context) {
+	uuid, found := c.Params.Get("uuid")
+	if !found {
+		abortWithProblem(c, http.StatusBadRequest, "uuid not found")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(
+		c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := s.storage.DeleteAPIKey(ctx, uuid); err != nil {
+		s.fail(c, err)
+		return
+	}
+
+	s.log(c).Info("api key deleted", append(
+		callerFields(c), zap.String("api_key", uuid))...)
+
+	c.Status(http.StatusNoContent)
+}
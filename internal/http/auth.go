@@ -0,0 +1,225 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Salam4nder/inventory/internal/persistence"
+	"github.com/Salam4nder/inventory/pkg/auth"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// dummyHash is compared against when a username does not
+// exist, so unknown and known usernames take equally long.
+const dummyHash = "$2a$10$TxMxY0ctIzfchhTGChPl1.FZXC8WWCxsa4C/hEByUWGdNk/X8rVhS"
+
+// TokenResponse is the response of a successful login.
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+	// RefreshToken is an opaque token that can be exchanged
+	// for a new access token once. Each exchange returns a
+	// new refresh token.
+	RefreshToken string `json:"refresh_token,omitempty"`
+}
+
+func (s *Server) login(c *gin.Context) {
+	var loginRequest LoginRequest
+
+	if err := c.ShouldBindJSON(&loginRequest); err != nil {
+		s.invalid(c, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(
+		c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	user, err := s.storage.ReadUserByUsername(
+		ctx, loginRequest.Username)
+	if err != nil && !errors.Is(err, persistence.ErrNotFound) {
+		s.fail(c, err)
+		return
+	}
+
+	hash := user.PasswordHash
+	if hash == "" {
+		hash = dummyHash
+	}
+
+	if err := auth.ComparePassword(
+		hash, loginRequest.Password); err != nil || user.PasswordHash == "" {
+		abortWithProblem(c, http.StatusUnauthorized, "invalid username or password")
+		return
+	}
+
+	if user.Disabled {
+		abortWithProblem(c, http.StatusForbidden, "user is disabled")
+		return
+	}
+
+	refreshToken, hash, err := auth.NewRefreshToken()
+	if err != nil {
+		s.fail(c, err)
+		return
+	}
+
+	// The user was found across tenants, the refresh token
+	// belongs to the tenant of the user.
+	ctx = persistence.WithTenant(ctx, user.TenantID)
+
+	if _, err := s.storage.CreateRefreshToken(
+		ctx, persistence.RefreshToken{
+			UserID:    user.ID,
+			TokenHash: hash,
+			ExpiresAt: time.Now().Add(s.config.RefreshTokenExpiration),
+		}); err != nil {
+		s.fail(c, err)
+		return
+	}
+
+	response, err := s.tokenResponse(user, refreshToken)
+	if err != nil {
+		s.fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// refresh exchanges a refresh token for a new access token
+// and a new refresh token. A refresh token can only be used
+// once, reusing it revokes all tokens issued since login.
+func (s *Server) refresh(c *gin.Context) {
+	var refreshRequest RefreshRequest
+
+	if err := c.ShouldBindJSON(&refreshRequest); err != nil {
+		s.invalid(c, err)
+		return
+	}
+
+	refreshToken, hash, err := auth.NewRefreshToken()
+	if err != nil {
+		s.fail(c, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(
+		c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	rotated, err := s.storage.RotateRefreshToken(
+		ctx,
+		auth.HashRefreshToken(refreshRequest.RefreshToken),
+		persistence.RefreshToken{
+			TokenHash: hash,
+			ExpiresAt: time.Now().Add(s.config.RefreshTokenExpiration),
+		})
+	if err != nil {
+		switch {
+		case errors.Is(err, persistence.ErrNotFound):
+			abortWithProblem(c, http.StatusUnauthorized, "invalid refresh token")
+			return
+		case errors.Is(err, persistence.ErrTokenReused),
+			errors.Is(err, persistence.ErrTokenExpired):
+			s.log(c).Info(err.Error(), zap.Error(err))
+		}
+		s.fail(c, err)
+		return
+	}
+
+	ctx = persistence.WithTenant(ctx, rotated.TenantID)
+
+	user, err := s.storage.ReadUser(ctx, rotated.UserID.String())
+	if err != nil {
+		s.fail(c, err)
+		return
+	}
+
+	if user.Disabled {
+		if err := s.storage.RevokeRefreshTokenFamily(
+			ctx, hash); err != nil {
+			s.log(c).Error(err.Error(), zap.Error(err))
+		}
+		abortWithProblem(c, http.StatusForbidden, "user is disabled")
+		return
+	}
+
+	response, err := s.tokenResponse(user, refreshToken)
+	if err != nil {
+		s.fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// logout revokes the given refresh token and every token
+// rotated from the same login.
+func (s *Server) logout(c *gin.Context) {
+	var logoutRequest RefreshRequest
+
+	if err := c.ShouldBindJSON(&logoutRequest); err != nil {
+		s.invalid(c, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(
+		c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := s.storage.RevokeRefreshTokenFamily(
+		ctx,
+		auth.HashRefreshToken(logoutRequest.RefreshToken)); err != nil {
+		s.fail(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// jwks publishes the public keys access tokens are verified
+// with. Keys are cached for a short time only, so clients
+// pick up rotated keys quickly.
+func (s *Server) jwks(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, s.keys.JWKS())
+}
+
+// tokenResponse issues an access token for the user and
+// returns it alongside the given refresh token.
+func (s *Server) tokenResponse(
+	user persistence.User, refreshToken string) (TokenResponse, error) {
+	token, err := s.keys.NewJWT(
+		s.config.AccessTokenExpiration, claimsFor(user))
+	if err != nil {
+		return TokenResponse{}, err
+	}
+
+	return TokenResponse{
+		AccessToken:  token,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(s.config.AccessTokenExpiration.Seconds()),
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+// claimsFor returns the access token claims of a user.
+func claimsFor(user persistence.User) auth.Claims {
+	role := auth.Role(user.Role)
+
+	claims := auth.Claims{
+		Username:    user.Username,
+		Tenant:      user.TenantID,
+		Roles:       []auth.Role{role},
+		Permissions: role.Permissions(),
+	}
+	claims.Subject = user.ID.String()
+
+	return claims
+}
@@ -0,0 +1,162 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Salam4nder/inventory/internal/persistence"
+	"github.com/Salam4nder/inventory/pkg/auth"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// BatchResponse is the response of the batch endpoint.
+type BatchResponse struct {
+	Atomic    bool                   `json:"atomic"`
+	Succeeded int                    `json:"succeeded"`
+	Failed    int                    `json:"failed"`
+	Results   []BatchOperationResult `json:"results"`
+}
+
+// BatchOperationResult is the outcome of a single
+// operation of a BatchRequest.
+type BatchOperationResult struct {
+	Index  int                 `json:"index"`
+	Op     persistence.BatchOp `json:"op"`
+	ID     uuid.UUID           `json:"uuid,omitempty"`
+	Status int                 `json:"status"`
+	Error  string              `json:"error,omitempty"`
+}
+
+func (s *Server) batchItems(c *gin.Context) {
+	var batchRequest BatchRequest
+
+	if err := c.ShouldBindJSON(&batchRequest); err != nil {
+		s.invalid(c, err)
+		return
+	}
+
+	response := BatchResponse{
+		Atomic: batchRequest.Atomic,
+		Results: make(
+			[]BatchOperationResult, len(batchRequest.Operations)),
+	}
+
+	// Operations that fail validation never reach the
+	// storage, indexes maps the valid ones back to their
+	// position in the request.
+	var (
+		ops     []persistence.BatchOperation
+		indexes []int
+	)
+
+	for idx, opRequest := range batchRequest.Operations {
+		response.Results[idx] = BatchOperationResult{
+			Index: idx,
+			Op:    opRequest.Op,
+			ID:    opRequest.ID,
+		}
+
+		// The route only requires the write permission,
+		// deletes are checked one by one.
+		if opRequest.Op == persistence.BatchDelete &&
+			!hasPermission(c, auth.PermissionItemsDelete) {
+			response.Results[idx].Status = http.StatusForbidden
+			response.Results[idx].Error = "missing permission " +
+				string(auth.PermissionItemsDelete)
+			continue
+		}
+
+		op, err := opRequest.ToPersistenceOperation()
+		if err != nil {
+			response.Results[idx].Status = http.StatusBadRequest
+			response.Results[idx].Error = err.Error()
+			continue
+		}
+
+		ops = append(ops, op)
+		indexes = append(indexes, idx)
+	}
+
+	if len(ops) < len(batchRequest.Operations) && batchRequest.Atomic {
+		response.finish(persistence.ErrBatchAborted)
+		c.JSON(http.StatusUnprocessableEntity, response)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(
+		c.Request.Context(), 60*time.Second)
+	defer cancel()
+
+	results, err := s.storage.Batch(ctx, ops, batchRequest.Atomic)
+	if err != nil && !errors.Is(err, persistence.ErrBatchAborted) {
+		s.fail(c, err)
+		return
+	}
+
+	for _, result := range results {
+		opResult := &response.Results[indexes[result.Index]]
+		opResult.ID = result.ID
+		opResult.Status = batchStatus(result)
+
+		if result.Err != nil {
+			opResult.Error = result.Err.Error()
+			continue
+		}
+
+		if result.Op == persistence.BatchCreate {
+			continue
+		}
+
+		// Updated and deleted items must not be served
+		// from the cache anymore.
+		if err := s.cache.Delete(ctx, result.ID.String()); err != nil {
+			s.log(c).Debug(err.Error(), zap.Error(err))
+		}
+	}
+
+	response.finish(err)
+
+	switch {
+	case err != nil:
+		c.JSON(http.StatusUnprocessableEntity, response)
+	case response.Failed > 0:
+		c.JSON(http.StatusMultiStatus, response)
+	default:
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// finish counts the results. Operations without a status
+// are marked with the given error, if any.
+func (r *BatchResponse) finish(err error) {
+	for idx := range r.Results {
+		result := &r.Results[idx]
+
+		if result.Status == 0 && err != nil {
+			result.Status = http.StatusConflict
+			result.Error = err.Error()
+		}
+
+		if result.Error != "" {
+			r.Failed++
+			continue
+		}
+		r.Succeeded++
+	}
+}
+
+func batchStatus(result persistence.BatchResult) int {
+	switch {
+	case result.Err == nil && result.Op == persistence.BatchCreate:
+		return http.StatusCreated
+	case result.Err == nil:
+		return http.StatusOK
+	default:
+		return problemStatus(result.Err, http.StatusUnprocessableEntity)
+	}
+}
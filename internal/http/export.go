@@ -0,0 +1,233 @@
+package http
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Salam4nder/inventory/internal/persistence"
+	"github.com/Salam4nder/inventory/pkg/xlsx"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// Formats the item listings can be exported in.
+const (
+	formatJSON   = "json"
+	formatCSV    = "csv"
+	formatXLSX   = "xlsx"
+	formatNDJSON = "ndjson"
+)
+
+// MIME types of the export formats.
+const (
+	mimeCSV    = "text/csv"
+	mimeXLSX   = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	mimeNDJSON = "application/x-ndjson"
+)
+
+var formatMIME = map[string]string{
+	formatJSON:   gin.MIMEJSON,
+	formatCSV:    mimeCSV,
+	formatXLSX:   mimeXLSX,
+	formatNDJSON: mimeNDJSON,
+}
+
+// exportHeader is the header row of CSV and XLSX exports.
+var exportHeader = []string{
+	"id", "name", "unit", "amount", "expires_at", "sku"}
+
+// itemWriter writes items in one of the export formats.
+type itemWriter interface {
+	Write(item persistence.Item) error
+	Close() error
+}
+
+// exportFormat returns the format of a listing. The format
+// query parameter takes precedence over the Accept header.
+// An empty string is returned if the format is not supported.
+func exportFormat(c *gin.Context) string {
+	if format := c.Query("format"); format != "" {
+		if _, ok := formatMIME[format]; !ok {
+			return ""
+		}
+		return format
+	}
+
+	switch c.NegotiateFormat(
+		gin.MIMEJSON, mimeCSV, mimeXLSX, mimeNDJSON) {
+	case gin.MIMEJSON:
+		return formatJSON
+	case mimeCSV:
+		return formatCSV
+	case mimeXLSX:
+		return formatXLSX
+	case mimeNDJSON:
+		return formatNDJSON
+	default:
+		return ""
+	}
+}
+
+// exportItems streams the items matching the filter to the
+// client in the given format. Rows are written as they are
+// read from the database, so not even JSON listings hold
+// the whole inventory in memory. Only the other formats are
+// sent as attachments.
+func (s *Server) exportItems(
+	c *gin.Context, format string, filter persistence.ItemFilter) {
+	ctx, cancel := context.WithTimeout(
+		c.Request.Context(), 5*time.Minute)
+	defer cancel()
+
+	c.Header("Content-Type", formatMIME[format])
+	if format != formatJSON {
+		c.Header("Content-Disposition", fmt.Sprintf(
+			`attachment; filename="inventory-%s.%s"`,
+			time.Now().Format("20060102-150405"), format))
+	}
+	c.Status(http.StatusOK)
+
+	writer, err := newItemWriter(c.Writer, format)
+	if err != nil {
+		s.log(c).Error(err.Error(), zap.Error(err))
+		return
+	}
+
+	if err := s.storage.Iterate(ctx, filter, writer.Write); err != nil {
+		// The status is already sent once rows are written,
+		// so errors can only be logged from then on.
+		if !c.Writer.Written() {
+			c.Writer.Header().Del("Content-Disposition")
+			s.fail(c, err)
+			return
+		}
+
+		s.log(c).Error(err.Error(), zap.Error(err))
+		return
+	}
+
+	if err := writer.Close(); err != nil {
+		s.log(c).Error(err.Error(), zap.Error(err))
+	}
+}
+
+func newItemWriter(w io.Writer, format string) (itemWriter, error) {
+	switch format {
+	case formatCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(exportHeader); err != nil {
+			return nil, err
+		}
+		return &csvItemWriter{writer: writer}, nil
+	case formatXLSX:
+		writer, err := xlsx.NewWriter(w, "Inventory")
+		if err != nil {
+			return nil, err
+		}
+
+		header := make([]interface{}, len(exportHeader))
+		for idx, column := range exportHeader {
+			header[idx] = column
+		}
+		if err := writer.WriteRow(header...); err != nil {
+			return nil, err
+		}
+
+		return &xlsxItemWriter{writer: writer}, nil
+	case formatJSON:
+		return &jsonItemWriter{writer: w}, nil
+	default:
+		return &ndjsonItemWriter{encoder: json.NewEncoder(w)}, nil
+	}
+}
+
+type csvItemWriter struct {
+	writer *csv.Writer
+}
+
+func (w *csvItemWriter) Write(item persistence.Item) error {
+	return w.writer.Write([]string{
+		item.ID.String(),
+		item.Name,
+		item.Unit,
+		strconv.FormatFloat(item.Amount, 'f', -1, 64),
+		item.ExpiresAt.Format(time.RFC3339),
+		item.SKU,
+	})
+}
+
+func (w *csvItemWriter) Close() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+type xlsxItemWriter struct {
+	writer *xlsx.Writer
+}
+
+func (w *xlsxItemWriter) Write(item persistence.Item) error {
+	return w.writer.WriteRow(
+		item.ID.String(),
+		item.Name,
+		item.Unit,
+		item.Amount,
+		item.ExpiresAt,
+		item.SKU)
+}
+
+func (w *xlsxItemWriter) Close() error {
+	return w.writer.Close()
+}
+
+type ndjsonItemWriter struct {
+	encoder *json.Encoder
+}
+
+func (w *ndjsonItemWriter) Write(item persistence.Item) error {
+	return w.encoder.Encode(item)
+}
+
+func (w *ndjsonItemWriter) Close() error {
+	return nil
+}
+
+// jsonItemWriter writes the items as a JSON array. The
+// opening bracket is written with the first item, so nothing
+// is sent before the first row is read.
+type jsonItemWriter struct {
+	writer  io.Writer
+	written bool
+}
+
+func (w *jsonItemWriter) Write(item persistence.Item) error {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+
+	separator := ","
+	if !w.written {
+		separator = "["
+		w.written = true
+	}
+
+	_, err = w.writer.Write(append([]byte(separator), data...))
+	return err
+}
+
+func (w *jsonItemWriter) Close() error {
+	closing := "]"
+	if !w.written {
+		closing = "[]"
+	}
+
+	_, err := io.WriteString(w.writer, closing)
+	return err
+}
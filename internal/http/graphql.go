@@ -0,0 +1,25 @@
+package http
+
+import (
+	"context"
+	"time"
+
+	"github.com/Salam4nder/inventory/internal/graph"
+
+	"github.com/gin-gonic/gin"
+)
+
+// graphQL serves the GraphQL API. Its fields check the
+// permissions of the caller themselves, since one query
+// may both read and write items.
+func (s *Server) graphQL() gin.HandlerFunc {
+	handler := graph.NewHandler(s.config, s.storage, s.cache)
+
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(
+			c.Request.Context(), 5*time.Second)
+		defer cancel()
+
+		handler.ServeHTTP(c.Writer, c.Request.WithContext(ctx))
+	}
+}
@@ -2,21 +2,22 @@ package http
 
 import (
 	"context"
-	"errors"
 	"net/http"
 	"time"
 
+	"github.com/Salam4nder/inventory/internal/cache"
 	"github.com/Salam4nder/inventory/internal/persistence"
-	"github.com/Salam4nder/inventory/pkg/auth"
+	"github.com/Salam4nder/inventory/pkg/logger"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
 func (s *Server) readItem(c *gin.Context) {
 	uuid, found := c.Params.Get("uuid")
 	if !found {
-		c.JSON(http.StatusBadRequest, "uuid not found")
+		abortWithProblem(c, http.StatusBadRequest, "uuid not found")
 		return
 	}
 
@@ -32,13 +33,7 @@ func (s *Server) readItem(c *gin.Context) {
 
 	item, err := s.storage.Read(ctx, uuid)
 	if err != nil {
-		if errors.Is(err, persistence.ErrNotFound) {
-			c.JSON(http.StatusNotFound, err)
-			return
-		}
-		s.logger.Error(err.Error(), zap.Error(err))
-		c.JSON(http.StatusInternalServerError, err)
-
+		s.fail(c, err)
 		return
 	}
 
@@ -46,63 +41,51 @@ func (s *Server) readItem(c *gin.Context) {
 }
 
 func (s *Server) readItems(c *gin.Context) {
-	ctx, cancel := context.WithTimeout(
-		c.Request.Context(), 5*time.Second)
-	defer cancel()
-
-	items, err := s.storage.ReadAll(ctx)
-	if err != nil {
-		if errors.Is(err, persistence.ErrNotFound) {
-			c.JSON(http.StatusNotFound, err)
-			return
-		}
-		s.logger.Error(err.Error(), zap.Error(err))
-		c.JSON(http.StatusInternalServerError, err)
-
+	format := exportFormat(c)
+	if format == "" {
+		abortWithProblem(c, http.StatusNotAcceptable, "unsupported format")
 		return
 	}
 
-	c.JSON(http.StatusOK, items)
+	s.exportItems(c, format, persistence.ItemFilter{})
 }
 
 func (s *Server) readItemsBy(c *gin.Context) {
 	var filter persistence.ItemFilter
 
 	if err := c.ShouldBindJSON(&filter); err != nil {
-		s.logger.Error(err.Error(), zap.Error(err))
-		c.JSON(http.StatusBadRequest, err)
+		s.invalid(c, err)
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(
-		c.Request.Context(), 5*time.Second)
-	defer cancel()
-
-	items, err := s.storage.ReadBy(ctx, filter)
-	if err != nil {
-		if errors.Is(err, persistence.ErrNotFound) {
-			c.JSON(http.StatusNotFound, err)
-			return
-		}
-		s.logger.Error(err.Error(), zap.Error(err))
-		c.JSON(http.StatusInternalServerError, err)
+	// Listing every item is what GET /api/item is for.
+	if filter.IsZero() {
+		abortWithProblem(c, http.StatusBadRequest, "empty filter")
+		return
+	}
 
+	format := exportFormat(c)
+	if format == "" {
+		abortWithProblem(c, http.StatusNotAcceptable, "unsupported format")
 		return
 	}
 
-	c.JSON(http.StatusOK, items)
+	s.exportItems(c, format, filter)
 }
 
 func (s *Server) createItem(c *gin.Context) {
 	var createRequest CreateItemRequest
 
 	if err := c.ShouldBindJSON(&createRequest); err != nil {
-		s.logger.Error(err.Error(), zap.Error(err))
-		c.JSON(http.StatusBadRequest, err)
+		s.invalid(c, err)
 		return
 	}
 
 	item := createRequest.ToPersistenceItem()
+	if err := persistence.ValidateItem(item); err != nil {
+		s.invalid(c, err)
+		return
+	}
 
 	ctx, cancel := context.WithTimeout(
 		c.Request.Context(), 5*time.Second)
@@ -110,32 +93,49 @@ func (s *Server) createItem(c *gin.Context) {
 
 	uuid, err := s.storage.Create(ctx, item)
 	if err != nil {
-		s.logger.Error(err.Error(), zap.Error(err))
-		c.JSON(http.StatusInternalServerError, err)
+		s.fail(c, err)
 		return
 	}
+	item.ID = uuid
 
 	if err := s.cache.Set(
 		ctx,
 		uuid.String(),
 		item,
 		time.Minute*20); err != nil {
-		s.logger.Error(err.Error(), zap.Error(err))
+		s.log(c).Error(err.Error(), zap.Error(err))
 	}
 
+	s.publish(ctx, cache.ItemCreated, item)
+
 	c.JSON(http.StatusOK, uuid)
 }
 
 func (s *Server) updateItem(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("uuid"))
+	if err != nil {
+		abortWithProblem(c, http.StatusBadRequest, "invalid uuid")
+		return
+	}
+
 	var updateRequest UpdateItemRequest
 
-	if err := c.ShouldBindJSON(updateRequest); err != nil {
-		s.logger.Error(err.Error(), zap.Error(err))
-		c.JSON(http.StatusBadRequest, err)
+	if err := c.ShouldBindJSON(&updateRequest); err != nil {
+		s.invalid(c, err)
+		return
+	}
+
+	if updateRequest.ID != id {
+		abortWithProblem(c, http.StatusBadRequest,
+			"uuid of the body does not match the path")
 		return
 	}
 
 	item := updateRequest.ToPersistenceItem()
+	if err := persistence.ValidateItem(item); err != nil {
+		s.invalid(c, err)
+		return
+	}
 
 	ctx, cancel := context.WithTimeout(
 		c.Request.Context(), 5*time.Second)
@@ -144,13 +144,7 @@ func (s *Server) updateItem(c *gin.Context) {
 	updatedItem, err := s.storage.Update(
 		ctx, item)
 	if err != nil {
-		if errors.Is(err, persistence.ErrNotFound) {
-			c.JSON(http.StatusNotFound, err)
-			return
-		}
-		s.logger.Error(err.Error(), zap.Error(err))
-		c.JSON(http.StatusInternalServerError, err)
-
+		s.fail(c, err)
 		return
 	}
 
@@ -159,16 +153,18 @@ func (s *Server) updateItem(c *gin.Context) {
 		updatedItem.ID.String(),
 		updatedItem,
 		time.Minute*20); err != nil {
-		s.logger.Error(err.Error(), zap.Error(err))
+		s.log(c).Error(err.Error(), zap.Error(err))
 	}
 
+	s.publish(ctx, cache.ItemUpdated, updatedItem)
+
 	c.JSON(http.StatusOK, updatedItem)
 }
 
 func (s *Server) deleteItem(c *gin.Context) {
-	uuid, found := c.Params.Get("uuid")
-	if !found {
-		c.JSON(http.StatusBadRequest, "uuid not found")
+	id, err := uuid.Parse(c.Param("uuid"))
+	if err != nil {
+		abortWithProblem(c, http.StatusBadRequest, "invalid uuid")
 		return
 	}
 
@@ -176,76 +172,36 @@ func (s *Server) deleteItem(c *gin.Context) {
 		c.Request.Context(), 5*time.Second)
 	defer cancel()
 
-	if err := s.storage.Delete(ctx, uuid); err != nil {
-		if errors.Is(err, persistence.ErrNotFound) {
-			c.JSON(http.StatusNotFound, err)
-			return
-		}
-		s.logger.Error(err.Error(), zap.Error(err))
-		c.JSON(http.StatusInternalServerError, err)
-
+	if err := s.storage.Delete(ctx, id.String()); err != nil {
+		s.fail(c, err)
 		return
 	}
 
-	if err := s.cache.Delete(ctx, uuid); err != nil {
-		s.logger.Info(err.Error(), zap.Error(err))
+	if err := s.cache.Delete(ctx, id.String()); err != nil {
+		s.log(c).Info(err.Error(), zap.Error(err))
 	}
 
-	c.JSON(http.StatusOK,
-		gin.H{"deleted": uuid})
-}
-
-// health check
-func (s *Server) health(c *gin.Context) {
-	var health []Health
+	s.publish(ctx, cache.ItemDeleted, persistence.Item{ID: id})
 
-	dbStatus := "Healthy"
-	cacheStatus := "Healthy"
-	serviceStatus := "Healthy"
+	s.log(c).Info("item deleted", append(
+		callerFields(c), zap.String("item", id.String()))...)
 
-	ctx, cancel := context.WithTimeout(
-		c.Request.Context(), 5*time.Second)
-	defer cancel()
-
-	err := s.storage.Ping(ctx)
-	if err != nil {
-		dbStatus = "Unhealthy"
-	}
-
-	err = s.cache.Ping(ctx)
-	if err != nil {
-		cacheStatus = "Unhealthy"
-	}
-
-	health = append(health, Health{
-		Service: "Database",
-		Status:  dbStatus,
-		Time:    time.Now().Local().Format(time.DateTime),
-	})
-	health = append(health, Health{
-		Service: "Cache",
-		Status:  cacheStatus,
-		Time:    time.Now().Local().Format(time.DateTime),
-	})
-	health = append(health, Health{
-		Service: "Service",
-		Status:  serviceStatus,
-		Time:    time.Now().Local().Format(time.DateTime),
-	})
-
-	c.JSON(http.StatusOK, health)
+	c.JSON(http.StatusOK,
+		gin.H{"deleted": id})
 }
 
-// Temporary, will add auth to the endpoint that creates
-// a new JWT token.
-func (s *Server) newJWT(c *gin.Context) {
-	token, err := auth.NewJWT(
-		s.config.JWTSecret, s.config.AccessTokenExpiration)
-	if err != nil {
-		s.logger.Error(err.Error(), zap.Error(err))
-		c.JSON(http.StatusInternalServerError, err)
-		return
+// publish passes the change of the item on to the watchers
+// of the gRPC API. Failures are logged, the change was made
+// regardless. Changes made by batches and imports are not
+// published.
+func (s *Server) publish(
+	ctx context.Context,
+	eventType cache.ItemEventType,
+	item persistence.Item) {
+	if err := s.cache.PublishItemEvent(ctx, cache.ItemEvent{
+		Type: eventType,
+		Item: item,
+	}); err != nil {
+		logger.FromContext(ctx).Error(err.Error(), zap.Error(err))
 	}
-
-	c.JSON(http.StatusOK, token)
 }
@@ -0,0 +1,660 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Salam4nder/inventory/internal/authn"
+	"github.com/Salam4nder/inventory/internal/cache"
+	"github.com/Salam4nder/inventory/internal/config"
+	"github.com/Salam4nder/inventory/internal/metrics"
+	"github.com/Salam4nder/inventory/internal/persistence"
+	"github.com/Salam4nder/inventory/pkg/auth"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// storage keeps items in memory. Calls of methods it does
+// not implement panic.
+type storage struct {
+	persistence.Storage
+
+	items    map[uuid.UUID]persistence.Item
+	users    map[uuid.UUID]persistence.User
+	sessions map[string]bool
+	// create is called by Create if set, to fail or panic.
+	create func() error
+	// ping is returned by Ping.
+	ping error
+}
+
+func (s *storage) Ping(context.Context) error {
+	return s.ping
+}
+
+func (s *storage) Create(
+	ctx context.Context, item persistence.Item) (uuid.UUID, error) {
+	if s.create != nil {
+		if err := s.create(); err != nil {
+			return uuid.Nil, err
+		}
+	}
+
+	item.ID = uuid.New()
+	s.items[item.ID] = item
+
+	return item.ID, nil
+}
+
+func (s *storage) Read(
+	ctx context.Context, id string) (persistence.Item, error) {
+	item, ok := s.items[uuid.MustParse(id)]
+	if !ok {
+		return persistence.Item{}, persistence.ErrNotFound
+	}
+
+	return item, nil
+}
+
+func (s *storage) Iterate(
+	ctx context.Context,
+	filter persistence.ItemFilter,
+	fn func(persistence.Item) error) error {
+	for _, item := range s.items {
+		if filter.Name != "" && filter.Name != item.Name {
+			continue
+		}
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *storage) Update(
+	ctx context.Context, item persistence.Item) (persistence.Item, error) {
+	if _, ok := s.items[item.ID]; !ok {
+		return persistence.Item{}, persistence.ErrNotFound
+	}
+
+	s.items[item.ID] = item
+
+	return item, nil
+}
+
+func (s *storage) Delete(ctx context.Context, id string) error {
+	if _, ok := s.items[uuid.MustParse(id)]; !ok {
+		return persistence.ErrNotFound
+	}
+
+	delete(s.items, uuid.MustParse(id))
+
+	return nil
+}
+
+func (s *storage) ReadUser(
+	ctx context.Context, id string) (persistence.User, error) {
+	user, ok := s.users[uuid.MustParse(id)]
+	if !ok || user.TenantID != persistence.TenantFrom(ctx) {
+		return persistence.User{}, persistence.ErrNotFound
+	}
+
+	return user, nil
+}
+
+func (s *storage) CreateUser(
+	ctx context.Context, user persistence.User) (persistence.User, error) {
+	user.ID = uuid.New()
+	user.TenantID = persistence.TenantFrom(ctx)
+	s.users[user.ID] = user
+
+	return user, nil
+}
+
+func (s *storage) SetUserRole(
+	ctx context.Context, id, role string) (persistence.User, error) {
+	user, err := s.ReadUser(ctx, id)
+	if err != nil {
+		return persistence.User{}, err
+	}
+
+	user.Role = role
+	s.users[user.ID] = user
+
+	return user, nil
+}
+
+func (s *storage) RevokeUserRefreshTokens(
+	ctx context.Context, userID string) error {
+	delete(s.sessions, userID)
+
+	return nil
+}
+
+// memory is a cache that is always missed and keeps no
+// state beyond what the tests look at.
+type memory struct {
+	cache.Service
+}
+
+func (memory) Get(context.Context, string) (persistence.Item, error) {
+	return persistence.Item{}, errors.New("cache miss")
+}
+
+func (memory) Set(context.Context, string,
+	persistence.Item, time.Duration) error {
+	return nil
+}
+
+func (memory) Delete(context.Context, string) error {
+	return nil
+}
+
+func (memory) Ping(context.Context) error {
+	return nil
+}
+
+func (memory) TokenRevoked(
+	context.Context, string, string, time.Time) (bool, error) {
+	return false, nil
+}
+
+func (memory) PublishItemEvent(context.Context, cache.ItemEvent) error {
+	return nil
+}
+
+// denylist is a cache that keeps the revoked subjects per
+// tenant, or fails with err if it is set.
+type denylist struct {
+	memory
+
+	err      error
+	subjects map[string]time.Time
+}
+
+func (d *denylist) RevokeSubject(
+	ctx context.Context,
+	subject string,
+	at time.Time,
+	expiration time.Duration) error {
+	d.subjects[persistence.TenantFrom(ctx)+"/"+subject] = at
+
+	return nil
+}
+
+func (d *denylist) TokenRevoked(
+	ctx context.Context,
+	tokenID, subject string,
+	issuedAt time.Time) (bool, error) {
+	if d.err != nil {
+		return false, d.err
+	}
+
+	at, ok := d.subjects[persistence.TenantFrom(ctx)+"/"+subject]
+
+	return ok && !issuedAt.After(at), nil
+}
+
+type fixture struct {
+	server  *Server
+	storage *storage
+	keys    *auth.KeySet
+}
+
+// setup returns a fixture whose database is migrated.
+func setup(t *testing.T, cfg config.Server, cache cache.Service) *fixture {
+	t.Helper()
+
+	f := setupPending(t, cfg, cache)
+	f.server.MigrationsDone()
+
+	return f
+}
+
+// setupPending returns a fixture whose database is
+// not migrated yet.
+func setupPending(
+	t *testing.T, cfg config.Server, cache cache.Service) *fixture {
+	t.Helper()
+
+	gin.SetMode(gin.TestMode)
+
+	keys, err := auth.NewHMACKeySet("secret")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	store := &storage{
+		items:    make(map[uuid.UUID]persistence.Item),
+		users:    make(map[uuid.UUID]persistence.User),
+		sessions: make(map[string]bool),
+	}
+
+	s := New(cfg, store, cache, keys,
+		authn.New(keys, nil, store, cache), metrics.New(), zap.NewNop())
+	s.initEndpoints()
+
+	return &fixture{server: s, storage: store, keys: keys}
+}
+
+// do sends the request as a user of the acme tenant with
+// the given role.
+func (f *fixture) do(
+	t *testing.T,
+	role auth.Role,
+	method, path, body string,
+	header http.Header) *httptest.ResponseRecorder {
+	t.Helper()
+
+	token, err := f.keys.NewJWT(time.Minute, auth.Claims{
+		Username:    "user",
+		Tenant:      "acme",
+		Roles:       []auth.Role{role},
+		Permissions: role.Permissions(),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", gin.MIMEJSON)
+	req.Header.Set("Authorization", "Bearer "+token)
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	w := httptest.NewRecorder()
+	f.server.http.Handler.ServeHTTP(w, req)
+
+	return w
+}
+
+func TestReservationRoutes_Invalid_UUID(t *testing.T) {
+	f := setup(t, config.Server{}, memory{})
+
+	tests := []struct {
+		method string
+		path   string
+	}{
+		{http.MethodGet, "/api/item/1/stock"},
+		{http.MethodGet, "/api/reservation/1"},
+		{http.MethodPost, "/api/reservation/1/commit"},
+		{http.MethodPost, "/api/reservation/1/release"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.method+" "+test.path, func(t *testing.T) {
+			w := f.do(t, auth.RoleAdmin, test.method, test.path, "", nil)
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("unexpected status: %d %s", w.Code, w.Body)
+			}
+		})
+	}
+}
+
+func TestReadItems_JSON(t *testing.T) {
+	f := setup(t, config.Server{}, memory{})
+
+	w := f.do(t, auth.RoleViewer, http.MethodGet, "/api/item", "", nil)
+	if w.Code != http.StatusOK || w.Body.String() != "[]" {
+		t.Fatalf("unexpected response: %d %s", w.Code, w.Body)
+	}
+
+	for _, name := range []string{"flour", "sugar"} {
+		item := persistence.Item{ID: uuid.New(), Name: name, Unit: "kg"}
+		f.storage.items[item.ID] = item
+	}
+
+	w = f.do(t, auth.RoleViewer, http.MethodGet, "/api/item", "", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d %s", w.Code, w.Body)
+	}
+
+	if w.Header().Get("Content-Disposition") != "" {
+		t.Errorf("JSON listing is sent as an attachment")
+	}
+
+	var items []persistence.Item
+	if err := json.Unmarshal(w.Body.Bytes(), &items); err != nil {
+		t.Fatalf("unexpected error: %v: %s", err, w.Body)
+	}
+
+	if len(items) != 2 {
+		t.Errorf("unexpected items: %v", items)
+	}
+
+	w = f.do(t, auth.RoleViewer, http.MethodGet, "/api/item/filter",
+		`{"name":"sugar"}`, nil)
+	if err := json.Unmarshal(w.Body.Bytes(), &items); err != nil {
+		t.Fatalf("unexpected error: %v: %s", err, w.Body)
+	}
+
+	if len(items) != 1 || items[0].Name != "sugar" {
+		t.Errorf("unexpected items: %v", items)
+	}
+
+	w = f.do(t, auth.RoleViewer, http.MethodGet, "/api/item/filter",
+		`{}`, nil)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("unexpected status: %d %s", w.Code, w.Body)
+	}
+}
+
+func TestRevokeToken_Tenant(t *testing.T) {
+	denied := &denylist{subjects: make(map[string]time.Time)}
+	f := setup(t, config.Server{}, denied)
+
+	own := persistence.User{ID: uuid.New(), TenantID: "acme"}
+	other := persistence.User{ID: uuid.New(), TenantID: "other"}
+	for _, user := range []persistence.User{own, other} {
+		f.storage.users[user.ID] = user
+		f.storage.sessions[user.ID.String()] = true
+	}
+
+	w := f.do(t, auth.RoleAdmin, http.MethodPost, "/admin/token/revoke",
+		`{"subject":"`+other.ID.String()+`"}`, nil)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("unexpected status: %d %s", w.Code, w.Body)
+	}
+
+	if len(denied.subjects) != 0 || !f.storage.sessions[other.ID.String()] {
+		t.Errorf("tokens of another tenant were revoked")
+	}
+
+	w = f.do(t, auth.RoleAdmin, http.MethodPost, "/admin/token/revoke",
+		`{"subject":"`+own.ID.String()+`"}`, nil)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("unexpected status: %d %s", w.Code, w.Body)
+	}
+
+	if _, ok := denied.subjects["acme/"+own.ID.String()]; !ok {
+		t.Errorf("subject was not revoked in its tenant: %v", denied.subjects)
+	}
+
+	if f.storage.sessions[own.ID.String()] {
+		t.Errorf("refresh tokens were not revoked")
+	}
+}
+
+func TestSetUserRole_Revokes_Tokens(t *testing.T) {
+	denied := &denylist{subjects: make(map[string]time.Time)}
+	f := setup(t, config.Server{}, denied)
+
+	user := persistence.User{
+		ID:       uuid.New(),
+		TenantID: "acme",
+		Role:     string(auth.RoleAdmin),
+	}
+	f.storage.users[user.ID] = user
+
+	w := f.do(t, auth.RoleAdmin, http.MethodPost,
+		"/admin/user/"+user.ID.String()+"/role", `{"role":"viewer"}`, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d %s", w.Code, w.Body)
+	}
+
+	if _, ok := denied.subjects["acme/"+user.ID.String()]; !ok {
+		t.Errorf("tokens with the old role were not revoked")
+	}
+}
+
+func TestDenylist_Unavailable(t *testing.T) {
+	denied := &denylist{err: errors.New("connection refused")}
+	f := setup(t, config.Server{}, denied)
+
+	w := f.do(t, auth.RoleViewer, http.MethodGet, "/api/item", "", nil)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("unexpected status: %d %s", w.Code, w.Body)
+	}
+
+	f.server.authn.FailOpen = true
+
+	w = f.do(t, auth.RoleViewer, http.MethodGet, "/api/item", "", nil)
+	if w.Code != http.StatusOK {
+		t.Errorf("unexpected status: %d %s", w.Code, w.Body)
+	}
+}
+
+func TestCreateUser_Tenant(t *testing.T) {
+	f := setup(t, config.Server{}, memory{})
+
+	tests := []struct {
+		name   string
+		role   auth.Role
+		body   string
+		status int
+		tenant string
+	}{
+		{"own tenant", auth.RoleAdmin,
+			`{"username":"alice","password":"password","role":"viewer"}`,
+			http.StatusCreated, "acme"},
+		{"other tenant", auth.RoleAdmin,
+			`{"username":"bob","password":"password","role":"viewer","tenant":"other"}`,
+			http.StatusForbidden, ""},
+		{"super admin", auth.RoleAdmin,
+			`{"username":"carol","password":"password","role":"superadmin"}`,
+			http.StatusForbidden, ""},
+		{"other tenant as super admin", auth.RoleSuperAdmin,
+			`{"username":"dave","password":"password","role":"admin","tenant":"other"}`,
+			http.StatusCreated, "other"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := f.do(t, test.role, http.MethodPost, "/admin/user", test.body, nil)
+			if w.Code != test.status {
+				t.Fatalf("unexpected status: %d %s", w.Code, w.Body)
+			}
+
+			if test.status != http.StatusCreated {
+				return
+			}
+
+			var user persistence.User
+			if err := json.Unmarshal(w.Body.Bytes(), &user); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if user.TenantID != test.tenant {
+				t.Errorf("unexpected tenant: %s", user.TenantID)
+			}
+		})
+	}
+}
+
+func TestSetUserRole_Super_Admin(t *testing.T) {
+	f := setup(t, config.Server{}, &denylist{
+		subjects: make(map[string]time.Time),
+	})
+
+	user := persistence.User{
+		ID:       uuid.New(),
+		TenantID: "acme",
+		Role:     string(auth.RoleSuperAdmin),
+	}
+	f.storage.users[user.ID] = user
+
+	path := "/admin/user/" + user.ID.String() + "/role"
+
+	w := f.do(t, auth.RoleAdmin, http.MethodPost, path, `{"role":"viewer"}`, nil)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("unexpected status: %d %s", w.Code, w.Body)
+	}
+
+	w = f.do(t, auth.RoleSuperAdmin, http.MethodPost, path, `{"role":"viewer"}`, nil)
+	if w.Code != http.StatusOK {
+		t.Errorf("unexpected status: %d %s", w.Code, w.Body)
+	}
+}
+
+func TestUpdateItem(t *testing.T) {
+	f := setup(t, config.Server{}, memory{})
+
+	item := persistence.Item{ID: uuid.New(), Name: "flour", Unit: "kg"}
+	f.storage.items[item.ID] = item
+
+	body := `{"uuid":"` + item.ID.String() + `","name":"sugar","unit":"kg",
+		"amount":2,"expires_at":"2030-01-01T00:00:00Z"}`
+
+	w := f.do(t, auth.RoleOperator, http.MethodPut,
+		"/api/item/"+item.ID.String(), body, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d %s", w.Code, w.Body)
+	}
+
+	if updated := f.storage.items[item.ID]; updated.Name != "sugar" ||
+		updated.Amount != 2 {
+		t.Errorf("item was not updated: %+v", updated)
+	}
+
+	unknown := uuid.New().String()
+	w = f.do(t, auth.RoleOperator, http.MethodPut, "/api/item/"+unknown,
+		strings.Replace(body, item.ID.String(), unknown, 1), nil)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("unexpected status: %d %s", w.Code, w.Body)
+	}
+
+	w = f.do(t, auth.RoleOperator, http.MethodPut,
+		"/api/item/"+item.ID.String(), `{"name":""}`, nil)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("unexpected status: %d %s", w.Code, w.Body)
+	}
+
+	other := persistence.Item{ID: uuid.New(), Name: "salt", Unit: "kg"}
+	f.storage.items[other.ID] = other
+
+	w = f.do(t, auth.RoleOperator, http.MethodPut,
+		"/api/item/"+other.ID.String(), body, nil)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("unexpected status: %d %s", w.Code, w.Body)
+	}
+
+	if f.storage.items[other.ID].Name != "salt" {
+		t.Errorf("item of the path was updated with another body")
+	}
+
+	w = f.do(t, auth.RoleOperator, http.MethodPut, "/api/item/1", body, nil)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("unexpected status: %d %s", w.Code, w.Body)
+	}
+}
+
+func TestCreateItem_Validation(t *testing.T) {
+	f := setup(t, config.Server{}, memory{})
+
+	tests := []struct {
+		name   string
+		amount string
+		sku    string
+		code   int
+		field  string
+	}{
+		{"out of stock", "0", "", http.StatusOK, ""},
+		{"negative amount", "-1", "", http.StatusBadRequest, "amount"},
+		{"long sku", "1", strings.Repeat("a", 65), http.StatusBadRequest, "sku"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			body := `{"name":"flour","unit":"kg","amount":` + test.amount +
+				`,"expires_at":"2030-01-01T00:00:00Z","sku":"` + test.sku + `"}`
+
+			w := f.do(t, auth.RoleOperator, http.MethodPost, "/api/item", body, nil)
+			if w.Code != test.code {
+				t.Fatalf("unexpected status: %d %s", w.Code, w.Body)
+			}
+
+			if test.field == "" {
+				return
+			}
+
+			var problem Problem
+			if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if problem.Type != "/problems/validation" ||
+				len(problem.Errors) != 1 ||
+				problem.Errors[0].Field != test.field {
+				t.Errorf("unexpected problem: %+v", problem)
+			}
+		})
+	}
+}
+
+func TestDeleteItem(t *testing.T) {
+	f := setup(t, config.Server{}, memory{})
+
+	item := persistence.Item{ID: uuid.New(), Name: "flour", Unit: "kg"}
+	f.storage.items[item.ID] = item
+
+	tests := []struct {
+		name string
+		id   string
+		code int
+	}{
+		{"deleted", item.ID.String(), http.StatusOK},
+		{"already deleted", item.ID.String(), http.StatusNotFound},
+		{"unknown", uuid.New().String(), http.StatusNotFound},
+		{"invalid uuid", "1", http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := f.do(t, auth.RoleAdmin, http.MethodDelete,
+				"/api/item/"+test.id, "", nil)
+			if w.Code != test.code {
+				t.Errorf("unexpected status: %d %s", w.Code, w.Body)
+			}
+		})
+	}
+}
+
+func TestMetrics_Not_Public(t *testing.T) {
+	f := setup(t, config.Server{MetricsPort: "9100"}, memory{})
+
+	w := f.do(t, auth.RoleAdmin, http.MethodGet, "/metrics", "", nil)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("metrics are served with the API: %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	f.server.metricsHTTP.Handler.ServeHTTP(w,
+		httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("unexpected status: %d %s", w.Code, w.Body)
+	}
+}
+
+func TestImportItems_Idempotency_Key(t *testing.T) {
+	f := setup(t, config.Server{}, memory{})
+
+	header := http.Header{idempotencyKeyHeader: []string{"import-1"}}
+	body := "name,unit,amount,expires_at\nflour,kg,1,2030-01-01\n"
+
+	tests := []struct {
+		query string
+		code  int
+	}{
+		{"", http.StatusBadRequest},
+		{"?dry_run=true", http.StatusOK},
+	}
+
+	for _, test := range tests {
+		t.Run("import"+test.query, func(t *testing.T) {
+			w := f.do(t, auth.RoleOperator, http.MethodPost,
+				"/api/import"+test.query, body, header)
+			if w.Code != test.code {
+				t.Errorf("unexpected status: %d %s", w.Code, w.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// Statuses of the service and its dependencies.
+const (
+	statusUp          = "up"
+	statusDown        = "down"
+	statusPending     = "pending"
+	statusFailed      = "failed"
+	statusReady       = "ready"
+	statusDegraded    = "degraded"
+	statusUnavailable = "unavailable"
+)
+
+// Health is the response of the probe endpoints.
+type Health struct {
+	Status string        `json:"status"`
+	Time   time.Time     `json:"time"`
+	Checks []HealthCheck `json:"checks,omitempty"`
+}
+
+// HealthCheck is the state of a single dependency.
+type HealthCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	// Critical dependencies fail readiness when they are
+	// down, the others only degrade the service.
+	Critical  bool    `json:"critical"`
+	LatencyMS float64 `json:"latency_ms,omitempty"`
+}
+
+// MigrationsDone marks the database migrations as finished.
+// The service is not ready before.
+func (s *Server) MigrationsDone() {
+	s.migration.Store(statusUp)
+}
+
+// MigrationsFailed marks the database migrations as failed.
+// The service stays unready and readiness reports the
+// failure, the process keeps running for the probes.
+func (s *Server) MigrationsFailed(err error) {
+	s.logger.Error("database migrations failed", zap.Error(err))
+	s.migration.Store(statusFailed)
+}
+
+// migrationStatus returns the status of the database
+// migrations: pending, up or failed.
+func (s *Server) migrationStatus() string {
+	if status, ok := s.migration.Load().(string); ok {
+		return status
+	}
+
+	return statusPending
+}
+
+// migrated is a middleware that answers with a 503 until
+// the database is migrated, so no request reaches a schema
+// that is not up to date yet.
+func (s *Server) migrated() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if status := s.migrationStatus(); status != statusUp {
+			abortWithProblem(c, http.StatusServiceUnavailable,
+				"database migrations are "+status)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// livez reports whether the process is able to serve
+// requests at all. It does not check any dependency, so
+// an outage of one does not get the service restarted.
+func (s *Server) livez(c *gin.Context) {
+	c.JSON(http.StatusOK, Health{
+		Status: statusUp,
+		Time:   time.Now().UTC(),
+	})
+}
+
+// readyz reports whether the service should receive
+// traffic, with the state and latency of every dependency.
+// It fails while migrations are running or after they
+// failed, and when the database is down. The service
+// keeps working without the cache, so a cache outage only
+// degrades it unless the cache is configured as required.
+func (s *Server) readyz(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(
+		c.Request.Context(), 2*time.Second)
+	defer cancel()
+
+	checks := []HealthCheck{
+		{Name: "migrations", Critical: true},
+		{Name: "database", Critical: true},
+		{Name: "cache", Critical: s.config.ReadinessRequiresCache},
+	}
+
+	checks[0].Status = s.migrationStatus()
+
+	pings := []func(context.Context) error{
+		nil, s.storage.Ping, s.cache.Ping,
+	}
+
+	var wg sync.WaitGroup
+
+	for idx := range checks {
+		if pings[idx] == nil {
+			continue
+		}
+
+		wg.Add(1)
+		go func(check *HealthCheck, ping func(context.Context) error) {
+			defer wg.Done()
+
+			start := time.Now()
+			err := ping(ctx)
+			check.LatencyMS = float64(
+				time.Since(start).Microseconds()) / 1000
+
+			check.Status = statusUp
+			if err != nil {
+				check.Status = statusDown
+				s.log(c).Warn("readiness check failed",
+					zap.String("dependency", check.Name),
+					zap.Error(err))
+			}
+		}(&checks[idx], pings[idx])
+	}
+
+	wg.Wait()
+
+	health := Health{
+		Status: statusReady,
+		Time:   time.Now().UTC(),
+		Checks: checks,
+	}
+
+	for _, check := range checks {
+		switch {
+		case check.Status == statusUp:
+		case check.Critical:
+			health.Status = statusUnavailable
+		case health.Status == statusReady:
+			health.Status = statusDegraded
+		}
+	}
+
+	if health.Status == statusUnavailable {
+		c.JSON(http.StatusServiceUnavailable, health)
+		return
+	}
+
+	c.JSON(http.StatusOK, health)
+}
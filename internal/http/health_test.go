@@ -0,0 +1,144 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Salam4nder/inventory/internal/cache"
+	"github.com/Salam4nder/inventory/internal/config"
+	"github.com/Salam4nder/inventory/pkg/auth"
+)
+
+// unreachable is a cache whose Redis is down.
+type unreachable struct {
+	memory
+}
+
+func (unreachable) Ping(context.Context) error {
+	return errors.New("connection refused")
+}
+
+func TestReadyz(t *testing.T) {
+	tests := []struct {
+		name      string
+		migration func(*Server)
+		database  error
+		cache     cache.Service
+		cfg       config.Server
+		code      int
+		status    string
+	}{
+		{
+			name:      "ready",
+			migration: (*Server).MigrationsDone,
+			cache:     memory{},
+			code:      http.StatusOK,
+			status:    statusReady,
+		},
+		{
+			name:   "migrations pending",
+			cache:  memory{},
+			code:   http.StatusServiceUnavailable,
+			status: statusUnavailable,
+		},
+		{
+			name: "migrations failed",
+			migration: func(s *Server) {
+				s.MigrationsFailed(errors.New("dirty database"))
+			},
+			cache:  memory{},
+			code:   http.StatusServiceUnavailable,
+			status: statusUnavailable,
+		},
+		{
+			name:      "database down",
+			migration: (*Server).MigrationsDone,
+			database:  errors.New("connection refused"),
+			cache:     memory{},
+			code:      http.StatusServiceUnavailable,
+			status:    statusUnavailable,
+		},
+		{
+			name:      "cache down",
+			migration: (*Server).MigrationsDone,
+			cache:     unreachable{},
+			code:      http.StatusOK,
+			status:    statusDegraded,
+		},
+		{
+			name:      "required cache down",
+			migration: (*Server).MigrationsDone,
+			cache:     unreachable{},
+			cfg:       config.Server{ReadinessRequiresCache: true},
+			code:      http.StatusServiceUnavailable,
+			status:    statusUnavailable,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f := setupPending(t, test.cfg, test.cache)
+			f.storage.ping = test.database
+			if test.migration != nil {
+				test.migration(f.server)
+			}
+
+			w := httptest.NewRecorder()
+			f.server.http.Handler.ServeHTTP(w,
+				httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			if w.Code != test.code {
+				t.Errorf("unexpected status: %d %s", w.Code, w.Body)
+			}
+
+			var health Health
+			if err := json.Unmarshal(w.Body.Bytes(), &health); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if health.Status != test.status {
+				t.Errorf("unexpected health: %s", w.Body)
+			}
+		})
+	}
+}
+
+func TestMigrations_Pending(t *testing.T) {
+	f := setupPending(t, config.Server{}, memory{})
+
+	tests := []struct {
+		method string
+		path   string
+		code   int
+	}{
+		{http.MethodGet, "/livez", http.StatusOK},
+		{http.MethodGet, "/api/item", http.StatusServiceUnavailable},
+		{http.MethodPost, "/auth/login", http.StatusServiceUnavailable},
+		{http.MethodPost, "/admin/token/revoke", http.StatusServiceUnavailable},
+	}
+
+	for _, test := range tests {
+		t.Run(test.method+" "+test.path, func(t *testing.T) {
+			w := f.do(t, auth.RoleAdmin, test.method, test.path, "", nil)
+			if w.Code != test.code {
+				t.Errorf("unexpected status: %d %s", w.Code, w.Body)
+			}
+		})
+	}
+
+	f.server.MigrationsFailed(errors.New("dirty database"))
+
+	w := f.do(t, auth.RoleAdmin, http.MethodGet, "/api/item", "", nil)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("unexpected status: %d %s", w.Code, w.Body)
+	}
+
+	f.server.MigrationsDone()
+
+	w = f.do(t, auth.RoleAdmin, http.MethodGet, "/api/item", "", nil)
+	if w.Code != http.StatusOK {
+		t.Errorf("unexpected status: %d %s", w.Code, w.Body)
+	}
+}
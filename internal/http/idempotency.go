@@ -0,0 +1,176 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/Salam4nder/inventory/internal/cache"
+	"github.com/Salam4nder/inventory/internal/persistence"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const (
+	idempotencyKeyHeader = "Idempotency-Key"
+	// idempotentReplayHeader marks replayed responses.
+	idempotentReplayHeader = "Idempotent-Replayed"
+	maxIdempotencyKey      = 255
+	// maxIdempotentBody limits the bodies read into memory
+	// to fingerprint them. It fits the largest batch.
+	maxIdempotentBody = 4 << 20
+)
+
+// recordingWriter keeps a copy of the response body.
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *recordingWriter) WriteString(data string) (int, error) {
+	w.body.WriteString(data)
+	return w.ResponseWriter.WriteString(data)
+}
+
+// idempotent is a middleware that makes POST requests with
+// an Idempotency-Key header safe to retry. The first response
+// for a key is stored and replayed to retries with the same
+// payload, retries with a different payload get a 422. Server
+// errors and panics are not stored, so the request can be
+// retried. Bodies larger than maxIdempotentBody get a 413.
+//
+// Keys are scoped to the tenant and the caller, so it must run
+// after jwtValidator. Requests are handled without replay if
+// the store cannot be reached.
+func (s *Server) idempotent() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		idempotencyKey := c.GetHeader(idempotencyKeyHeader)
+		if idempotencyKey == "" || c.Request.Method != http.MethodPost ||
+			s.config.IdempotencyTTL <= 0 {
+			c.Next()
+			return
+		}
+
+		if len(idempotencyKey) > maxIdempotencyKey {
+			abortWithProblem(c, http.StatusBadRequest,
+				"idempotency key is too long")
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(
+			c.Writer, c.Request.Body, maxIdempotentBody))
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.As(err, &tooLarge):
+			abortWithProblem(c, http.StatusRequestEntityTooLarge,
+				"request body is too large")
+			return
+		case err != nil:
+			abortWithProblem(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		hash := sha256.New()
+		hash.Write([]byte(c.Request.Method + " " + c.Request.URL.Path + "\n"))
+		hash.Write(body)
+		fingerprint := hex.EncodeToString(hash.Sum(nil))
+
+		key := persistence.TenantFrom(c.Request.Context()) + ":" +
+			rateLimitClient(c) + ":" + idempotencyKey
+
+		ctx, cancel := context.WithTimeout(
+			c.Request.Context(), time.Second)
+		defer cancel()
+
+		stored, claimed, err := s.cache.ReserveIdempotencyKey(
+			ctx, key, fingerprint, s.config.IdempotencyTTL)
+		if err != nil {
+			s.log(c).Error(err.Error(), zap.Error(err))
+			c.Next()
+			return
+		}
+
+		if !claimed {
+			replay(c, stored, fingerprint)
+			return
+		}
+
+		writer := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+
+		// The claim is given up unless the response is stored,
+		// also when the handler panics. recovery runs outside
+		// of this middleware and responds afterwards.
+		saved := false
+		defer func() {
+			if !saved {
+				s.releaseIdempotencyKey(c, key)
+			}
+		}()
+
+		c.Next()
+
+		if writer.Status() >= http.StatusInternalServerError {
+			return
+		}
+
+		// The request context may be done by now.
+		ctx, cancel = context.WithTimeout(
+			context.Background(), time.Second)
+		defer cancel()
+
+		if err := s.cache.StoreIdempotentResponse(ctx, key,
+			cache.IdempotentResponse{
+				Fingerprint: fingerprint,
+				Status:      writer.Status(),
+				ContentType: writer.Header().Get("Content-Type"),
+				Body:        writer.body.Bytes(),
+			}, s.config.IdempotencyTTL); err != nil {
+			s.log(c).Error(err.Error(), zap.Error(err))
+			return
+		}
+
+		saved = true
+	}
+}
+
+// releaseIdempotencyKey gives up the claim on key, so
+// the request can be retried with the same key.
+func (s *Server) releaseIdempotencyKey(c *gin.Context, key string) {
+	ctx, cancel := context.WithTimeout(
+		context.Background(), time.Second)
+	defer cancel()
+
+	if err := s.cache.ReleaseIdempotencyKey(ctx, key); err != nil {
+		s.log(c).Error(err.Error(), zap.Error(err))
+	}
+}
+
+// replay answers a retry with the stored response.
+func replay(
+	c *gin.Context, stored cache.IdempotentResponse, fingerprint string) {
+	switch {
+	case stored.Fingerprint != fingerprint:
+		abortWithProblem(c, http.StatusUnprocessableEntity,
+			"idempotency key was used with a different payload")
+	case stored.Status == 0:
+		abortWithProblem(c, http.StatusConflict,
+			"a request with this idempotency key is in progress")
+	default:
+		c.Header(idempotentReplayHeader, "true")
+		c.Data(stored.Status, stored.ContentType, stored.Body)
+		c.Abort()
+	}
+}
@@ -0,0 +1,165 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Salam4nder/inventory/internal/cache"
+	"github.com/Salam4nder/inventory/internal/config"
+	"github.com/Salam4nder/inventory/pkg/auth"
+)
+
+// responses is a cache that keeps idempotent responses.
+type responses struct {
+	memory
+
+	stored map[string]cache.IdempotentResponse
+}
+
+func (r *responses) ReserveIdempotencyKey(
+	ctx context.Context,
+	key, fingerprint string,
+	expiration time.Duration) (cache.IdempotentResponse, bool, error) {
+	if stored, ok := r.stored[key]; ok {
+		return stored, false, nil
+	}
+
+	r.stored[key] = cache.IdempotentResponse{Fingerprint: fingerprint}
+
+	return cache.IdempotentResponse{}, true, nil
+}
+
+func (r *responses) StoreIdempotentResponse(
+	ctx context.Context,
+	key string,
+	response cache.IdempotentResponse,
+	expiration time.Duration) error {
+	r.stored[key] = response
+
+	return nil
+}
+
+func (r *responses) ReleaseIdempotencyKey(
+	ctx context.Context, key string) error {
+	delete(r.stored, key)
+
+	return nil
+}
+
+const createItemBody = `{"name":"flour","unit":"kg","amount":1,
+	"expires_at":"2030-01-01T00:00:00Z"}`
+
+func idempotencySetup(t *testing.T) (*fixture, *responses) {
+	t.Helper()
+
+	stored := &responses{stored: make(map[string]cache.IdempotentResponse)}
+
+	return setup(t, config.Server{IdempotencyTTL: time.Hour}, stored), stored
+}
+
+func postItem(
+	t *testing.T, f *fixture, key, body string) (int, string, string) {
+	t.Helper()
+
+	w := f.do(t, auth.RoleOperator, http.MethodPost, "/api/item", body,
+		http.Header{idempotencyKeyHeader: {key}})
+
+	return w.Code, w.Body.String(), w.Header().Get(idempotentReplayHeader)
+}
+
+func TestIdempotent_Replay(t *testing.T) {
+	f, _ := idempotencySetup(t)
+
+	status, body, replayed := postItem(t, f, "first", createItemBody)
+	if status != http.StatusOK || replayed != "" {
+		t.Fatalf("unexpected response: %d %s", status, body)
+	}
+
+	retryStatus, retryBody, replayed := postItem(t, f, "first", createItemBody)
+	if retryStatus != status || retryBody != body || replayed != "true" {
+		t.Errorf("unexpected replay: %d %s", retryStatus, retryBody)
+	}
+
+	if len(f.storage.items) != 1 {
+		t.Errorf("retry created another item: %d", len(f.storage.items))
+	}
+}
+
+func TestIdempotent_Payload_Mismatch(t *testing.T) {
+	f, _ := idempotencySetup(t)
+
+	postItem(t, f, "first", createItemBody)
+
+	status, body, _ := postItem(t, f, "first",
+		strings.Replace(createItemBody, "flour", "sugar", 1))
+	if status != http.StatusUnprocessableEntity {
+		t.Errorf("unexpected response: %d %s", status, body)
+	}
+}
+
+func TestIdempotent_In_Progress(t *testing.T) {
+	f, stored := idempotencySetup(t)
+
+	postItem(t, f, "first", createItemBody)
+
+	// A claim without a status is still being handled.
+	for key, response := range stored.stored {
+		response.Status = 0
+		stored.stored[key] = response
+	}
+
+	status, body, _ := postItem(t, f, "first", createItemBody)
+	if status != http.StatusConflict {
+		t.Errorf("unexpected response: %d %s", status, body)
+	}
+}
+
+func TestIdempotent_Release(t *testing.T) {
+	tests := []struct {
+		name   string
+		create func() error
+	}{
+		{"server error", func() error {
+			return errors.New("connection refused")
+		}},
+		{"panic", func() error {
+			panic("unexpected")
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f, stored := idempotencySetup(t)
+
+			f.storage.create = test.create
+			status, body, _ := postItem(t, f, "first", createItemBody)
+			if status != http.StatusInternalServerError {
+				t.Fatalf("unexpected response: %d %s", status, body)
+			}
+
+			if len(stored.stored) != 0 {
+				t.Fatalf("key was not released: %v", stored.stored)
+			}
+
+			f.storage.create = nil
+			status, body, replayed := postItem(t, f, "first", createItemBody)
+			if status != http.StatusOK || replayed != "" {
+				t.Errorf("unexpected retry: %d %s", status, body)
+			}
+		})
+	}
+}
+
+func TestIdempotent_Body_Too_Large(t *testing.T) {
+	f, _ := idempotencySetup(t)
+
+	status, _, _ := postItem(t, f, "first",
+		strings.Repeat(" ", maxIdempotentBody+1))
+	if status != http.StatusRequestEntityTooLarge {
+		t.Errorf("unexpected status: %d", status)
+	}
+}
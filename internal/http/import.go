@@ -0,0 +1,369 @@
+package http
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Salam4nder/inventory/internal/persistence"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// importChunk is the number of valid rows written per batch.
+const importChunk = 500
+
+// importColumns are the item fields that CSV columns
+// can be mapped to.
+var importColumns = []string{"name", "unit", "amount", "expires_at", "sku"}
+
+// ImportReport is the response of the import endpoint.
+type ImportReport struct {
+	DryRun  bool             `json:"dry_run"`
+	Upsert  string           `json:"upsert,omitempty"`
+	Rows    int              `json:"rows"`
+	Valid   int              `json:"valid"`
+	Created int              `json:"created"`
+	Updated int              `json:"updated"`
+	Failed  int              `json:"failed"`
+	Errors  []ImportRowError `json:"errors"`
+}
+
+// ImportRowError describes why a row of the CSV was
+// not imported. Row is the line number in the file.
+type ImportRowError struct {
+	Row    int    `json:"row"`
+	Column string `json:"column,omitempty"`
+	Error  string `json:"error"`
+}
+
+// importFailed responds with a problem telling how far the
+// import got, the chunks written before err are kept.
+func (s *Server) importFailed(c *gin.Context, report ImportReport, err error) {
+	s.log(c).Error(err.Error(), zap.Error(err))
+	abortWithProblem(c, http.StatusInternalServerError, fmt.Sprintf(
+		"import stopped at row %d, %d items were created and %d updated",
+		report.Rows, report.Created, report.Updated))
+}
+
+func (r *ImportReport) fail(row int, column string, err error) {
+	r.Failed++
+	r.Errors = append(r.Errors, ImportRowError{
+		Row:    row,
+		Column: column,
+		Error:  err.Error(),
+	})
+}
+
+// importItems imports items from a CSV file. The file is either
+// the request body or the "file" part of a multipart form. The
+// query parameters are:
+//
+//	dry_run=true          validate the rows without writing them
+//	upsert=name|sku       update existing items instead of creating
+//	columns[field]=header map an item field to a CSV header
+//	delimiter=;           field delimiter, defaults to a comma
+//
+// Imports are too large to replay. An import sent with an
+// Idempotency-Key must upsert, so retrying it updates the
+// items of the first attempt instead of creating them again.
+func (s *Server) importItems(c *gin.Context) {
+	report := ImportReport{Errors: []ImportRowError{}}
+
+	if dryRun := c.Query("dry_run"); dryRun != "" {
+		var err error
+		if report.DryRun, err = strconv.ParseBool(dryRun); err != nil {
+			abortWithProblem(c, http.StatusBadRequest, "invalid dry_run")
+			return
+		}
+	}
+
+	op := persistence.BatchCreate
+
+	switch report.Upsert = c.Query("upsert"); report.Upsert {
+	case "":
+	case "name":
+		op = persistence.BatchUpsertName
+	case "sku":
+		op = persistence.BatchUpsertSKU
+	default:
+		abortWithProblem(c, http.StatusBadRequest, "upsert must be name or sku")
+		return
+	}
+
+	if op == persistence.BatchCreate && !report.DryRun &&
+		c.GetHeader(idempotencyKeyHeader) != "" {
+		abortWithProblem(c, http.StatusBadRequest,
+			"imports with an Idempotency-Key must upsert by name or sku")
+		return
+	}
+
+	body, err := importBody(c)
+	if err != nil {
+		abortWithProblem(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	reader := csv.NewReader(body)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	if delimiter := c.Query("delimiter"); delimiter != "" {
+		if len(delimiter) != 1 {
+			abortWithProblem(c, http.StatusBadRequest, "invalid delimiter")
+			return
+		}
+		reader.Comma = rune(delimiter[0])
+	}
+
+	header, err := reader.Read()
+	if err != nil {
+		abortWithProblem(c, http.StatusBadRequest,
+			"invalid CSV header: "+err.Error())
+		return
+	}
+
+	index, err := importIndex(header, c.QueryMap("columns"))
+	if err != nil {
+		abortWithProblem(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var (
+		ops  []persistence.BatchOperation
+		rows []int
+	)
+
+	flush := func() error {
+		if len(ops) == 0 {
+			return nil
+		}
+
+		ctx, cancel := context.WithTimeout(
+			c.Request.Context(), 30*time.Second)
+		defer cancel()
+
+		results, err := s.storage.Batch(ctx, ops, false)
+		if err != nil {
+			return err
+		}
+
+		for _, result := range results {
+			switch {
+			case result.Err != nil:
+				report.fail(rows[result.Index], "", result.Err)
+				continue
+			case result.Op == persistence.BatchCreate:
+				report.Created++
+				continue
+			}
+
+			report.Updated++
+			if err := s.cache.Delete(
+				ctx, result.ID.String()); err != nil {
+				s.log(c).Debug(err.Error(), zap.Error(err))
+			}
+		}
+
+		ops, rows = ops[:0], rows[:0]
+
+		return nil
+	}
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		report.Rows++
+
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				abortWithProblem(c, http.StatusBadRequest, err.Error())
+				return
+			}
+			report.fail(parseErr.Line, "", parseErr.Err)
+			continue
+		}
+
+		line, _ := reader.FieldPos(0)
+
+		item, column, err := importRow(record, index)
+		if err == nil && op == persistence.BatchUpsertSKU && item.SKU == "" {
+			column, err = "sku", errors.New("sku is required to upsert by sku")
+		}
+		if err != nil {
+			report.fail(line, column, err)
+			continue
+		}
+
+		report.Valid++
+
+		if report.DryRun {
+			continue
+		}
+
+		ops = append(ops, persistence.BatchOperation{Op: op, Item: item})
+		rows = append(rows, line)
+
+		if len(ops) == importChunk {
+			if err := flush(); err != nil {
+				s.importFailed(c, report, err)
+				return
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		s.importFailed(c, report, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// importBody returns the CSV stream of the request without
+// buffering it, either the body or the "file" form part.
+func importBody(c *gin.Context) (io.Reader, error) {
+	if !strings.HasPrefix(c.ContentType(), "multipart/") {
+		return c.Request.Body, nil
+	}
+
+	multipart, err := c.Request.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		part, err := multipart.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("file part not found")
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if part.FormName() == "file" {
+			return part, nil
+		}
+	}
+}
+
+// importIndex returns the position of every item field in
+// the CSV header. Fields map to the header of the same name
+// unless the mapping says otherwise.
+func importIndex(
+	header []string, mapping map[string]string) (map[string]int, error) {
+	positions := make(map[string]int, len(header))
+
+	for idx, name := range header {
+		if idx == 0 {
+			name = strings.TrimPrefix(name, "\ufeff")
+		}
+		positions[strings.ToLower(strings.TrimSpace(name))] = idx
+	}
+
+	for field := range mapping {
+		if !contains(importColumns, field) {
+			return nil, fmt.Errorf("unknown field %q in columns", field)
+		}
+	}
+
+	index := make(map[string]int, len(importColumns))
+
+	for _, field := range importColumns {
+		column := field
+		if mapped, ok := mapping[field]; ok {
+			column = mapped
+		}
+
+		position, ok := positions[strings.ToLower(strings.TrimSpace(column))]
+		if !ok {
+			if _, mapped := mapping[field]; field == "sku" && !mapped {
+				continue
+			}
+			return nil, fmt.Errorf("column %q not found in header", column)
+		}
+
+		index[field] = position
+	}
+
+	return index, nil
+}
+
+// importRow converts a CSV record to an item and validates it
+// with persistence.ValidateItem. On error the offending column
+// is returned if it is known.
+func importRow(
+	record []string,
+	index map[string]int) (persistence.Item, string, error) {
+	value := func(field string) string {
+		position, ok := index[field]
+		if !ok || position >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[position])
+	}
+
+	createRequest := CreateItemRequest{
+		Name: value("name"),
+		Unit: value("unit"),
+		SKU:  value("sku"),
+	}
+
+	if amount := value("amount"); amount != "" {
+		parsed, err := strconv.ParseFloat(amount, 64)
+		if err != nil {
+			return persistence.Item{}, "amount",
+				fmt.Errorf("invalid amount %q", amount)
+		}
+		createRequest.Amount = parsed
+	}
+
+	if expiresAt := value("expires_at"); expiresAt != "" {
+		parsed, err := parseImportTime(expiresAt)
+		if err != nil {
+			return persistence.Item{}, "expires_at",
+				fmt.Errorf("invalid expires_at %q", expiresAt)
+		}
+		createRequest.ExpiresAt = parsed
+	}
+
+	item := createRequest.ToPersistenceItem()
+
+	var violations persistence.Violations
+	if err := persistence.ValidateItem(item); errors.As(err, &violations) {
+		return persistence.Item{}, violations[0].Field, err
+	}
+
+	return item, "", nil
+}
+
+// parseImportTime parses RFC 3339 timestamps and plain dates.
+func parseImportTime(value string) (time.Time, error) {
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err == nil {
+		return parsed, nil
+	}
+
+	return time.Parse(time.DateOnly, value)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,88 @@
+package http
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/Salam4nder/inventory/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// requestIDHeader carries the ID of a request.
+const requestIDHeader = "X-Request-ID"
+
+// requestID is a middleware that identifies every request.
+// The X-Request-ID of the client is kept if it is valid,
+// otherwise a new ID is generated. The ID is returned in the
+// response, and the request context carries it along with a
+// logger that adds it and the trace ID to every line.
+func (s *Server) requestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := logger.RequestID(c.GetHeader(requestIDHeader))
+
+		c.Header(requestIDHeader, id)
+
+		c.Request = c.Request.WithContext(logger.WithRequestID(
+			c.Request.Context(), s.logger, id))
+
+		c.Next()
+	}
+}
+
+// accessLog is a middleware that logs every request once it
+// was handled. Server errors are logged as errors and client
+// errors as warnings.
+func (s *Server) accessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		status := c.Writer.Status()
+
+		fields := append([]zap.Field{
+			zap.String("method", c.Request.Method),
+			zap.String("path", c.Request.URL.Path),
+			zap.String("route", route(c)),
+			zap.Int("status", status),
+			zap.Duration("latency", time.Since(start)),
+			zap.Int("bytes", c.Writer.Size()),
+			zap.String("client_ip", c.ClientIP()),
+			zap.String("user_agent", c.Request.UserAgent()),
+		}, callerFields(c)...)
+
+		log := s.log(c)
+
+		switch {
+		case status >= http.StatusInternalServerError:
+			log.Error("request", fields...)
+		case status >= http.StatusBadRequest:
+			log.Warn("request", fields...)
+		default:
+			log.Info("request", fields...)
+		}
+	}
+}
+
+// recovery is a middleware that turns panics of handlers
+// into logged internal errors.
+func (s *Server) recovery() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard,
+		func(c *gin.Context, recovered interface{}) {
+			s.log(c).Error("panic recovered",
+				zap.Any("panic", recovered), zap.Stack("stack"))
+			abortWithProblem(c, http.StatusInternalServerError, "")
+		})
+}
+
+// log returns the logger of the request.
+func (s *Server) log(c *gin.Context) *zap.Logger {
+	if logger.RequestIDFrom(c.Request.Context()) == "" {
+		return s.logger
+	}
+
+	return logger.FromContext(c.Request.Context())
+}
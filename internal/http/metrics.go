@@ -0,0 +1,43 @@
+package http
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Salam4nder/inventory/internal/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// metricsHandler serves the metrics on /metrics of the
+// metrics server. They are not served with the API, which
+// would expose the item counts of every tenant to clients.
+func metricsHandler(m *metrics.Metrics) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", m.Handler())
+
+	return mux
+}
+
+// instrument is a middleware that records the duration
+// and status of every request in the metrics.
+func (s *Server) instrument() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		s.metrics.ObserveRequest(c.Request.Method,
+			route(c), c.Writer.Status(), time.Since(start))
+	}
+}
+
+// route returns the pattern of the route that handled the
+// request, or "unmatched" if there was none.
+func route(c *gin.Context) string {
+	if path := c.FullPath(); path != "" {
+		return path
+	}
+
+	return "unmatched"
+}
@@ -1,28 +1,172 @@
 package http
 
 import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Salam4nder/inventory/internal/authn"
+	"github.com/Salam4nder/inventory/internal/persistence"
 	"github.com/Salam4nder/inventory/pkg/auth"
 
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// claimsKey is the gin context key of the validated JWT claims.
+const claimsKey = "claims"
+
+// apiKeyHeader is the header API keys are sent in.
+const apiKeyHeader = "X-API-Key"
+
+// authRealm is the realm of the WWW-Authenticate challenges.
+const authRealm = "inventory"
+
+// Error codes of RFC 6750.
+const (
+	bearerInvalidRequest    = "invalid_request"
+	bearerInvalidToken      = "invalid_token"
+	bearerInsufficientScope = "insufficient_scope"
 )
 
-// jwtValidator is a middleware that checks if the
-// request has a valid JWT token.
-func jwtValidator(secret string) gin.HandlerFunc {
+// jwtValidator is a middleware that checks if the request
+// has a valid bearer token in the Authorization header.
+// Tokens of the OIDC provider are validated by it, if one
+// is configured. An API key in the X-API-Key header is
+// accepted instead of a token.
+func (s *Server) jwtValidator() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		token := c.GetHeader("Authorization")
-		if token == "" {
-			c.JSON(401, gin.H{"error": " JWT token required"})
-			c.Abort()
+		if key := c.GetHeader(apiKeyHeader); key != "" {
+			claims, err := s.authn.APIKey(c.Request.Context(), key)
+			switch {
+			case errors.Is(err, authn.ErrInvalidAPIKey):
+				bearerChallenge(c, http.StatusUnauthorized,
+					bearerInvalidToken, err.Error(), "")
+				return
+			case err != nil:
+				s.fail(c, err)
+				return
+			}
+
+			setClaims(c, claims)
+			c.Next()
+			return
+		}
+
+		token, err := auth.ParseBearer(c.GetHeader("Authorization"))
+		switch {
+		case errors.Is(err, auth.ErrNoCredentials):
+			// Requests without credentials get a challenge
+			// without an error code, as RFC 6750 asks.
+			bearerChallenge(c, http.StatusUnauthorized, "", err.Error(), "")
+			return
+		case err != nil:
+			bearerChallenge(c, http.StatusBadRequest,
+				bearerInvalidRequest, err.Error(), "")
 			return
 		}
 
-		if err := auth.ValidateJWT(token, secret); err != nil {
-			c.JSON(401, gin.H{"error": "JWT token is invalid: " + err.Error()})
-			c.Abort()
+		claims, err := s.authn.Token(c.Request.Context(), token)
+		switch {
+		case errors.Is(err, authn.ErrDenylistUnavailable):
+			s.fail(c, err)
 			return
+		case err != nil:
+			bearerChallenge(c, http.StatusUnauthorized,
+				bearerInvalidToken, authn.Describe(err), "")
+			return
+		}
+
+		setClaims(c, claims)
+		c.Next()
+	}
+}
+
+// bearerChallenge aborts the request with a WWW-Authenticate
+// challenge as described in RFC 6750. The code, description
+// and scope are left out of the challenge if empty. The body
+// is a problem whose type is named after the code.
+func bearerChallenge(
+	c *gin.Context, status int, code, description, scope string) {
+	challenge := fmt.Sprintf(`Bearer realm=%q`, authRealm)
+	if code != "" {
+		challenge += fmt.Sprintf(`, error=%q`, code)
+	}
+	if code != "" && description != "" {
+		challenge += fmt.Sprintf(`, error_description=%q`, description)
+	}
+	if scope != "" {
+		challenge += fmt.Sprintf(`, scope=%q`, scope)
+	}
+
+	c.Header("WWW-Authenticate", challenge)
+
+	problem := newProblem(c, status, description)
+	if code != "" {
+		problem.Type = "/problems/" + strings.ReplaceAll(code, "_", "-")
+	}
+
+	writeProblem(c, problem)
+}
+
+// setClaims keeps the claims in the gin context for handlers
+// and in the request context for everything below them. The
+// request context is also scoped to the tenant of the claims.
+func setClaims(c *gin.Context, claims *auth.Claims) {
+	c.Set(claimsKey, claims)
+	c.Request = c.Request.WithContext(persistence.WithTenant(
+		auth.NewContext(c.Request.Context(), claims), claims.Tenant))
+}
+
+// authorize is a middleware that only lets requests through
+// whose claims grant all of the given permissions.
+// It must run after jwtValidator.
+func authorize(permissions ...auth.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, permission := range permissions {
+			if !hasPermission(c, permission) {
+				bearerChallenge(c, http.StatusForbidden,
+					bearerInsufficientScope,
+					"missing permission "+string(permission),
+					string(permission))
+				return
+			}
 		}
 
 		c.Next()
 	}
 }
+
+// claimsFrom returns the claims stored by jwtValidator,
+// or nil if the request is not authenticated.
+func claimsFrom(c *gin.Context) *auth.Claims {
+	claims, ok := c.Get(claimsKey)
+	if !ok {
+		return nil
+	}
+
+	return claims.(*auth.Claims)
+}
+
+// callerFields returns log fields identifying the caller.
+func callerFields(c *gin.Context) []zap.Field {
+	claims := claimsFrom(c)
+	if claims == nil {
+		return nil
+	}
+
+	return []zap.Field{
+		zap.String("subject", claims.Subject),
+		zap.String("username", claims.Username),
+		zap.String("token_id", claims.ID),
+	}
+}
+
+// hasPermission reports whether the claims of the
+// request grant the given permission.
+func hasPermission(c *gin.Context, permission auth.Permission) bool {
+	claims := claimsFrom(c)
+
+	return claims != nil && claims.HasPermission(permission)
+}
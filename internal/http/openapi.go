@@ -0,0 +1,32 @@
+package http
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/swaggest/swgui/v4emb"
+)
+
+// openAPIDocument describes every route of initEndpoints.
+// Routes and documented schemas are checked against the
+// router and the request and response types by the tests,
+// so the document has to be updated along with them.
+//
+//go:embed openapi.json
+var openAPIDocument []byte
+
+// docsPath is where the Swagger UI is served.
+const docsPath = "/docs/"
+
+func (s *Server) openAPI(c *gin.Context) {
+	c.Data(http.StatusOK, gin.MIMEJSON, openAPIDocument)
+}
+
+// swaggerUI serves the Swagger UI for the OpenAPI document.
+// Its assets are embedded, so it works without access to
+// a CDN.
+func swaggerUI() gin.HandlerFunc {
+	return gin.WrapH(v4emb.New(
+		"Inventory API", "/openapi.json", docsPath))
+}
@@ -0,0 +1,279 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/Salam4nder/inventory/internal/authn"
+	"github.com/Salam4nder/inventory/internal/config"
+	"github.com/Salam4nder/inventory/internal/metrics"
+	"github.com/Salam4nder/inventory/internal/persistence"
+	"github.com/Salam4nder/inventory/pkg/auth"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// undocumented are the routes left out of the document.
+var undocumented = map[string]bool{
+	http.MethodGet + " " + docsPath + "*any": true,
+}
+
+// documentedTypes are the types behind the schemas of the
+// document. Their JSON fields must match the schema.
+var documentedTypes = map[string]interface{}{
+	"Item":                     persistence.Item{},
+	"ItemFilter":               persistence.ItemFilter{},
+	"CreateItemRequest":        CreateItemRequest{},
+	"UpdateItemRequest":        UpdateItemRequest{},
+	"SearchResult":             persistence.SearchResult{},
+	"BatchRequest":             BatchRequest{},
+	"BatchOperationRequest":    BatchOperationRequest{},
+	"BatchResponse":            BatchResponse{},
+	"BatchOperationResult":     BatchOperationResult{},
+	"ImportReport":             ImportReport{},
+	"ImportRowError":           ImportRowError{},
+	"CreateReservationRequest": CreateReservationRequest{},
+	"Reservation":              persistence.Reservation{},
+	"Stock":                    persistence.Stock{},
+	"LoginRequest":             LoginRequest{},
+	"RefreshRequest":           RefreshRequest{},
+	"TokenResponse":            TokenResponse{},
+	"JWKS":                     auth.JWKS{},
+	"JWK":                      auth.JWK{},
+	"User":                     persistence.User{},
+	"CreateUserRequest":        CreateUserRequest{},
+	"SetRoleRequest":           SetRoleRequest{},
+	"ResetPasswordRequest":     ResetPasswordRequest{},
+	"APIKey":                   persistence.APIKey{},
+	"APIKeyRequest":            APIKeyRequest{},
+	"APIKeyResponse":           APIKeyResponse{},
+	"RevokeTokenRequest":       RevokeTokenRequest{},
+	"Health":                   Health{},
+	"HealthCheck":              HealthCheck{},
+	"Problem":                  Problem{},
+	"FieldError":               FieldError{},
+}
+
+var pathParam = regexp.MustCompile(`:([^/]+)`)
+
+func loadOpenAPI(t *testing.T) *openapi3.T {
+	t.Helper()
+
+	doc, err := openapi3.NewLoader().LoadFromData(openAPIDocument)
+	if err != nil {
+		t.Fatalf("unexpected error while loading the document: %v", err)
+	}
+
+	if err := doc.Validate(context.Background()); err != nil {
+		t.Fatalf("invalid document: %v", err)
+	}
+
+	return doc
+}
+
+func testServer(t *testing.T) *Server {
+	t.Helper()
+
+	gin.SetMode(gin.TestMode)
+
+	keys, err := auth.NewHMACKeySet("secret")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	s := New(config.Server{}, nil, nil, keys,
+		authn.New(keys, nil, nil, nil), metrics.New(), zap.NewNop())
+	s.initEndpoints()
+	s.MigrationsDone()
+
+	return s
+}
+
+func TestOpenAPI_Routes(t *testing.T) {
+	doc := loadOpenAPI(t)
+	router := testServer(t).http.Handler.(*gin.Engine)
+
+	routes := make(map[string]bool)
+
+	for _, route := range router.Routes() {
+		key := route.Method + " " + route.Path
+		if undocumented[key] {
+			continue
+		}
+
+		path := pathParam.ReplaceAllString(route.Path, "{$1}")
+		routes[route.Method+" "+path] = true
+
+		item := doc.Paths.Find(path)
+		if item == nil || item.GetOperation(route.Method) == nil {
+			t.Errorf("route %s is not documented", key)
+		}
+	}
+
+	for path, item := range doc.Paths {
+		for method := range item.Operations() {
+			if !routes[method+" "+path] {
+				t.Errorf("documented operation %s %s has no route",
+					method, path)
+			}
+		}
+	}
+}
+
+func TestOpenAPI_Schemas(t *testing.T) {
+	doc := loadOpenAPI(t)
+
+	for name, value := range documentedTypes {
+		schema, ok := doc.Components.Schemas[name]
+		if !ok {
+			t.Errorf("schema %s is not documented", name)
+			continue
+		}
+
+		want := jsonFields(reflect.TypeOf(value))
+		got := schemaProperties(schema.Value)
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("schema %s has properties %v, %T has fields %v",
+				name, got, value, want)
+		}
+	}
+}
+
+func TestOpenAPI_Responses(t *testing.T) {
+	doc := loadOpenAPI(t)
+	s := testServer(t)
+
+	tests := []struct {
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{http.MethodGet, "/livez", "", http.StatusOK},
+		{http.MethodGet, "/openapi.json", "", http.StatusOK},
+		{http.MethodGet, "/.well-known/jwks.json", "", http.StatusOK},
+		{http.MethodPost, "/auth/login", `{"username":"admin"}`, http.StatusBadRequest},
+		{http.MethodGet, "/api/item", "", http.StatusUnauthorized},
+		{http.MethodDelete, "/admin/apikey/{uuid}", "", http.StatusUnauthorized},
+	}
+
+	for _, test := range tests {
+		t.Run(test.method+" "+test.path, func(t *testing.T) {
+			target := strings.ReplaceAll(test.path,
+				"{uuid}", "8d3a2cb9-5bd6-4b0c-9b55-26a6c4e5e1e2")
+
+			req := httptest.NewRequest(
+				test.method, target, strings.NewReader(test.body))
+			req.Header.Set("Content-Type", gin.MIMEJSON)
+
+			w := httptest.NewRecorder()
+			s.http.Handler.ServeHTTP(w, req)
+
+			if w.Code != test.status {
+				t.Fatalf("unexpected status: %d %s", w.Code, w.Body)
+			}
+
+			item := doc.Paths.Find(test.path)
+			if item == nil {
+				t.Fatalf("path %s is not documented", test.path)
+			}
+
+			input := &openapi3filter.ResponseValidationInput{
+				RequestValidationInput: &openapi3filter.RequestValidationInput{
+					Request: req,
+					Route: &routers.Route{
+						Spec:      doc,
+						Path:      test.path,
+						PathItem:  item,
+						Method:    test.method,
+						Operation: item.GetOperation(test.method),
+					},
+				},
+				Status: w.Code,
+				Header: w.Header(),
+				Body:   io.NopCloser(bytes.NewReader(w.Body.Bytes())),
+			}
+
+			if err := openapi3filter.ValidateResponse(
+				context.Background(), input); err != nil {
+				t.Errorf("response does not match the document: %v", err)
+			}
+		})
+	}
+}
+
+func TestSwaggerUI(t *testing.T) {
+	s := testServer(t)
+
+	w := httptest.NewRecorder()
+	s.http.Handler.ServeHTTP(w, httptest.NewRequest(
+		http.MethodGet, docsPath, nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", w.Code)
+	}
+
+	if !strings.Contains(w.Body.String(), "/openapi.json") {
+		t.Errorf("the UI does not load the document")
+	}
+}
+
+// jsonFields returns the sorted JSON field names of a
+// struct type, including those of embedded structs.
+func jsonFields(typ reflect.Type) []string {
+	var fields []string
+
+	for idx := 0; idx < typ.NumField(); idx++ {
+		field := typ.Field(idx)
+
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" || !field.IsExported() {
+			continue
+		}
+
+		if field.Anonymous && name == "" {
+			fields = append(fields, jsonFields(field.Type)...)
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+		fields = append(fields, name)
+	}
+
+	sort.Strings(fields)
+
+	return fields
+}
+
+// schemaProperties returns the sorted property names of an
+// object schema, including those of its allOf schemas.
+func schemaProperties(schema *openapi3.Schema) []string {
+	var properties []string
+
+	for name := range schema.Properties {
+		properties = append(properties, name)
+	}
+
+	for _, ref := range schema.AllOf {
+		properties = append(properties, schemaProperties(ref.Value)...)
+	}
+
+	sort.Strings(properties)
+
+	return properties
+}
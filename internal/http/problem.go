@@ -0,0 +1,242 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/Salam4nder/inventory/internal/authn"
+	"github.com/Salam4nder/inventory/internal/persistence"
+	"github.com/Salam4nder/inventory/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+)
+
+// problemContentType is the media type of problem details.
+const problemContentType = "application/problem+json"
+
+// Problem is an error response as described by RFC 7807.
+// Type is a URI reference identifying the kind of problem,
+// "about:blank" when the status code says it all.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError is a field of the request that failed
+// validation. Field is the JSON path of the field.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// errorProblem is the problem returned for an error.
+type errorProblem struct {
+	err    error
+	status int
+	kind   string
+	title  string
+}
+
+// errorProblems maps the errors of the storage and the
+// authenticator to problems. This is the only place that
+// decides how they are reported.
+var errorProblems = []errorProblem{
+	{persistence.ErrNotFound, http.StatusNotFound,
+		"not-found", "Resource not found"},
+	{persistence.ErrUserExists, http.StatusConflict,
+		"user-exists", "User already exists"},
+	{persistence.ErrInsufficientStock, http.StatusConflict,
+		"insufficient-stock", "Insufficient stock"},
+	{persistence.ErrReservationInactive, http.StatusConflict,
+		"reservation-inactive", "Reservation is not active"},
+	{persistence.ErrInvalidBatchOp, http.StatusBadRequest,
+		"invalid-batch-operation", "Invalid batch operation"},
+	{persistence.ErrBatchAborted, http.StatusConflict,
+		"batch-aborted", "Batch aborted"},
+	{persistence.ErrTokenReused, http.StatusUnauthorized,
+		"refresh-token-reused", "Refresh token reused"},
+	{persistence.ErrTokenExpired, http.StatusUnauthorized,
+		"refresh-token-expired", "Refresh token expired"},
+	{authn.ErrDenylistUnavailable, http.StatusServiceUnavailable,
+		"", "Service unavailable"},
+	{context.DeadlineExceeded, http.StatusGatewayTimeout,
+		"", "Timeout"},
+}
+
+func init() {
+	// Report the JSON names of invalid fields.
+	if validate, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		validate.RegisterTagNameFunc(jsonFieldName)
+	}
+}
+
+// fail responds with the problem err maps to. Errors that do
+// not map to a problem are internal errors, they are logged
+// and their message is not sent to the client.
+func (s *Server) fail(c *gin.Context, err error) {
+	for _, mapping := range errorProblems {
+		if !errors.Is(err, mapping.err) {
+			continue
+		}
+
+		problem := newProblem(c, mapping.status, err.Error())
+		problem.Title = mapping.title
+		if mapping.kind != "" {
+			problem.Type = "/problems/" + mapping.kind
+		}
+
+		if mapping.status >= http.StatusInternalServerError {
+			s.log(c).Error(err.Error(), zap.Error(err))
+			problem.Detail = ""
+		}
+
+		writeProblem(c, problem)
+		return
+	}
+
+	s.log(c).Error(err.Error(), zap.Error(err))
+	writeProblem(c, newProblem(c, http.StatusInternalServerError, ""))
+}
+
+// invalid responds with a 400 for a request that could not
+// be bound or holds an invalid item. Validation errors and
+// item violations are reported per field.
+func (s *Server) invalid(c *gin.Context, err error) {
+	problem := newProblem(c, http.StatusBadRequest, err.Error())
+
+	var violations persistence.Violations
+	if errors.As(err, &violations) {
+		problem.Type = "/problems/validation"
+		problem.Title = "Request validation failed"
+		problem.Detail = "one or more fields are invalid"
+
+		for _, violation := range violations {
+			problem.Errors = append(problem.Errors, FieldError{
+				Field:   violation.Field,
+				Message: violation.Description,
+			})
+		}
+	}
+
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		problem.Type = "/problems/validation"
+		problem.Title = "Request validation failed"
+		problem.Detail = "one or more fields are invalid"
+
+		for _, fieldErr := range validationErrors {
+			problem.Errors = append(problem.Errors, FieldError{
+				Field:   fieldPath(fieldErr),
+				Message: fieldMessage(fieldErr),
+			})
+		}
+	}
+
+	writeProblem(c, problem)
+}
+
+// abortWithProblem responds with a problem of the given status.
+func abortWithProblem(c *gin.Context, status int, detail string) {
+	writeProblem(c, newProblem(c, status, detail))
+}
+
+func newProblem(c *gin.Context, status int, detail string) Problem {
+	return Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    detail,
+		Instance:  c.Request.URL.Path,
+		RequestID: logger.RequestIDFrom(c.Request.Context()),
+	}
+}
+
+// writeProblem aborts the request with the problem.
+func writeProblem(c *gin.Context, problem Problem) {
+	c.Header("Content-Type", problemContentType)
+	c.AbortWithStatusJSON(problem.Status, problem)
+}
+
+// problemStatus returns the status of the problem err maps
+// to, or fallback if it does not map to one.
+func problemStatus(err error, fallback int) int {
+	for _, mapping := range errorProblems {
+		if errors.Is(err, mapping.err) {
+			return mapping.status
+		}
+	}
+
+	return fallback
+}
+
+// jsonFieldName returns the JSON name of a struct field.
+func jsonFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return field.Name
+	}
+
+	return name
+}
+
+// fieldPath returns the path of the field below the request,
+// such as "operations[0].op".
+func fieldPath(fieldErr validator.FieldError) string {
+	_, path, found := strings.Cut(fieldErr.Namespace(), ".")
+	if !found {
+		return fieldErr.Field()
+	}
+
+	return path
+}
+
+func fieldMessage(fieldErr validator.FieldError) string {
+	param := fieldErr.Param()
+
+	switch fieldErr.Tag() {
+	case "required", "required_without":
+		return "is required"
+	case "oneof":
+		return "must be one of " + strings.Join(strings.Fields(param), ", ")
+	case "min":
+		switch fieldErr.Kind() {
+		case reflect.String:
+			return "must be at least " + param + " characters long"
+		case reflect.Slice:
+			return "must have at least " + param + " elements"
+		}
+		return "must be at least " + param
+	case "max":
+		switch fieldErr.Kind() {
+		case reflect.String:
+			return "must be at most " + param + " characters long"
+		case reflect.Slice:
+			return "must have at most " + param + " elements"
+		}
+		return "must be at most " + param
+	case "gt":
+		if param == "" {
+			return "must be in the future"
+		}
+		return "must be greater than " + param
+	case "gte":
+		return "must be at least " + param
+	}
+
+	return fmt.Sprintf("failed the %s validation", fieldErr.Tag())
+}
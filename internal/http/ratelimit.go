@@ -0,0 +1,81 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Salam4nder/inventory/internal/config"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// rateLimit is a middleware that limits the requests of a
+// client to the route group. Authenticated clients are
+// limited per API key or user, everyone else per IP address.
+// Requests are let through if the limiter cannot be reached,
+// so the API stays available while the cache is down.
+//
+// The RateLimit headers follow the IETF draft on rate limit
+// header fields, Reset is in seconds.
+func (s *Server) rateLimit(
+	group string, limit config.RateLimit) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !limit.Enabled() {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(
+			c.Request.Context(), time.Second)
+		defer cancel()
+
+		result, err := s.cache.Allow(
+			ctx, group+":"+rateLimitClient(c), limit)
+		if err != nil {
+			s.log(c).Error(err.Error(), zap.Error(err))
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Limit", strconv.Itoa(limit.Requests))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("RateLimit-Reset", seconds(result.Reset))
+		c.Header("RateLimit-Policy", fmt.Sprintf(
+			"%d;w=%s", limit.Requests, seconds(limit.Period)))
+
+		if !result.Allowed {
+			c.Header("Retry-After", seconds(result.RetryAfter))
+			abortWithProblem(c, http.StatusTooManyRequests,
+				"rate limit exceeded")
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// rateLimitClient identifies the client of a request.
+// It must run after jwtValidator to tell clients apart
+// by their credentials.
+func rateLimitClient(c *gin.Context) string {
+	claims := claimsFrom(c)
+
+	switch {
+	case claims == nil:
+		return "ip:" + c.ClientIP()
+	case c.GetHeader(apiKeyHeader) != "":
+		return "apikey:" + claims.Subject
+	default:
+		return "user:" + claims.Subject
+	}
+}
+
+// seconds formats d as whole seconds, rounded up.
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
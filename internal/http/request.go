@@ -1,28 +1,34 @@
 package http
 
 import (
+	"errors"
 	"time"
 
 	"github.com/Salam4nder/inventory/internal/persistence"
 
+	"github.com/gin-gonic/gin/binding"
 	"github.com/google/uuid"
 )
 
 // CreateItemRequest is a request to create an item.
+// The item is checked by persistence.ValidateItem.
 type CreateItemRequest struct {
-	Name      string    `json:"name" binding:"required"`
-	Unit      string    `json:"unit" binding:"required"`
-	Amount    float64   `json:"amount" binding:"required"`
-	ExpiresAt time.Time `json:"expires_at" binding:"required"`
+	Name      string    `json:"name"`
+	Unit      string    `json:"unit"`
+	Amount    float64   `json:"amount"`
+	ExpiresAt time.Time `json:"expires_at"`
+	SKU       string    `json:"sku"`
 }
 
 // UpdateItemRequest is a request to update an item.
+// The item is checked by persistence.ValidateItem.
 type UpdateItemRequest struct {
 	ID        uuid.UUID `json:"uuid" binding:"required"`
-	Name      string    `json:"name" binding:"required"`
-	Unit      string    `json:"unit" binding:"required"`
-	Amount    float64   `json:"amount" binding:"required"`
-	ExpiresAt time.Time `json:"expires_at" binding:"required"`
+	Name      string    `json:"name"`
+	Unit      string    `json:"unit"`
+	Amount    float64   `json:"amount"`
+	ExpiresAt time.Time `json:"expires_at"`
+	SKU       string    `json:"sku"`
 }
 
 // ToPersistenceItem converts CreateItemRequest
@@ -33,6 +39,7 @@ func (r *CreateItemRequest) ToPersistenceItem() persistence.Item {
 		Unit:      r.Unit,
 		Amount:    r.Amount,
 		ExpiresAt: r.ExpiresAt,
+		SKU:       r.SKU,
 	}
 }
 
@@ -45,5 +52,176 @@ func (r *UpdateItemRequest) ToPersistenceItem() persistence.Item {
 		Unit:      r.Unit,
 		Amount:    r.Amount,
 		ExpiresAt: r.ExpiresAt,
+		SKU:       r.SKU,
 	}
 }
+
+// CreateReservationRequest is a request to reserve
+// a quantity of an item.
+type CreateReservationRequest struct {
+	Quantity   float64 `json:"quantity" binding:"required,gt=0"`
+	Owner      string  `json:"owner" binding:"required"`
+	TTLSeconds int     `json:"ttl_seconds" binding:"required,gt=0"`
+}
+
+// ToPersistenceReservation converts CreateReservationRequest
+// to a persistence.Reservation for the given item.
+func (r *CreateReservationRequest) ToPersistenceReservation(
+	itemID uuid.UUID) persistence.Reservation {
+	return persistence.Reservation{
+		ItemID:   itemID,
+		Quantity: r.Quantity,
+		Owner:    r.Owner,
+	}
+}
+
+// TTL returns how long the reservation is held.
+func (r *CreateReservationRequest) TTL() time.Duration {
+	return time.Duration(r.TTLSeconds) * time.Second
+}
+
+// BatchRequest is a request to create, update and
+// delete many items at once. If Atomic is set, either
+// all operations succeed or none of them are applied.
+type BatchRequest struct {
+	Atomic     bool                    `json:"atomic"`
+	Operations []BatchOperationRequest `json:"operations" binding:"required,min=1,max=10000"`
+}
+
+// BatchOperationRequest is a single operation of a BatchRequest.
+// Creates take the same fields as CreateItemRequest, updates the
+// same fields as UpdateItemRequest and deletes only the uuid.
+type BatchOperationRequest struct {
+	Op        persistence.BatchOp `json:"op"`
+	ID        uuid.UUID           `json:"uuid"`
+	Name      string              `json:"name"`
+	Unit      string              `json:"unit"`
+	Amount    float64             `json:"amount"`
+	ExpiresAt time.Time           `json:"expires_at"`
+	SKU       string              `json:"sku"`
+}
+
+// ToPersistenceOperation validates BatchOperationRequest
+// and converts it to a persistence.BatchOperation.
+func (r *BatchOperationRequest) ToPersistenceOperation() (
+	persistence.BatchOperation, error) {
+	switch r.Op {
+	case persistence.BatchCreate:
+		createRequest := CreateItemRequest{
+			Name:      r.Name,
+			Unit:      r.Unit,
+			Amount:    r.Amount,
+			ExpiresAt: r.ExpiresAt,
+			SKU:       r.SKU,
+		}
+		item := createRequest.ToPersistenceItem()
+		if err := persistence.ValidateItem(item); err != nil {
+			return persistence.BatchOperation{}, err
+		}
+
+		return persistence.BatchOperation{
+			Op:   r.Op,
+			Item: item,
+		}, nil
+	case persistence.BatchUpdate:
+		updateRequest := UpdateItemRequest{
+			ID:        r.ID,
+			Name:      r.Name,
+			Unit:      r.Unit,
+			Amount:    r.Amount,
+			ExpiresAt: r.ExpiresAt,
+			SKU:       r.SKU,
+		}
+		if err := binding.Validator.ValidateStruct(
+			&updateRequest); err != nil {
+			return persistence.BatchOperation{}, err
+		}
+
+		item := updateRequest.ToPersistenceItem()
+		if err := persistence.ValidateItem(item); err != nil {
+			return persistence.BatchOperation{}, err
+		}
+
+		return persistence.BatchOperation{
+			Op:   r.Op,
+			Item: item,
+		}, nil
+	case persistence.BatchDelete:
+		if r.ID == uuid.Nil {
+			return persistence.BatchOperation{},
+				errors.New("uuid is required")
+		}
+
+		return persistence.BatchOperation{
+			Op:   r.Op,
+			Item: persistence.Item{ID: r.ID},
+		}, nil
+	default:
+		return persistence.BatchOperation{},
+			persistence.ErrInvalidBatchOp
+	}
+}
+
+// SearchRequest is a request to search items by name.
+type SearchRequest struct {
+	Query string `form:"q" binding:"required"`
+	Limit int    `form:"limit" binding:"omitempty,min=1,max=100"`
+}
+
+// LoginRequest is a request to log in with credentials.
+type LoginRequest struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// RefreshRequest is a request to exchange a refresh
+// token, or to revoke it on logout.
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// CreateUserRequest is a request to create a user. The user
+// is created in the tenant of the caller unless Tenant is set.
+type CreateUserRequest struct {
+	Username string `json:"username" binding:"required,max=255"`
+	Password string `json:"password" binding:"required,min=8,max=72"`
+	Role     string `json:"role" binding:"required,oneof=viewer operator admin superadmin"`
+	Tenant   string `json:"tenant" binding:"omitempty,max=64"`
+}
+
+// SetRoleRequest is a request to change the role of a user.
+type SetRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=viewer operator admin superadmin"`
+}
+
+// ResetPasswordRequest is a request to reset
+// the password of a user.
+type ResetPasswordRequest struct {
+	Password string `json:"password" binding:"required,min=8,max=72"`
+}
+
+// APIKeyRequest is a request to create or update an API key.
+// Scopes are the permissions granted to the key. Keys without
+// an expiry are valid until they are deleted.
+type APIKeyRequest struct {
+	Name      string     `json:"name" binding:"required,max=255"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1,dive,oneof=items:read items:write items:delete users:manage"`
+	ExpiresAt *time.Time `json:"expires_at" binding:"omitempty,gt"`
+}
+
+// ToPersistenceAPIKey converts APIKeyRequest
+// to a persistence.APIKey.
+func (r *APIKeyRequest) ToPersistenceAPIKey() persistence.APIKey {
+	return persistence.APIKey{
+		Name:      r.Name,
+		Scopes:    r.Scopes,
+		ExpiresAt: r.ExpiresAt,
+	}
+}
+
+// RevokeTokenRequest is a request to revoke the access token
+// with the given ID, or every access token of a subject.
+type RevokeTokenRequest struct {
+	TokenID string `json:"jti" binding:"required_without=Subject"`
+	Subject string `json:"subject" binding:"required_without=TokenID"`
+}
@@ -0,0 +1,156 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+func (s *Server) reserveItem(c *gin.Context) {
+	itemID, err := uuid.Parse(c.Param("uuid"))
+	if err != nil {
+		abortWithProblem(c, http.StatusBadRequest, "invalid uuid")
+		return
+	}
+
+	var reservationRequest CreateReservationRequest
+
+	if err := c.ShouldBindJSON(&reservationRequest); err != nil {
+		s.invalid(c, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(
+		c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	reservation, err := s.storage.Reserve(ctx,
+		reservationRequest.ToPersistenceReservation(itemID),
+		reservationRequest.TTL())
+	if err != nil {
+		s.fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, reservation)
+}
+
+func (s *Server) readStock(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("uuid"))
+	if err != nil {
+		abortWithProblem(c, http.StatusBadRequest, "invalid uuid")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(
+		c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	stock, err := s.storage.Available(ctx, id.String())
+	if err != nil {
+		s.fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, stock)
+}
+
+func (s *Server) readReservation(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("uuid"))
+	if err != nil {
+		abortWithProblem(c, http.StatusBadRequest, "invalid uuid")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(
+		c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	reservation, err := s.storage.ReadReservation(ctx, id.String())
+	if err != nil {
+		s.fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, reservation)
+}
+
+func (s *Server) commitReservation(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("uuid"))
+	if err != nil {
+		abortWithProblem(c, http.StatusBadRequest, "invalid uuid")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(
+		c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	reservation, err := s.storage.CommitReservation(ctx, id.String())
+	if err != nil {
+		s.fail(c, err)
+		return
+	}
+
+	// The amount of the item changed, so the cached
+	// copy is stale.
+	if err := s.cache.Delete(
+		ctx, reservation.ItemID.String()); err != nil {
+		s.log(c).Info(err.Error(), zap.Error(err))
+	}
+
+	c.JSON(http.StatusOK, reservation)
+}
+
+func (s *Server) releaseReservation(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("uuid"))
+	if err != nil {
+		abortWithProblem(c, http.StatusBadRequest, "invalid uuid")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(
+		c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	reservation, err := s.storage.ReleaseReservation(ctx, id.String())
+	if err != nil {
+		s.fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, reservation)
+}
+
+// sweepReservations periodically releases expired
+// reservations until the given context is done. It
+// waits for the database to be migrated.
+func (s *Server) sweepReservations(ctx context.Context) {
+	ticker := time.NewTicker(s.config.ReservationSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if s.migrationStatus() != statusUp {
+				continue
+			}
+
+			released, err := s.storage.ReleaseExpiredReservations(ctx)
+			if err != nil {
+				s.logger.Error(err.Error(), zap.Error(err))
+				continue
+			}
+			if released > 0 {
+				s.logger.Info("released expired reservations",
+					zap.Int64("count", released))
+			}
+		}
+	}
+}
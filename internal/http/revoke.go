@@ -0,0 +1,106 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Salam4nder/inventory/internal/persistence"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// revokeToken denies an access token, or all access tokens of
+// a subject, until they expire. Revoking a subject also revokes
+// the refresh tokens of the user, so no new tokens are issued.
+//
+// Tokens are only denied in the tenant of the caller. Subjects
+// must be users of that tenant, unless an OIDC provider issues
+// tokens, whose subjects are not known to the service. Token
+// IDs cannot be looked up, they only deny tokens of the tenant.
+//
+// The denylist entries live as long as the access tokens the
+// service issues, tokens of an OIDC provider that live longer
+// are denied for that time only.
+func (s *Server) revokeToken(c *gin.Context) {
+	var revokeRequest RevokeTokenRequest
+
+	if err := c.ShouldBindJSON(&revokeRequest); err != nil {
+		s.invalid(c, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(
+		c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	var local bool
+	if revokeRequest.Subject != "" {
+		var err error
+		local, err = s.localSubject(ctx, revokeRequest.Subject)
+		if err != nil {
+			s.fail(c, err)
+			return
+		}
+
+		if !local && s.config.OIDCIssuer == "" {
+			abortWithProblem(c, http.StatusNotFound, "subject not found")
+			return
+		}
+	}
+
+	expiration := s.config.AccessTokenExpiration
+
+	if revokeRequest.TokenID != "" {
+		if err := s.cache.RevokeToken(
+			ctx, revokeRequest.TokenID, expiration); err != nil {
+			s.fail(c, err)
+			return
+		}
+	}
+
+	if revokeRequest.Subject != "" {
+		if err := s.cache.RevokeSubject(
+			ctx, revokeRequest.Subject, time.Now(), expiration); err != nil {
+			s.fail(c, err)
+			return
+		}
+
+		// API keys and OIDC users have no refresh tokens.
+		if local {
+			if err := s.storage.RevokeUserRefreshTokens(
+				ctx, revokeRequest.Subject); err != nil {
+				s.fail(c, err)
+				return
+			}
+		}
+	}
+
+	s.log(c).Info("tokens revoked", append(callerFields(c),
+		zap.String("jti", revokeRequest.TokenID),
+		zap.String("revoked_subject", revokeRequest.Subject))...)
+
+	c.Status(http.StatusNoContent)
+}
+
+// localSubject reports whether the subject is a user of the
+// tenant of ctx. Subjects of local users are their IDs.
+func (s *Server) localSubject(
+	ctx context.Context, subject string) (bool, error) {
+	if _, err := uuid.Parse(subject); err != nil {
+		return false, nil
+	}
+
+	_, err := s.storage.ReadUser(ctx, subject)
+	if errors.Is(err, persistence.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
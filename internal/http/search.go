@@ -0,0 +1,39 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultSearchLimit is the number of results returned
+// when the search request does not set a limit.
+const defaultSearchLimit = 20
+
+func (s *Server) searchItems(c *gin.Context) {
+	var searchRequest SearchRequest
+
+	if err := c.ShouldBindQuery(&searchRequest); err != nil {
+		s.invalid(c, err)
+		return
+	}
+
+	if searchRequest.Limit == 0 {
+		searchRequest.Limit = defaultSearchLimit
+	}
+
+	ctx, cancel := context.WithTimeout(
+		c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	results, err := s.storage.Search(
+		ctx, searchRequest.Query, searchRequest.Limit)
+	if err != nil {
+		s.fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, results)
+}
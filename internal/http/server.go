@@ -5,11 +5,16 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"sync/atomic"
 	"time"
 
+	"github.com/Salam4nder/inventory/internal/authn"
 	"github.com/Salam4nder/inventory/internal/cache"
 	"github.com/Salam4nder/inventory/internal/config"
+	"github.com/Salam4nder/inventory/internal/metrics"
 	"github.com/Salam4nder/inventory/internal/persistence"
+	"github.com/Salam4nder/inventory/internal/tracing"
+	"github.com/Salam4nder/inventory/pkg/auth"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -21,14 +26,16 @@ type Server struct {
 	config  config.Server
 	storage persistence.Storage
 	cache   cache.Service
+	keys    *auth.KeySet
+	authn   *authn.Authenticator
+	metrics *metrics.Metrics
 	logger  *zap.Logger
-}
-
-// Health is the structure for the health check endpoint.
-type Health struct {
-	Service string `json:"service"`
-	Status  string `json:"status"`
-	Time    string `json:"time"`
+	// metricsHTTP serves the metrics apart from the API,
+	// it is nil if they are not served.
+	metricsHTTP *http.Server
+	// migration is the status of the database migrations,
+	// see migrationStatus.
+	migration atomic.Value
 }
 
 // New creates a new instance of the API server.
@@ -36,17 +43,32 @@ func New(
 	cfg config.Server,
 	store persistence.Storage,
 	cache cache.Service,
+	keys *auth.KeySet,
+	authn *authn.Authenticator,
+	metrics *metrics.Metrics,
 	log *zap.Logger) *Server {
 	srv := &http.Server{
 		Addr: cfg.Addr(),
 	}
 
+	var metricsSrv *http.Server
+	if metrics != nil && cfg.MetricsPort != "" {
+		metricsSrv = &http.Server{
+			Addr:    cfg.MetricsAddr(),
+			Handler: metricsHandler(metrics),
+		}
+	}
+
 	return &Server{
-		http:    srv,
-		config:  cfg,
-		storage: store,
-		cache:   cache,
-		logger:  log,
+		http:        srv,
+		metricsHTTP: metricsSrv,
+		config:      cfg,
+		storage:     store,
+		cache:       cache,
+		keys:        keys,
+		authn:       authn,
+		metrics:     metrics,
+		logger:      log,
 	}
 }
 
@@ -58,6 +80,10 @@ func (s *Server) Start() {
 
 	s.initEndpoints()
 
+	if s.config.ReservationSweepInterval > 0 {
+		go s.sweepReservations(ctx)
+	}
+
 	go func() {
 		if err := s.http.ListenAndServe(); err != nil &&
 			err != http.ErrServerClosed {
@@ -65,6 +91,17 @@ func (s *Server) Start() {
 		}
 	}()
 
+	if s.metricsHTTP != nil {
+		go func() {
+			if err := s.metricsHTTP.ListenAndServe(); err != nil &&
+				err != http.ErrServerClosed {
+				s.logger.Fatal("metrics listen: ", zap.Error(err))
+			}
+		}()
+		s.logger.Info("metrics server listening...",
+			zap.String("addr", s.metricsHTTP.Addr))
+	}
+
 	<-ctx.Done()
 
 	stop()
@@ -74,25 +111,91 @@ func (s *Server) Start() {
 		context.Background(), 5*time.Second)
 	defer cancel()
 
+	if s.metricsHTTP != nil {
+		if err := s.metricsHTTP.Shutdown(ctx); err != nil {
+			s.logger.Error("metrics server shutdown: ", zap.Error(err))
+		}
+	}
+
 	if err := s.http.Shutdown(ctx); err != nil {
 		s.logger.Fatal("server shutdown: ", zap.Error(err))
 	}
 }
 
 func (s *Server) initEndpoints() {
-	router := gin.Default()
-	router.GET("/auth", s.newJWT)
-	router.GET("/health", s.health)
+	router := gin.New()
+	router.Use(tracing.Gin(), s.requestID(),
+		s.accessLog(), s.instrument(), s.recovery())
+	router.NoRoute(func(c *gin.Context) {
+		abortWithProblem(c, http.StatusNotFound, "route not found")
+	})
+
+	// Routes that use the database are only served once
+	// it is migrated. The probes and documentation are
+	// served right away.
+	migrated := s.migrated()
+
+	authLimit := s.rateLimit("auth", s.config.RateLimitAuth)
+	router.POST("/auth/login", migrated, authLimit, s.login)
+	router.POST("/auth/refresh", migrated, authLimit, s.refresh)
+	router.POST("/auth/logout", migrated, authLimit, s.logout)
+	router.GET("/.well-known/jwks.json", s.jwks)
+	router.GET("/openapi.json", s.openAPI)
+	router.GET(docsPath+"*any", swaggerUI())
+	router.GET("/livez", s.livez)
+	router.GET("/readyz", s.readyz)
+	// /health predates the probes and is kept for
+	// existing monitors.
+	router.GET("/health", s.readyz)
+
+	read := authorize(auth.PermissionItemsRead)
+	write := authorize(auth.PermissionItemsWrite)
+	remove := authorize(auth.PermissionItemsDelete)
+	// Imports are streamed and not buffered for idempotency.
+	// Retries of imports must upsert instead, importItems
+	// refuses an Idempotency-Key otherwise.
+	idempotent := s.idempotent()
 
 	authRoute := router.Group("/api").
-		Use(jwtValidator(s.config.JWTSecret))
+		Use(migrated, s.jwtValidator(),
+			s.rateLimit("api", s.config.RateLimitAPI))
+	{
+		authRoute.GET("/item", read, s.readItems)
+		authRoute.GET("/item/:uuid", read, s.readItem)
+		authRoute.GET("/item/filter", read, s.readItemsBy)
+		authRoute.GET("/search", read, s.searchItems)
+		authRoute.POST("/item", write, idempotent, s.createItem)
+		authRoute.POST("/item/batch", write, idempotent, s.batchItems)
+		authRoute.POST("/import", write, s.importItems)
+		authRoute.PUT("/item/:uuid", write, s.updateItem)
+		authRoute.DELETE("/item/:uuid", remove, s.deleteItem)
+		authRoute.GET("/item/:uuid/stock", read, s.readStock)
+		authRoute.POST("/item/:uuid/reservation", write, idempotent, s.reserveItem)
+		authRoute.GET("/reservation/:uuid", read, s.readReservation)
+		authRoute.POST("/reservation/:uuid/commit", write, idempotent, s.commitReservation)
+		authRoute.POST("/reservation/:uuid/release", write, idempotent, s.releaseReservation)
+	}
+
+	router.POST("/graphql", migrated, s.jwtValidator(),
+		s.rateLimit("api", s.config.RateLimitAPI), s.graphQL())
+
+	adminRoute := router.Group("/admin").
+		Use(migrated, s.jwtValidator(),
+			s.rateLimit("admin", s.config.RateLimitAdmin),
+			authorize(auth.PermissionUsersManage))
 	{
-		authRoute.GET("/item", s.readItems)
-		authRoute.GET("/item/:uuid", s.readItem)
-		authRoute.GET("/item/filter", s.readItemsBy)
-		authRoute.POST("/item", s.createItem)
-		authRoute.PUT("/item/:uuid", s.updateItem)
-		authRoute.DELETE("/item/:uuid", s.deleteItem)
+		adminRoute.GET("/user", s.readUsers)
+		adminRoute.POST("/user", s.createUser)
+		adminRoute.POST("/user/:uuid/disable", s.disableUser)
+		adminRoute.POST("/user/:uuid/enable", s.enableUser)
+		adminRoute.POST("/user/:uuid/password", s.resetPassword)
+		adminRoute.POST("/user/:uuid/role", s.setUserRole)
+		adminRoute.GET("/apikey", s.readAPIKeys)
+		adminRoute.POST("/apikey", s.createAPIKey)
+		adminRoute.GET("/apikey/:uuid", s.readAPIKey)
+		adminRoute.PUT("/apikey/:uuid", s.updateAPIKey)
+		adminRoute.DELETE("/apikey/:uuid", s.deleteAPIKey)
+		adminRoute.POST("/token/revoke", s.revokeToken)
 	}
 
 	s.http.Handler = router
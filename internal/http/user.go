@@ -0,0 +1,237 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/Salam4nder/inventory/internal/persistence"
+	"github.com/Salam4nder/inventory/pkg/auth"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func (s *Server) readUsers(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(
+		c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	users, err := s.storage.ReadUsers(ctx)
+	if err != nil {
+		s.fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, users)
+}
+
+func (s *Server) createUser(c *gin.Context) {
+	var createRequest CreateUserRequest
+
+	if err := c.ShouldBindJSON(&createRequest); err != nil {
+		s.invalid(c, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(
+		c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	// Only super admins may create users in other tenants,
+	// such as the first user of one, and other super admins.
+	if !hasPermission(c, auth.PermissionTenantsManage) &&
+		auth.Role(createRequest.Role) == auth.RoleSuperAdmin {
+		abortWithProblem(c, http.StatusForbidden, "cannot create super admins")
+		return
+	}
+
+	if tenant := createRequest.Tenant; tenant != "" &&
+		tenant != persistence.TenantFrom(ctx) {
+		if !hasPermission(c, auth.PermissionTenantsManage) {
+			abortWithProblem(c, http.StatusForbidden, "cannot create users in another tenant")
+			return
+		}
+		ctx = persistence.WithTenant(ctx, tenant)
+	}
+
+	hash, err := auth.HashPassword(createRequest.Password)
+	if err != nil {
+		s.fail(c, err)
+		return
+	}
+
+	user, err := s.storage.CreateUser(ctx, persistence.User{
+		Username:     createRequest.Username,
+		PasswordHash: hash,
+		Role:         createRequest.Role,
+	})
+	if err != nil {
+		s.fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, user)
+}
+
+func (s *Server) disableUser(c *gin.Context) {
+	s.setUserDisabled(c, true)
+}
+
+func (s *Server) enableUser(c *gin.Context) {
+	s.setUserDisabled(c, false)
+}
+
+func (s *Server) setUserDisabled(c *gin.Context, disabled bool) {
+	uuid, found := c.Params.Get("uuid")
+	if !found {
+		abortWithProblem(c, http.StatusBadRequest, "uuid not found")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(
+		c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	if !s.manageable(ctx, c, uuid) {
+		return
+	}
+
+	user, err := s.storage.SetUserDisabled(ctx, uuid, disabled)
+	if err != nil {
+		s.fail(c, err)
+		return
+	}
+
+	// Access tokens issued before the user was disabled
+	// must not be accepted anymore.
+	if disabled {
+		if err := s.denyUserTokens(
+			ctx, user.ID.String()); err != nil {
+			s.log(c).Error(err.Error(), zap.Error(err))
+		}
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+func (s *Server) resetPassword(c *gin.Context) {
+	uuid, found := c.Params.Get("uuid")
+	if !found {
+		abortWithProblem(c, http.StatusBadRequest, "uuid not found")
+		return
+	}
+
+	var resetRequest ResetPasswordRequest
+
+	if err := c.ShouldBindJSON(&resetRequest); err != nil {
+		s.invalid(c, err)
+		return
+	}
+
+	hash, err := auth.HashPassword(resetRequest.Password)
+	if err != nil {
+		s.fail(c, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(
+		c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	if !s.manageable(ctx, c, uuid) {
+		return
+	}
+
+	user, err := s.storage.SetUserPassword(ctx, uuid, hash)
+	if err != nil {
+		s.fail(c, err)
+		return
+	}
+
+	// Sessions started with the old password must
+	// not outlive the reset.
+	if err := s.storage.RevokeUserRefreshTokens(
+		ctx, user.ID.String()); err != nil {
+		s.log(c).Error(err.Error(), zap.Error(err))
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+func (s *Server) setUserRole(c *gin.Context) {
+	uuid, found := c.Params.Get("uuid")
+	if !found {
+		abortWithProblem(c, http.StatusBadRequest, "uuid not found")
+		return
+	}
+
+	var roleRequest SetRoleRequest
+
+	if err := c.ShouldBindJSON(&roleRequest); err != nil {
+		s.invalid(c, err)
+		return
+	}
+
+	if !hasPermission(c, auth.PermissionTenantsManage) &&
+		auth.Role(roleRequest.Role) == auth.RoleSuperAdmin {
+		abortWithProblem(c, http.StatusForbidden, "cannot grant super admin")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(
+		c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	if !s.manageable(ctx, c, uuid) {
+		return
+	}
+
+	user, err := s.storage.SetUserRole(ctx, uuid, roleRequest.Role)
+	if err != nil {
+		s.fail(c, err)
+		return
+	}
+
+	// Tokens issued before the change still carry the
+	// permissions of the old role. The user gets the new
+	// ones with the next refresh.
+	if err := s.denyUserTokens(
+		ctx, user.ID.String()); err != nil {
+		s.log(c).Error(err.Error(), zap.Error(err))
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// denyUserTokens puts the access tokens the user holds
+// on the denylist. Refresh tokens stay valid and issue
+// tokens that reflect the current state of the user.
+func (s *Server) denyUserTokens(
+	ctx context.Context, userID string) error {
+	return s.cache.RevokeSubject(
+		ctx, userID, time.Now(), s.config.AccessTokenExpiration)
+}
+
+// manageable reports whether the caller may change the user.
+// Only super admins may change other super admins. Otherwise
+// the request is aborted.
+func (s *Server) manageable(
+	ctx context.Context, c *gin.Context, uuid string) bool {
+	if hasPermission(c, auth.PermissionTenantsManage) {
+		return true
+	}
+
+	user, err := s.storage.ReadUser(ctx, uuid)
+	if err != nil {
+		s.fail(c, err)
+		return false
+	}
+
+	if auth.Role(user.Role) == auth.RoleSuperAdmin {
+		abortWithProblem(c, http.StatusForbidden, "cannot change a super admin")
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,206 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Salam4nder/inventory/internal/persistence"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "inventory"
+
+// Cache results counted by CacheResult.
+const (
+	CacheHit   = "hit"
+	CacheMiss  = "miss"
+	CacheError = "error"
+)
+
+// Metrics are the Prometheus metrics of the application.
+// The methods of a nil *Metrics do nothing, so metrics
+// are optional wherever they are recorded.
+type Metrics struct {
+	registry *prometheus.Registry
+	requests *prometheus.HistogramVec
+	cache    *prometheus.CounterVec
+}
+
+// New returns metrics with the request and cache metrics
+// and the Go runtime and process collectors registered.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "Duration of HTTP requests by route and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		cache: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "cache",
+			Name:      "requests_total",
+			Help:      "Item cache lookups by result: hit, miss or error.",
+		}, []string{"result"}),
+	}
+
+	m.registry.MustRegister(
+		m.requests,
+		m.cache,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(
+			collectors.ProcessCollectorOpts{}),
+	)
+
+	return m
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// ObserveRequest records an HTTP request. The route is
+// the pattern of the request path, such as /api/item/:uuid,
+// to keep the number of series bounded.
+func (m *Metrics) ObserveRequest(
+	method, route string, status int, duration time.Duration) {
+	if m == nil {
+		return
+	}
+
+	m.requests.WithLabelValues(
+		method, route, strconv.Itoa(status)).Observe(duration.Seconds())
+}
+
+// CacheResult counts a lookup of the item cache.
+func (m *Metrics) CacheResult(result string) {
+	if m == nil {
+		return
+	}
+
+	m.cache.WithLabelValues(result).Inc()
+}
+
+// RegisterDB exports the connection pool stats of db,
+// such as the open, in-use and idle connections and the
+// time spent waiting for one.
+func (m *Metrics) RegisterDB(db *sql.DB, name string) error {
+	return m.registry.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+// ItemCounter counts the items of every tenant.
+// It is implemented by persistence.SQLDatabase.
+type ItemCounter interface {
+	ItemStats(ctx context.Context, lowStock float64) (
+		[]persistence.ItemStats, error)
+}
+
+// RegisterItems exports the total, expired and low stock
+// items of every tenant. The items are counted at most once
+// per interval, scrapes in between get the last count. Items
+// with an amount below lowStock are low on stock.
+func (m *Metrics) RegisterItems(
+	counter ItemCounter, lowStock float64, interval time.Duration) error {
+	return m.registry.Register(
+		newItemCollector(counter, lowStock, interval))
+}
+
+// itemCollector counts the items when it is collected
+// and the last count is older than the interval.
+type itemCollector struct {
+	counter  ItemCounter
+	lowStock float64
+	interval time.Duration
+	total    *prometheus.Desc
+	expired  *prometheus.Desc
+	low      *prometheus.Desc
+
+	mu        sync.Mutex
+	stats     []persistence.ItemStats
+	countedAt time.Time
+}
+
+func newItemCollector(
+	counter ItemCounter,
+	lowStock float64,
+	interval time.Duration) *itemCollector {
+	tenant := []string{"tenant"}
+
+	return &itemCollector{
+		counter:  counter,
+		lowStock: lowStock,
+		interval: interval,
+		total: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "items", "total"),
+			"Number of items.", tenant, nil),
+		expired: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "items", "expired"),
+			"Number of expired items.", tenant, nil),
+		low: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "items", "low_stock"),
+			"Number of items with an amount below the low stock threshold.",
+			tenant, nil),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *itemCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.total
+	ch <- c.expired
+	ch <- c.low
+}
+
+// Collect implements prometheus.Collector. If the items
+// cannot be counted the scrape reports the error.
+func (c *itemCollector) Collect(ch chan<- prometheus.Metric) {
+	stats, err := c.itemStats()
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.total, err)
+		return
+	}
+
+	for _, stat := range stats {
+		ch <- prometheus.MustNewConstMetric(c.total,
+			prometheus.GaugeValue, float64(stat.Total), stat.Tenant)
+		ch <- prometheus.MustNewConstMetric(c.expired,
+			prometheus.GaugeValue, float64(stat.Expired), stat.Tenant)
+		ch <- prometheus.MustNewConstMetric(c.low,
+			prometheus.GaugeValue, float64(stat.LowStock), stat.Tenant)
+	}
+}
+
+// itemStats returns the last count of the items, or counts
+// them again if it is older than the interval. Concurrent
+// scrapes wait for the same count. Failed counts are not
+// kept, the next scrape tries again.
+func (c *itemCollector) itemStats() ([]persistence.ItemStats, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.countedAt.IsZero() && time.Since(c.countedAt) < c.interval {
+		return c.stats, nil
+	}
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(), 5*time.Second)
+	defer cancel()
+
+	stats, err := c.counter.ItemStats(ctx, c.lowStock)
+	if err != nil {
+		return nil, err
+	}
+
+	c.stats, c.countedAt = stats, time.Now()
+
+	return stats, nil
+}
@@ -0,0 +1,125 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Salam4nder/inventory/internal/persistence"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+type itemCounter struct {
+	stats []persistence.ItemStats
+	err   error
+	calls int
+}
+
+func (c *itemCounter) ItemStats(
+	ctx context.Context, lowStock float64) ([]persistence.ItemStats, error) {
+	c.calls++
+
+	return c.stats, c.err
+}
+
+func TestObserveRequest(t *testing.T) {
+	m := New()
+
+	m.ObserveRequest("GET", "/api/item/:uuid", 200, time.Millisecond)
+	m.ObserveRequest("GET", "/api/item/:uuid", 404, time.Millisecond)
+	m.ObserveRequest("GET", "/api/item/:uuid", 404, time.Millisecond)
+
+	if n := testutil.CollectAndCount(m.requests); n != 2 {
+		t.Errorf("unexpected number of series: %d", n)
+	}
+}
+
+func TestCacheResult(t *testing.T) {
+	m := New()
+
+	m.CacheResult(CacheHit)
+	m.CacheResult(CacheHit)
+	m.CacheResult(CacheMiss)
+
+	if v := testutil.ToFloat64(m.cache.WithLabelValues(CacheHit)); v != 2 {
+		t.Errorf("unexpected hits: %v", v)
+	}
+
+	if v := testutil.ToFloat64(m.cache.WithLabelValues(CacheMiss)); v != 1 {
+		t.Errorf("unexpected misses: %v", v)
+	}
+
+	var nilMetrics *Metrics
+	nilMetrics.CacheResult(CacheHit)
+}
+
+func TestRegisterItems(t *testing.T) {
+	m := New()
+
+	err := m.RegisterItems(&itemCounter{stats: []persistence.ItemStats{
+		{Tenant: "acme", Total: 5, Expired: 1, LowStock: 2},
+	}}, 10, time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+	for _, line := range []string{
+		`inventory_items_total{tenant="acme"} 5`,
+		`inventory_items_expired{tenant="acme"} 1`,
+		`inventory_items_low_stock{tenant="acme"} 2`,
+	} {
+		if !strings.Contains(w.Body.String(), line) {
+			t.Errorf("missing %s", line)
+		}
+	}
+}
+
+func TestRegisterItems_Error(t *testing.T) {
+	m := New()
+
+	err := m.RegisterItems(
+		&itemCounter{err: errors.New("connection lost")}, 10, time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := m.registry.Gather(); err == nil {
+		t.Errorf("expected the scrape to fail")
+	}
+}
+
+func TestRegisterItems_Interval(t *testing.T) {
+	m := New()
+
+	counter := &itemCounter{err: errors.New("connection lost")}
+	if err := m.RegisterItems(counter, 10, time.Minute); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Failed counts are tried again on the next scrape.
+	for idx := 0; idx < 2; idx++ {
+		if _, err := m.registry.Gather(); err == nil {
+			t.Errorf("expected the scrape to fail")
+		}
+	}
+
+	counter.err = nil
+	counter.stats = []persistence.ItemStats{{Tenant: "acme", Total: 5}}
+
+	for idx := 0; idx < 3; idx++ {
+		if _, err := m.registry.Gather(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if counter.calls != 3 {
+		t.Errorf("items counted %d times, want 3", counter.calls)
+	}
+}
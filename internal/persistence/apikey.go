@@ -0,0 +1,169 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// APIKey is a long-lived credential for non-interactive
+// clients. Only the hash of the key is stored, the prefix
+// identifies the key in listings.
+type APIKey struct {
+	ID         uuid.UUID  `json:"id"`
+	TenantID   string     `json:"tenant"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	KeyHash    string     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	CreatedBy  string     `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// APIKeyStorage is the persistence layer of API keys.
+type APIKeyStorage interface {
+	CreateAPIKey(ctx context.Context, key APIKey) (APIKey, error)
+	ReadAPIKey(ctx context.Context, uuid string) (APIKey, error)
+	ReadAPIKeys(ctx context.Context) ([]APIKey, error)
+	UpdateAPIKey(ctx context.Context, key APIKey) (APIKey, error)
+	DeleteAPIKey(ctx context.Context, uuid string) error
+	UseAPIKey(ctx context.Context, keyHash string) (APIKey, error)
+}
+
+// apiKeyColumns are the columns scanned by scanAPIKey.
+const apiKeyColumns = `id, tenant_id, name, prefix, key_hash, scopes,
+        created_by, created_at, expires_at, last_used_at`
+
+// CreateAPIKey stores a new API key in the tenant of ctx.
+func (s *SQLDatabase) CreateAPIKey(
+	ctx context.Context, key APIKey) (APIKey, error) {
+	query := `INSERT INTO api_keys (
+        name, prefix, key_hash, scopes, created_by, expires_at, tenant_id)
+        VALUES (
+        $1, $2, $3, $4, $5, $6, $7) RETURNING ` + apiKeyColumns
+
+	return scanAPIKey(s.DB.QueryRowContext(
+		ctx,
+		query,
+		key.Name,
+		key.Prefix,
+		key.KeyHash,
+		pq.Array(key.Scopes),
+		key.CreatedBy,
+		key.ExpiresAt,
+		TenantFrom(ctx)))
+}
+
+// ReadAPIKey reads an API key based off of an uuid.
+func (s *SQLDatabase) ReadAPIKey(
+	ctx context.Context, uuid string) (APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys
+        WHERE id = $1 AND tenant_id = $2`
+
+	return scanAPIKey(s.DB.QueryRowContext(ctx, query, uuid, TenantFrom(ctx)))
+}
+
+// ReadAPIKeys reads all API keys ordered by name.
+func (s *SQLDatabase) ReadAPIKeys(ctx context.Context) ([]APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys
+        WHERE tenant_id = $1 ORDER BY name`
+
+	rows, err := s.DB.QueryContext(ctx, query, TenantFrom(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []APIKey{}
+
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+// UpdateAPIKey changes the name, scopes and expiry of an
+// API key. The key itself cannot be changed.
+func (s *SQLDatabase) UpdateAPIKey(
+	ctx context.Context, key APIKey) (APIKey, error) {
+	query := `UPDATE api_keys SET name = $1, scopes = $2, expires_at = $3
+        WHERE id = $4 AND tenant_id = $5 RETURNING ` + apiKeyColumns
+
+	return scanAPIKey(s.DB.QueryRowContext(
+		ctx,
+		query,
+		key.Name,
+		pq.Array(key.Scopes),
+		key.ExpiresAt,
+		key.ID,
+		TenantFrom(ctx)))
+}
+
+// DeleteAPIKey deletes an API key, it is rejected from
+// then on.
+func (s *SQLDatabase) DeleteAPIKey(
+	ctx context.Context, uuid string) error {
+	query := `DELETE FROM api_keys WHERE id = $1 AND tenant_id = $2`
+
+	result, err := s.DB.ExecContext(ctx, query, uuid, TenantFrom(ctx))
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// UseAPIKey reads the unexpired API key with the given hash,
+// in any tenant, and records that it was used. It returns
+// ErrNotFound for unknown and expired keys alike.
+func (s *SQLDatabase) UseAPIKey(
+	ctx context.Context, keyHash string) (APIKey, error) {
+	query := `UPDATE api_keys SET last_used_at = now()
+        WHERE key_hash = $1 AND (expires_at IS NULL OR expires_at > now())
+        RETURNING ` + apiKeyColumns
+
+	return scanAPIKey(s.DB.QueryRowContext(ctx, query, keyHash))
+}
+
+func scanAPIKey(row scanner) (APIKey, error) {
+	var key APIKey
+
+	if err := row.Scan(
+		&key.ID,
+		&key.TenantID,
+		&key.Name,
+		&key.Prefix,
+		&key.KeyHash,
+		pq.Array(&key.Scopes),
+		&key.CreatedBy,
+		&key.CreatedAt,
+		&key.ExpiresAt,
+		&key.LastUsedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return APIKey{}, ErrNotFound
+		}
+		return APIKey{}, err
+	}
+
+	return key, nil
+}
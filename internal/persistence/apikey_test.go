@@ -0,0 +1,126 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	sqlMock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+)
+
+var apiKeyRowColumns = []string{
+	"id", "tenant_id", "name", "prefix", "key_hash", "scopes",
+	"created_by", "created_at", "expires_at", "last_used_at"}
+
+func Test_CreateAPIKey_Success(t *testing.T) {
+	driver, mock, err := sqlMock.New()
+	if err != nil {
+		t.Fatalf(
+			"unexpected error while creating sqlmock: %s",
+			err)
+	}
+	defer driver.Close()
+
+	storage := SQLDatabase{
+		DB: driver,
+	}
+
+	key := APIKey{
+		Name:      "scanner",
+		Prefix:    "inv_abcdefgh",
+		KeyHash:   "hash",
+		Scopes:    []string{"items:read", "items:write"},
+		CreatedBy: "admin",
+	}
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(), 5*time.Second)
+	defer cancel()
+
+	mock.ExpectQuery("INSERT INTO api_keys").WithArgs(
+		key.Name, key.Prefix, key.KeyHash, "{\"items:read\",\"items:write\"}",
+		key.CreatedBy, nil, DefaultTenant).WillReturnRows(
+		sqlMock.NewRows(apiKeyRowColumns).AddRow(
+			uuid.New(), DefaultTenant, key.Name, key.Prefix, key.KeyHash,
+			"{items:read,items:write}", key.CreatedBy, time.Now(),
+			nil, nil))
+
+	created, err := storage.CreateAPIKey(ctx, key)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if len(created.Scopes) != 2 || created.Scopes[1] != "items:write" {
+		t.Errorf("unexpected scopes: %v", created.Scopes)
+	}
+
+	if created.ExpiresAt != nil || created.LastUsedAt != nil {
+		t.Errorf("unexpected timestamps: %+v", created)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func Test_UseAPIKey_Unknown_Or_Expired_Returns_ErrNotFound(t *testing.T) {
+	driver, mock, err := sqlMock.New()
+	if err != nil {
+		t.Fatalf(
+			"unexpected error while creating sqlmock: %s",
+			err)
+	}
+	defer driver.Close()
+
+	storage := SQLDatabase{
+		DB: driver,
+	}
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(), 5*time.Second)
+	defer cancel()
+
+	mock.ExpectQuery("UPDATE api_keys SET last_used_at = now()").
+		WithArgs("hash").WillReturnRows(sqlMock.NewRows(apiKeyRowColumns))
+
+	if _, err := storage.UseAPIKey(ctx, "hash"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func Test_DeleteAPIKey_Missing_Returns_ErrNotFound(t *testing.T) {
+	driver, mock, err := sqlMock.New()
+	if err != nil {
+		t.Fatalf(
+			"unexpected error while creating sqlmock: %s",
+			err)
+	}
+	defer driver.Close()
+
+	storage := SQLDatabase{
+		DB: driver,
+	}
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(), 5*time.Second)
+	defer cancel()
+
+	id := uuid.New().String()
+
+	mock.ExpectExec("DELETE FROM api_keys").WithArgs(id, DefaultTenant).
+		WillReturnResult(sqlMock.NewResult(0, 0))
+
+	if err := storage.DeleteAPIKey(ctx, id); !errors.Is(err, ErrNotFound) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
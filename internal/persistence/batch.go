@@ -0,0 +1,384 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// BatchOp is the kind of a single batch operation.
+type BatchOp string
+
+// Batch operation kinds.
+const (
+	BatchCreate BatchOp = "create"
+	BatchUpdate BatchOp = "update"
+	BatchDelete BatchOp = "delete"
+	// BatchUpsertName updates all items with the name
+	// of the operation item, or creates it if none exist.
+	BatchUpsertName BatchOp = "upsert_name"
+	// BatchUpsertSKU updates the item with the SKU of
+	// the operation item, or creates it if none exists.
+	BatchUpsertSKU BatchOp = "upsert_sku"
+)
+
+// batchInsertChunk is the number of rows per multi-row
+// INSERT. Postgres allows at most 65535 parameters per
+// statement and every row takes seven of them.
+const batchInsertChunk = 1000
+
+// BatchOperation is a single create, update or delete
+// in a batch. Deletes only use the ID of the item.
+type BatchOperation struct {
+	Op   BatchOp
+	Item Item
+}
+
+// BatchResult is the outcome of a single batch operation.
+// Index is the position of the operation in the batch.
+// Upserts are reported as either a create or an update.
+type BatchResult struct {
+	Index int
+	Op    BatchOp
+	ID    uuid.UUID
+	Err   error
+}
+
+// Batch executes the given operations in a single transaction,
+// in the order they are given. Consecutive creates are grouped
+// into multi-row inserts. If atomic is
+// true, any failing operation rolls back the whole batch and
+// ErrBatchAborted is returned alongside the results. Otherwise
+// every operation runs in its own savepoint so failures only
+// affect the operation itself.
+func (s *SQLDatabase) Batch(
+	ctx context.Context,
+	ops []BatchOperation,
+	atomic bool) ([]BatchResult, error) {
+	results := make([]BatchResult, len(ops))
+
+	for idx, op := range ops {
+		results[idx] = BatchResult{Index: idx, Op: op.Op, ID: op.Item.ID}
+
+		switch op.Op {
+		case BatchCreate:
+			results[idx].ID = uuid.New()
+		case BatchUpdate, BatchDelete, BatchUpsertName, BatchUpsertSKU:
+		default:
+			results[idx].Err = ErrInvalidBatchOp
+		}
+	}
+
+	if atomic && failed(results) {
+		return abort(results), ErrBatchAborted
+	}
+
+	tx, err := s.beginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tenant := TenantFrom(ctx)
+	defer tx.Rollback()
+
+	// step runs fn in a savepoint when the batch is
+	// not atomic, so a failing statement does not
+	// poison the rest of the transaction.
+	step := func(fn func() error) error {
+		if atomic {
+			return fn()
+		}
+
+		if _, err := tx.ExecContext(
+			ctx, "SAVEPOINT batch_op"); err != nil {
+			return err
+		}
+
+		if err := fn(); err != nil {
+			if _, rbErr := tx.ExecContext(
+				ctx, "ROLLBACK TO SAVEPOINT batch_op"); rbErr != nil {
+				return errors.Join(err, rbErr)
+			}
+			return err
+		}
+
+		_, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT batch_op")
+		return err
+	}
+
+	insert := func(indexes []int) error {
+		query := insertQueryBuilder(len(indexes))
+		args := make([]interface{}, 0, len(indexes)*7)
+
+		for _, idx := range indexes {
+			item := ops[idx].Item
+			args = append(args,
+				results[idx].ID,
+				item.Name,
+				item.Unit,
+				item.Amount,
+				item.ExpiresAt,
+				item.SKU,
+				tenant)
+		}
+
+		_, err := tx.ExecContext(ctx, query, args...)
+		return err
+	}
+
+	// creates are the indexes of the creates that are not
+	// inserted yet.
+	var creates []int
+
+	// flush inserts the pending creates. It returns
+	// ErrBatchAborted if the batch is atomic and the insert
+	// failed.
+	flush := func() error {
+		chunk := creates
+		creates = nil
+
+		if len(chunk) == 0 {
+			return nil
+		}
+
+		err := step(func() error { return insert(chunk) })
+		if err == nil {
+			return nil
+		}
+
+		if atomic {
+			for _, idx := range chunk {
+				results[idx].Err = err
+			}
+			return ErrBatchAborted
+		}
+
+		// Retry the rows one by one to find the bad ones.
+		for _, idx := range chunk {
+			idx := idx
+			results[idx].Err = step(func() error {
+				return insert([]int{idx})
+			})
+		}
+
+		return nil
+	}
+
+	for idx, op := range ops {
+		if results[idx].Err != nil {
+			continue
+		}
+
+		// Creates are held back until an operation of another
+		// kind follows, which may depend on them.
+		if op.Op == BatchCreate {
+			creates = append(creates, idx)
+			if len(creates) < batchInsertChunk {
+				continue
+			}
+		}
+
+		if err := flush(); err != nil {
+			return abort(results), err
+		}
+
+		switch op.Op {
+		case BatchUpdate:
+			results[idx].Err = step(func() error {
+				if err := checkAmount(ctx, tx,
+					op.Item.ID.String(), op.Item.Amount); err != nil {
+					return err
+				}
+
+				return execOne(ctx, tx,
+					`UPDATE inventory SET
+        name = $1, unit = $2, amount = $3, expires_at = $4,
+        sku = NULLIF($5, '') WHERE id = $6 AND tenant_id = $7`,
+					op.Item.Name,
+					op.Item.Unit,
+					op.Item.Amount,
+					op.Item.ExpiresAt,
+					op.Item.SKU,
+					op.Item.ID,
+					tenant)
+			})
+		case BatchDelete:
+			results[idx].Err = step(func() error {
+				return execOne(ctx, tx,
+					`DELETE FROM inventory WHERE id = $1 AND tenant_id = $2`,
+					op.Item.ID,
+					tenant)
+			})
+		case BatchUpsertName, BatchUpsertSKU:
+			results[idx].Err = step(func() error {
+				var err error
+				results[idx].Op, results[idx].ID, err = upsert(
+					ctx, tx, tenant, op)
+				return err
+			})
+		default:
+			continue
+		}
+
+		if atomic && results[idx].Err != nil {
+			return abort(results), ErrBatchAborted
+		}
+	}
+
+	if err := flush(); err != nil {
+		return abort(results), err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// upsert updates the items of the tenant matching the name or
+// SKU of the operation item, or inserts the item if there is no
+// match. It returns whether the item was created or updated.
+func upsert(
+	ctx context.Context,
+	tx *sql.Tx,
+	tenant string,
+	op BatchOperation) (BatchOp, uuid.UUID, error) {
+	column, key := "name", op.Item.Name
+	if op.Op == BatchUpsertSKU {
+		column, key = "sku", op.Item.SKU
+	}
+
+	query := `UPDATE inventory SET
+        name = $1, unit = $2, amount = $3, expires_at = $4,
+        sku = COALESCE(NULLIF($5, ''), sku)
+        WHERE ` + column + ` = $6 AND tenant_id = $7 RETURNING id`
+
+	rows, err := tx.QueryContext(
+		ctx,
+		query,
+		op.Item.Name,
+		op.Item.Unit,
+		op.Item.Amount,
+		op.Item.ExpiresAt,
+		op.Item.SKU,
+		key,
+		tenant)
+	if err != nil {
+		return op.Op, uuid.Nil, err
+	}
+
+	var (
+		id      uuid.UUID
+		updated bool
+	)
+
+	for rows.Next() {
+		if updated {
+			continue
+		}
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return op.Op, uuid.Nil, err
+		}
+		updated = true
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return op.Op, uuid.Nil, err
+	}
+
+	if updated {
+		return BatchUpdate, id, nil
+	}
+
+	id = uuid.New()
+
+	if _, err := tx.ExecContext(
+		ctx,
+		insertQueryBuilder(1),
+		id,
+		op.Item.Name,
+		op.Item.Unit,
+		op.Item.Amount,
+		op.Item.ExpiresAt,
+		op.Item.SKU,
+		tenant); err != nil {
+		return op.Op, uuid.Nil, err
+	}
+
+	return BatchCreate, id, nil
+}
+
+// execOne executes a statement that must affect exactly
+// one row. It returns ErrNotFound if no row was affected.
+func execOne(
+	ctx context.Context,
+	tx *sql.Tx,
+	query string,
+	args ...interface{}) error {
+	res, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// failed reports whether any of the results has an error.
+func failed(results []BatchResult) bool {
+	for _, result := range results {
+		if result.Err != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// abort marks every successful result of a rolled back
+// batch as aborted.
+func abort(results []BatchResult) []BatchResult {
+	for idx := range results {
+		if results[idx].Err == nil {
+			results[idx].Err = ErrBatchAborted
+		}
+	}
+
+	return results
+}
+
+// insertQueryBuilder builds a multi-row INSERT for rows items.
+func insertQueryBuilder(rows int) string {
+	var query strings.Builder
+
+	query.WriteString(
+		"INSERT INTO inventory (id, name, unit, amount, expires_at, sku, tenant_id) VALUES ")
+
+	for row := 0; row < rows; row++ {
+		if row > 0 {
+			query.WriteString(", ")
+		}
+
+		param := row * 7
+		query.WriteString(fmt.Sprintf(
+			"($%d, $%d, $%d, $%d, $%d, NULLIF($%d, ''), $%d)",
+			param+1, param+2, param+3, param+4, param+5, param+6, param+7))
+	}
+
+	return query.String()
+}
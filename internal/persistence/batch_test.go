@@ -0,0 +1,359 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	sqlMock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+)
+
+func Test_Batch_Atomic_Success(t *testing.T) {
+	driver, mock, err := sqlMock.New()
+	if err != nil {
+		t.Fatalf(
+			"unexpected error while creating sqlmock: %s",
+			err)
+	}
+	defer driver.Close()
+
+	storage := SQLDatabase{
+		DB: driver,
+	}
+
+	ops := []BatchOperation{
+		{Op: BatchCreate, Item: Item{
+			Name: "a", Unit: "kg", Amount: 1,
+			ExpiresAt: time.Now().Add(5 * time.Minute)}},
+		{Op: BatchCreate, Item: Item{
+			Name: "b", Unit: "kg", Amount: 2,
+			ExpiresAt: time.Now().Add(5 * time.Minute)}},
+		{Op: BatchDelete, Item: Item{ID: uuid.New()}},
+	}
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(), 5*time.Second)
+	defer cancel()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO inventory").WillReturnResult(
+		sqlMock.NewResult(0, 2))
+	mock.ExpectExec("DELETE FROM inventory").WithArgs(
+		ops[2].Item.ID, DefaultTenant).WillReturnResult(sqlMock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	results, err := storage.Batch(ctx, ops, true)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	for _, result := range results {
+		if result.Err != nil {
+			t.Errorf("unexpected error in result %d: %v",
+				result.Index, result.Err)
+		}
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func Test_Batch_Atomic_Rolls_Back_On_Missing_Item(t *testing.T) {
+	driver, mock, err := sqlMock.New()
+	if err != nil {
+		t.Fatalf(
+			"unexpected error while creating sqlmock: %s",
+			err)
+	}
+	defer driver.Close()
+
+	storage := SQLDatabase{
+		DB: driver,
+	}
+
+	ops := []BatchOperation{
+		{Op: BatchDelete, Item: Item{ID: uuid.New()}},
+		{Op: BatchDelete, Item: Item{ID: uuid.New()}},
+	}
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(), 5*time.Second)
+	defer cancel()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM inventory").WithArgs(
+		ops[0].Item.ID, DefaultTenant).WillReturnResult(sqlMock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	results, err := storage.Batch(ctx, ops, true)
+	if !errors.Is(err, ErrBatchAborted) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if !errors.Is(results[0].Err, ErrNotFound) {
+		t.Errorf("unexpected error in result 0: %v", results[0].Err)
+	}
+
+	if !errors.Is(results[1].Err, ErrBatchAborted) {
+		t.Errorf("unexpected error in result 1: %v", results[1].Err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func Test_Batch_Best_Effort_Uses_Savepoints(t *testing.T) {
+	driver, mock, err := sqlMock.New()
+	if err != nil {
+		t.Fatalf(
+			"unexpected error while creating sqlmock: %s",
+			err)
+	}
+	defer driver.Close()
+
+	storage := SQLDatabase{
+		DB: driver,
+	}
+
+	ops := []BatchOperation{
+		{Op: BatchDelete, Item: Item{ID: uuid.New()}},
+		{Op: BatchDelete, Item: Item{ID: uuid.New()}},
+	}
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(), 5*time.Second)
+	defer cancel()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SAVEPOINT batch_op").WillReturnResult(
+		sqlMock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM inventory").WithArgs(
+		ops[0].Item.ID, DefaultTenant).WillReturnError(errors.New("delete failed"))
+	mock.ExpectExec("ROLLBACK TO SAVEPOINT batch_op").WillReturnResult(
+		sqlMock.NewResult(0, 0))
+	mock.ExpectExec("SAVEPOINT batch_op").WillReturnResult(
+		sqlMock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM inventory").WithArgs(
+		ops[1].Item.ID, DefaultTenant).WillReturnResult(sqlMock.NewResult(0, 1))
+	mock.ExpectExec("RELEASE SAVEPOINT batch_op").WillReturnResult(
+		sqlMock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	results, err := storage.Batch(ctx, ops, false)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if results[0].Err == nil {
+		t.Errorf("expected error in result 0")
+	}
+
+	if results[1].Err != nil {
+		t.Errorf("unexpected error in result 1: %v", results[1].Err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func Test_Batch_Keeps_Order(t *testing.T) {
+	driver, mock, err := sqlMock.New()
+	if err != nil {
+		t.Fatalf(
+			"unexpected error while creating sqlmock: %s",
+			err)
+	}
+	defer driver.Close()
+
+	storage := SQLDatabase{
+		DB: driver,
+	}
+
+	item := Item{
+		ID:        uuid.New(),
+		Name:      "a",
+		Unit:      "kg",
+		Amount:    1,
+		ExpiresAt: time.Now().Add(5 * time.Minute),
+	}
+
+	ops := []BatchOperation{
+		{Op: BatchDelete, Item: Item{ID: uuid.New()}},
+		{Op: BatchCreate, Item: item},
+		{Op: BatchCreate, Item: item},
+		{Op: BatchUpdate, Item: item},
+		{Op: BatchCreate, Item: item},
+	}
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(), 5*time.Second)
+	defer cancel()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM inventory").WithArgs(
+		ops[0].Item.ID, DefaultTenant).WillReturnResult(sqlMock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO inventory (.+) VALUES (.+), (.+)").
+		WillReturnResult(sqlMock.NewResult(0, 2))
+	mock.ExpectQuery("SELECT amount FROM inventory").WithArgs(
+		item.ID.String(), DefaultTenant).WillReturnRows(
+		sqlMock.NewRows([]string{"amount"}).AddRow(1.0))
+	mock.ExpectQuery("SELECT COALESCE").WithArgs(
+		item.ID.String()).WillReturnRows(
+		sqlMock.NewRows([]string{"sum"}).AddRow(0.0))
+	mock.ExpectExec("UPDATE inventory").WillReturnResult(
+		sqlMock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO inventory").WillReturnResult(
+		sqlMock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	results, err := storage.Batch(ctx, ops, true)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	for _, result := range results {
+		if result.Err != nil {
+			t.Errorf("unexpected error in result %d: %v",
+				result.Index, result.Err)
+		}
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func Test_Batch_Update_Below_Reserved(t *testing.T) {
+	driver, mock, err := sqlMock.New()
+	if err != nil {
+		t.Fatalf(
+			"unexpected error while creating sqlmock: %s",
+			err)
+	}
+	defer driver.Close()
+
+	storage := SQLDatabase{
+		DB: driver,
+	}
+
+	item := Item{
+		ID:        uuid.New(),
+		Name:      "a",
+		Unit:      "kg",
+		Amount:    1,
+		ExpiresAt: time.Now().Add(5 * time.Minute),
+	}
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(), 5*time.Second)
+	defer cancel()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT amount FROM inventory").WithArgs(
+		item.ID.String(), DefaultTenant).WillReturnRows(
+		sqlMock.NewRows([]string{"amount"}).AddRow(5.0))
+	mock.ExpectQuery("SELECT COALESCE").WithArgs(
+		item.ID.String()).WillReturnRows(
+		sqlMock.NewRows([]string{"sum"}).AddRow(3.0))
+	mock.ExpectRollback()
+
+	results, err := storage.Batch(ctx,
+		[]BatchOperation{{Op: BatchUpdate, Item: item}}, true)
+	if !errors.Is(err, ErrBatchAborted) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if !errors.Is(results[0].Err, ErrInsufficientStock) {
+		t.Errorf("unexpected error in result 0: %v", results[0].Err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func Test_insertQueryBuilder(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     int
+		wantQuery string
+	}{
+		{
+			name:  "single row",
+			input: 1,
+			wantQuery: "INSERT INTO inventory (id, name, unit, amount, expires_at, sku, tenant_id) " +
+				"VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7)",
+		},
+		{
+			name:  "two rows",
+			input: 2,
+			wantQuery: "INSERT INTO inventory (id, name, unit, amount, expires_at, sku, tenant_id) " +
+				"VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7), " +
+				"($8, $9, $10, $11, $12, NULLIF($13, ''), $14)",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gotQuery := insertQueryBuilder(test.input)
+
+			if test.wantQuery != gotQuery {
+				t.Errorf("unexpected query: %s", gotQuery)
+			}
+		})
+	}
+}
+
+func Test_Batch_Upsert_Creates_When_No_Match(t *testing.T) {
+	driver, mock, err := sqlMock.New()
+	if err != nil {
+		t.Fatalf(
+			"unexpected error while creating sqlmock: %s",
+			err)
+	}
+	defer driver.Close()
+
+	storage := SQLDatabase{
+		DB: driver,
+	}
+
+	item := Item{
+		Name:      "test",
+		Unit:      "kg",
+		Amount:    1.1,
+		ExpiresAt: time.Now().Add(5 * time.Minute),
+		SKU:       "SKU-1",
+	}
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(), 5*time.Second)
+	defer cancel()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE inventory SET (.+) WHERE sku = \\$6 AND tenant_id = \\$7").WithArgs(
+		item.Name, item.Unit, item.Amount, item.ExpiresAt,
+		item.SKU, item.SKU, DefaultTenant).WillReturnRows(
+		sqlMock.NewRows([]string{"id"}))
+	mock.ExpectExec("INSERT INTO inventory").WillReturnResult(
+		sqlMock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	results, err := storage.Batch(
+		ctx, []BatchOperation{{Op: BatchUpsertSKU, Item: item}}, true)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if results[0].Op != BatchCreate {
+		t.Errorf("unexpected op: %s", results[0].Op)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
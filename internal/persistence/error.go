@@ -5,4 +5,23 @@ import "errors"
 // Common errors.
 var (
 	ErrNotFound = errors.New("item(s) not found")
+	// ErrInsufficientStock is returned when a reservation asks
+	// for more than the available quantity of an item.
+	ErrInsufficientStock = errors.New("insufficient stock")
+	// ErrReservationInactive is returned when a reservation is
+	// committed or released after it has expired or was settled.
+	ErrReservationInactive = errors.New("reservation is not active")
+	// ErrInvalidBatchOp is returned for batch operations
+	// of an unknown kind.
+	ErrInvalidBatchOp = errors.New("invalid batch operation")
+	// ErrBatchAborted is returned when an atomic batch was
+	// rolled back because one of its operations failed.
+	ErrBatchAborted = errors.New("batch aborted")
+	// ErrUserExists is returned when a username is taken.
+	ErrUserExists = errors.New("user already exists")
+	// ErrTokenReused is returned when a refresh token that
+	// was already rotated or revoked is presented again.
+	ErrTokenReused = errors.New("refresh token reused")
+	// ErrTokenExpired is returned for expired refresh tokens.
+	ErrTokenExpired = errors.New("refresh token expired")
 )
@@ -1,7 +1,10 @@
 package persistence
 
 import (
+	"fmt"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 )
@@ -13,6 +16,7 @@ type Item struct {
 	Unit      string    `json:"unit"`
 	Amount    float64   `json:"amount"`
 	ExpiresAt time.Time `json:"expires_at"`
+	SKU       string    `json:"sku,omitempty"`
 }
 
 // ItemFilter represents a filter for items.
@@ -22,4 +26,65 @@ type ItemFilter struct {
 	Unit      string    `json:"unit"`
 	Amount    float64   `json:"amount"`
 	ExpiresAt time.Time `json:"expires_at"`
+	SKU       string    `json:"sku"`
+}
+
+// IsZero reports whether the filter has no field set.
+func (f ItemFilter) IsZero() bool {
+	return f.Name == "" && f.Unit == "" && f.Amount == 0 &&
+		f.ExpiresAt.IsZero() && f.SKU == ""
+}
+
+// MaxSKULength is the maximum length of a SKU in characters.
+const MaxSKULength = 64
+
+// Violation is a field of an item that breaks a rule.
+type Violation struct {
+	Field       string
+	Description string
+}
+
+// Violations is the error of ValidateItem.
+type Violations []Violation
+
+func (v Violations) Error() string {
+	messages := make([]string, len(v))
+	for idx, violation := range v {
+		messages[idx] = violation.Field + " " + violation.Description
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+// ValidateItem checks an item against the rules shared by all
+// APIs. Items may be out of stock, so an amount of 0 is valid.
+// The returned error is Violations, or nil if the item is valid.
+func ValidateItem(item Item) error {
+	var violations Violations
+	add := func(field, description string) {
+		violations = append(violations, Violation{field, description})
+	}
+
+	if item.Name == "" {
+		add("name", "is required")
+	}
+	if item.Unit == "" {
+		add("unit", "is required")
+	}
+	if item.Amount < 0 {
+		add("amount", "must not be negative")
+	}
+	if item.ExpiresAt.IsZero() {
+		add("expires_at", "is required")
+	}
+	if utf8.RuneCountInString(item.SKU) > MaxSKULength {
+		add("sku", fmt.Sprintf(
+			"must be at most %d characters long", MaxSKULength))
+	}
+
+	if len(violations) == 0 {
+		return nil
+	}
+
+	return violations
 }
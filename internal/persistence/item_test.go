@@ -0,0 +1,62 @@
+package persistence
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_ValidateItem(t *testing.T) {
+	valid := Item{
+		Name:      "milk",
+		Unit:      "l",
+		Amount:    1,
+		ExpiresAt: time.Now(),
+		SKU:       "MILK-1",
+	}
+
+	tests := []struct {
+		name   string
+		modify func(item *Item)
+		fields []string
+	}{
+		{"Valid", func(item *Item) {}, nil},
+		{"Out_Of_Stock", func(item *Item) { item.Amount = 0 }, nil},
+		{"Negative_Amount",
+			func(item *Item) { item.Amount = -1 },
+			[]string{"amount"}},
+		{"Longest_SKU",
+			func(item *Item) { item.SKU = strings.Repeat("é", MaxSKULength) },
+			nil},
+		{"Long_SKU",
+			func(item *Item) { item.SKU = strings.Repeat("a", MaxSKULength+1) },
+			[]string{"sku"}},
+		{"Empty",
+			func(item *Item) { *item = Item{} },
+			[]string{"name", "unit", "expires_at"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			item := valid
+			test.modify(&item)
+
+			err := ValidateItem(item)
+
+			var violations Violations
+			if err != nil && !errors.As(err, &violations) {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			var fields []string
+			for _, violation := range violations {
+				fields = append(fields, violation.Field)
+			}
+			if !reflect.DeepEqual(fields, test.fields) {
+				t.Errorf("unexpected fields: %v", fields)
+			}
+		})
+	}
+}
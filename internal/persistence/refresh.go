@@ -0,0 +1,193 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Salam4nder/inventory/pkg/logger"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// RefreshToken is the server-side record of a refresh token.
+// Every rotation adds a token to the family of the first
+// token issued at login.
+type RefreshToken struct {
+	ID        uuid.UUID
+	TenantID  string
+	FamilyID  uuid.UUID
+	UserID    uuid.UUID
+	TokenHash string
+	UsedAt    sql.NullTime
+	RevokedAt sql.NullTime
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+// RefreshTokenStorage is the persistence layer of refresh tokens.
+type RefreshTokenStorage interface {
+	CreateRefreshToken(ctx context.Context, token RefreshToken) (
+		RefreshToken, error)
+	RotateRefreshToken(
+		ctx context.Context, tokenHash string, next RefreshToken) (
+		RefreshToken, error)
+	RevokeRefreshTokenFamily(ctx context.Context, tokenHash string) error
+	RevokeUserRefreshTokens(ctx context.Context, userID string) error
+}
+
+// CreateRefreshToken stores a refresh token in the tenant of
+// ctx. A new family is started if the token has no family ID.
+func (s *SQLDatabase) CreateRefreshToken(
+	ctx context.Context, token RefreshToken) (RefreshToken, error) {
+	if token.FamilyID == uuid.Nil {
+		token.FamilyID = uuid.New()
+	}
+
+	token.TenantID = TenantFrom(ctx)
+
+	query := `INSERT INTO refresh_tokens (
+        family_id, user_id, token_hash, expires_at, tenant_id)
+        VALUES (
+        $1, $2, $3, $4, $5) RETURNING id, created_at`
+
+	if err := s.DB.QueryRowContext(
+		ctx,
+		query,
+		token.FamilyID,
+		token.UserID,
+		token.TokenHash,
+		token.ExpiresAt,
+		token.TenantID).
+		Scan(&token.ID, &token.CreatedAt); err != nil {
+		return RefreshToken{}, err
+	}
+
+	return token, nil
+}
+
+// RotateRefreshToken marks the token with the given hash as used
+// and stores next in its family, for the same user. If the token
+// was used or revoked before, the whole family is revoked and
+// ErrTokenReused is returned. Expired tokens return ErrTokenExpired.
+// Tokens are found by hash in any tenant, next is stored in the
+// tenant of the rotated token.
+func (s *SQLDatabase) RotateRefreshToken(
+	ctx context.Context,
+	tokenHash string,
+	next RefreshToken) (RefreshToken, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return RefreshToken{}, err
+	}
+	defer tx.Rollback()
+
+	query := `SELECT id, tenant_id, family_id, user_id, token_hash,
+        used_at, revoked_at, created_at, expires_at FROM refresh_tokens
+        WHERE token_hash = $1 FOR UPDATE`
+
+	var current RefreshToken
+
+	if err := tx.QueryRowContext(ctx, query, tokenHash).Scan(
+		&current.ID,
+		&current.TenantID,
+		&current.FamilyID,
+		&current.UserID,
+		&current.TokenHash,
+		&current.UsedAt,
+		&current.RevokedAt,
+		&current.CreatedAt,
+		&current.ExpiresAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return RefreshToken{}, ErrNotFound
+		}
+		return RefreshToken{}, err
+	}
+
+	if current.UsedAt.Valid || current.RevokedAt.Valid {
+		// A token that was already rotated is presented again,
+		// so it has leaked. Revoke every token of the family.
+		if _, err := tx.ExecContext(
+			ctx,
+			`UPDATE refresh_tokens SET revoked_at = now()
+        WHERE family_id = $1 AND revoked_at IS NULL`,
+			current.FamilyID); err != nil {
+			return RefreshToken{}, err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return RefreshToken{}, err
+		}
+
+		logger.FromContext(ctx).Warn("refresh token reused, family revoked",
+			zap.String("family_id", current.FamilyID.String()),
+			zap.String("user_id", current.UserID.String()))
+
+		return RefreshToken{}, ErrTokenReused
+	}
+
+	if !current.ExpiresAt.After(time.Now()) {
+		return RefreshToken{}, ErrTokenExpired
+	}
+
+	if _, err := tx.ExecContext(
+		ctx,
+		`UPDATE refresh_tokens SET used_at = now() WHERE id = $1`,
+		current.ID); err != nil {
+		return RefreshToken{}, err
+	}
+
+	next.TenantID = current.TenantID
+	next.FamilyID = current.FamilyID
+	next.UserID = current.UserID
+
+	insert := `INSERT INTO refresh_tokens (
+        family_id, user_id, token_hash, expires_at, tenant_id)
+        VALUES (
+        $1, $2, $3, $4, $5) RETURNING id, created_at`
+
+	if err := tx.QueryRowContext(
+		ctx,
+		insert,
+		next.FamilyID,
+		next.UserID,
+		next.TokenHash,
+		next.ExpiresAt,
+		next.TenantID).
+		Scan(&next.ID, &next.CreatedAt); err != nil {
+		return RefreshToken{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return RefreshToken{}, err
+	}
+
+	return next, nil
+}
+
+// RevokeRefreshTokenFamily revokes the token with the given
+// hash and every other token of its family.
+func (s *SQLDatabase) RevokeRefreshTokenFamily(
+	ctx context.Context, tokenHash string) error {
+	query := `UPDATE refresh_tokens SET revoked_at = now()
+        WHERE family_id = (
+            SELECT family_id FROM refresh_tokens WHERE token_hash = $1)
+        AND revoked_at IS NULL`
+
+	_, err := s.DB.ExecContext(ctx, query, tokenHash)
+
+	return err
+}
+
+// RevokeUserRefreshTokens revokes every refresh token of a user.
+func (s *SQLDatabase) RevokeUserRefreshTokens(
+	ctx context.Context, userID string) error {
+	query := `UPDATE refresh_tokens SET revoked_at = now()
+        WHERE user_id = $1 AND tenant_id = $2 AND revoked_at IS NULL`
+
+	_, err := s.DB.ExecContext(ctx, query, userID, TenantFrom(ctx))
+
+	return err
+}
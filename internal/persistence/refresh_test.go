@@ -0,0 +1,124 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	sqlMock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+)
+
+var refreshTokenColumns = []string{
+	"id", "tenant_id", "family_id", "user_id", "token_hash", "used_at",
+	"revoked_at", "created_at", "expires_at"}
+
+func Test_RotateRefreshToken_Success(t *testing.T) {
+	driver, mock, err := sqlMock.New()
+	if err != nil {
+		t.Fatalf(
+			"unexpected error while creating sqlmock: %s",
+			err)
+	}
+	defer driver.Close()
+
+	storage := SQLDatabase{
+		DB: driver,
+	}
+
+	current := RefreshToken{
+		ID:        uuid.New(),
+		FamilyID:  uuid.New(),
+		UserID:    uuid.New(),
+		TokenHash: "current",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	next := RefreshToken{
+		TokenHash: "next",
+		ExpiresAt: time.Now().Add(2 * time.Hour),
+	}
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(), 5*time.Second)
+	defer cancel()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT (.+) FROM refresh_tokens").WithArgs(
+		current.TokenHash).WillReturnRows(
+		sqlMock.NewRows(refreshTokenColumns).AddRow(
+			current.ID, DefaultTenant, current.FamilyID, current.UserID,
+			current.TokenHash, nil, nil, time.Now(), current.ExpiresAt))
+	mock.ExpectExec("UPDATE refresh_tokens SET used_at").WithArgs(
+		current.ID).WillReturnResult(sqlMock.NewResult(0, 1))
+	mock.ExpectQuery("INSERT INTO refresh_tokens").WithArgs(
+		current.FamilyID, current.UserID,
+		next.TokenHash, next.ExpiresAt, DefaultTenant).WillReturnRows(
+		sqlMock.NewRows([]string{"id", "created_at"}).AddRow(
+			uuid.New(), time.Now()))
+	mock.ExpectCommit()
+
+	rotated, err := storage.RotateRefreshToken(
+		ctx, current.TokenHash, next)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if rotated.FamilyID != current.FamilyID {
+		t.Errorf("expected rotated token to stay in the family")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func Test_RotateRefreshToken_Reuse_Revokes_Family(t *testing.T) {
+	driver, mock, err := sqlMock.New()
+	if err != nil {
+		t.Fatalf(
+			"unexpected error while creating sqlmock: %s",
+			err)
+	}
+	defer driver.Close()
+
+	storage := SQLDatabase{
+		DB: driver,
+	}
+
+	current := RefreshToken{
+		ID:        uuid.New(),
+		FamilyID:  uuid.New(),
+		UserID:    uuid.New(),
+		TokenHash: "current",
+		UsedAt:    sql.NullTime{Time: time.Now(), Valid: true},
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(), 5*time.Second)
+	defer cancel()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT (.+) FROM refresh_tokens").WithArgs(
+		current.TokenHash).WillReturnRows(
+		sqlMock.NewRows(refreshTokenColumns).AddRow(
+			current.ID, DefaultTenant, current.FamilyID, current.UserID,
+			current.TokenHash, current.UsedAt.Time, nil,
+			time.Now(), current.ExpiresAt))
+	mock.ExpectExec("UPDATE refresh_tokens SET revoked_at").WithArgs(
+		current.FamilyID).WillReturnResult(sqlMock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	_, err = storage.RotateRefreshToken(
+		ctx, current.TokenHash, RefreshToken{TokenHash: "next"})
+	if !errors.Is(err, ErrTokenReused) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,429 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// ReservationStatus is the lifecycle state of a reservation.
+type ReservationStatus string
+
+// Reservation statuses.
+const (
+	ReservationActive    ReservationStatus = "active"
+	ReservationCommitted ReservationStatus = "committed"
+	ReservationReleased  ReservationStatus = "released"
+)
+
+// Reservation holds a quantity of an item for an owner,
+// e.g. an order that is being picked, until it expires.
+type Reservation struct {
+	ID        uuid.UUID         `json:"id"`
+	ItemID    uuid.UUID         `json:"item_id"`
+	Quantity  float64           `json:"quantity"`
+	Owner     string            `json:"owner"`
+	Status    ReservationStatus `json:"status"`
+	CreatedAt time.Time         `json:"created_at"`
+	ExpiresAt time.Time         `json:"expires_at"`
+}
+
+// Stock is the available quantity of an item, that is
+// the amount minus all active reservations.
+type Stock struct {
+	ItemID    uuid.UUID `json:"item_id"`
+	Amount    float64   `json:"amount"`
+	Reserved  float64   `json:"reserved"`
+	Available float64   `json:"available"`
+}
+
+// reservedQuery sums the active reservations of an item.
+const reservedQuery = `SELECT COALESCE(SUM(quantity), 0)
+        FROM reservations
+        WHERE item_id = $1 AND status = 'active' AND expires_at > now()`
+
+// lockStock locks the row of an item, so changes of its amount
+// and reservations of it are serialized, and returns its amount
+// and the quantity held by active reservations. It returns
+// ErrNotFound if the tenant of ctx has no such item.
+func lockStock(
+	ctx context.Context,
+	tx *sql.Tx,
+	uuid string) (amount, reserved float64, err error) {
+	if err := tx.QueryRowContext(
+		ctx,
+		`SELECT amount FROM inventory
+        WHERE id = $1 AND tenant_id = $2 FOR UPDATE`,
+		uuid,
+		TenantFrom(ctx)).Scan(&amount); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, 0, ErrNotFound
+		}
+		return 0, 0, err
+	}
+
+	if err := tx.QueryRowContext(
+		ctx, reservedQuery, uuid).Scan(&reserved); err != nil {
+		return 0, 0, err
+	}
+
+	return amount, reserved, nil
+}
+
+// checkAmount locks an item and returns ErrInsufficientStock
+// if the given amount is below the quantity held by its
+// active reservations.
+func checkAmount(
+	ctx context.Context, tx *sql.Tx, uuid string, amount float64) error {
+	_, reserved, err := lockStock(ctx, tx, uuid)
+	if err != nil {
+		return err
+	}
+
+	if amount < reserved {
+		return ErrInsufficientStock
+	}
+
+	return nil
+}
+
+// Reserve holds the given quantity of an item for the owner
+// of the reservation until the ttl has passed. It returns
+// ErrInsufficientStock if the available quantity of the item
+// is lower than requested. The expiry is computed with the
+// clock of the database, like the sweep that releases it.
+func (s *SQLDatabase) Reserve(
+	ctx context.Context,
+	reservation Reservation,
+	ttl time.Duration) (Reservation, error) {
+	tx, err := s.beginTx(ctx)
+	if err != nil {
+		return Reservation{}, err
+	}
+	defer tx.Rollback()
+
+	amount, reserved, err := lockStock(
+		ctx, tx, reservation.ItemID.String())
+	if err != nil {
+		return Reservation{}, err
+	}
+
+	if amount-reserved < reservation.Quantity {
+		return Reservation{}, ErrInsufficientStock
+	}
+
+	query := `INSERT INTO reservations (
+        item_id, quantity, owner, expires_at, tenant_id)
+        VALUES (
+        $1, $2, $3, now() + make_interval(secs => $4), $5)
+        RETURNING id, status, created_at, expires_at`
+
+	if err := tx.QueryRowContext(
+		ctx,
+		query,
+		reservation.ItemID,
+		reservation.Quantity,
+		reservation.Owner,
+		ttl.Seconds(),
+		TenantFrom(ctx)).
+		Scan(
+			&reservation.ID,
+			&reservation.Status,
+			&reservation.CreatedAt,
+			&reservation.ExpiresAt); err != nil {
+		return Reservation{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Reservation{}, err
+	}
+
+	return reservation, nil
+}
+
+// ReadReservation reads a reservation based off of an uuid.
+func (s *SQLDatabase) ReadReservation(
+	ctx context.Context, uuid string) (Reservation, error) {
+	q, done, err := s.scoped(ctx)
+	if err != nil {
+		return Reservation{}, err
+	}
+
+	query := `SELECT id, item_id, quantity, owner, status,
+        created_at, expires_at FROM reservations
+        WHERE id = $1 AND tenant_id = $2`
+
+	reservation, err := scanReservation(
+		q.QueryRowContext(ctx, query, uuid, TenantFrom(ctx)))
+	if err := done(err); err != nil {
+		return Reservation{}, err
+	}
+
+	return reservation, nil
+}
+
+// Available returns the stock of an item with the
+// quantity held by active reservations subtracted.
+func (s *SQLDatabase) Available(
+	ctx context.Context, uuid string) (Stock, error) {
+	var stock Stock
+
+	q, done, err := s.scoped(ctx)
+	if err != nil {
+		return Stock{}, err
+	}
+	defer done(nil)
+
+	if err := q.QueryRowContext(
+		ctx,
+		`SELECT id, amount FROM inventory WHERE id = $1 AND tenant_id = $2`,
+		uuid,
+		TenantFrom(ctx)).Scan(&stock.ItemID, &stock.Amount); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Stock{}, ErrNotFound
+		}
+		return Stock{}, err
+	}
+
+	if err := q.QueryRowContext(
+		ctx, reservedQuery, uuid).Scan(&stock.Reserved); err != nil {
+		return Stock{}, err
+	}
+
+	stock.Available = stock.Amount - stock.Reserved
+
+	return stock, nil
+}
+
+// Stocks returns the stock of the items with the given uuids,
+// like Available. Unknown uuids are left out and the order
+// is not kept.
+func (s *SQLDatabase) Stocks(
+	ctx context.Context, uuids []string) ([]Stock, error) {
+	q, done, err := s.scoped(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done(nil)
+
+	query := `SELECT i.id, i.amount, COALESCE(SUM(r.quantity), 0)
+        FROM inventory i
+        LEFT JOIN reservations r ON r.item_id = i.id
+            AND r.status = 'active' AND r.expires_at > now()
+        WHERE i.tenant_id = $1 AND i.id = ANY($2)
+        GROUP BY i.id, i.amount`
+
+	rows, err := q.QueryContext(ctx, query, TenantFrom(ctx), pq.Array(uuids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stocks := []Stock{}
+
+	for rows.Next() {
+		var stock Stock
+
+		if err := rows.Scan(
+			&stock.ItemID, &stock.Amount, &stock.Reserved); err != nil {
+			return nil, err
+		}
+
+		stock.Available = stock.Amount - stock.Reserved
+		stocks = append(stocks, stock)
+	}
+
+	return stocks, rows.Err()
+}
+
+// ActiveReservations returns the active reservations of the
+// items with the given uuids, oldest first.
+func (s *SQLDatabase) ActiveReservations(
+	ctx context.Context, itemUUIDs []string) ([]Reservation, error) {
+	q, done, err := s.scoped(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done(nil)
+
+	query := `SELECT id, item_id, quantity, owner, status,
+        created_at, expires_at FROM reservations
+        WHERE tenant_id = $1 AND item_id = ANY($2)
+        AND status = 'active' AND expires_at > now()
+        ORDER BY created_at`
+
+	rows, err := q.QueryContext(
+		ctx, query, TenantFrom(ctx), pq.Array(itemUUIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reservations := []Reservation{}
+
+	for rows.Next() {
+		reservation, err := scanReservation(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		reservations = append(reservations, reservation)
+	}
+
+	return reservations, rows.Err()
+}
+
+// AdjustStock adds delta to the amount of an item, a negative
+// delta takes stock away. The amount may not drop below the
+// quantity held by active reservations, ErrInsufficientStock
+// is returned instead.
+func (s *SQLDatabase) AdjustStock(
+	ctx context.Context, uuid string, delta float64) (Item, error) {
+	tx, err := s.beginTx(ctx)
+	if err != nil {
+		return Item{}, err
+	}
+	defer tx.Rollback()
+
+	amount, reserved, err := lockStock(ctx, tx, uuid)
+	if err != nil {
+		return Item{}, err
+	}
+
+	if amount+delta < reserved {
+		return Item{}, ErrInsufficientStock
+	}
+
+	item, err := scanItem(tx.QueryRowContext(
+		ctx,
+		`UPDATE inventory SET amount = amount + $1
+        WHERE id = $2 AND tenant_id = $3 RETURNING `+itemColumns,
+		delta,
+		uuid,
+		TenantFrom(ctx)))
+	if err != nil {
+		return Item{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Item{}, err
+	}
+
+	return item, nil
+}
+
+// CommitReservation turns an active reservation into
+// consumption by subtracting its quantity from the item.
+func (s *SQLDatabase) CommitReservation(
+	ctx context.Context, uuid string) (Reservation, error) {
+	return s.settleReservation(ctx, uuid, ReservationCommitted)
+}
+
+// ReleaseReservation releases an active reservation so
+// its quantity becomes available again.
+func (s *SQLDatabase) ReleaseReservation(
+	ctx context.Context, uuid string) (Reservation, error) {
+	return s.settleReservation(ctx, uuid, ReservationReleased)
+}
+
+// ReleaseExpiredReservations releases all active reservations
+// that have expired, of every tenant. It returns the number of
+// released rows.
+func (s *SQLDatabase) ReleaseExpiredReservations(
+	ctx context.Context) (int64, error) {
+	q, done, err := s.allTenants(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	query := `UPDATE reservations SET status = 'released'
+        WHERE status = 'active' AND expires_at <= now()`
+
+	res, err := q.ExecContext(ctx, query)
+	if err := done(err); err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+func (s *SQLDatabase) settleReservation(
+	ctx context.Context,
+	uuid string,
+	status ReservationStatus) (Reservation, error) {
+	tx, err := s.beginTx(ctx)
+	if err != nil {
+		return Reservation{}, err
+	}
+	defer tx.Rollback()
+
+	// The expiry is checked with the clock of the database,
+	// which writes and sweeps the reservations.
+	query := `SELECT id, item_id, quantity, owner, status,
+        created_at, expires_at, expires_at > now() FROM reservations
+        WHERE id = $1 AND tenant_id = $2 FOR UPDATE`
+
+	var live bool
+	reservation, err := scanReservation(
+		tx.QueryRowContext(ctx, query, uuid, TenantFrom(ctx)), &live)
+	if err != nil {
+		return Reservation{}, err
+	}
+
+	if reservation.Status != ReservationActive || !live {
+		return Reservation{}, ErrReservationInactive
+	}
+
+	if status == ReservationCommitted {
+		if _, err := tx.ExecContext(
+			ctx,
+			`UPDATE inventory SET amount = amount - $1 WHERE id = $2`,
+			reservation.Quantity,
+			reservation.ItemID); err != nil {
+			return Reservation{}, err
+		}
+	}
+
+	if _, err := tx.ExecContext(
+		ctx,
+		`UPDATE reservations SET status = $1 WHERE id = $2`,
+		status,
+		reservation.ID); err != nil {
+		return Reservation{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Reservation{}, err
+	}
+
+	reservation.Status = status
+
+	return reservation, nil
+}
+
+// scanReservation scans the columns of a reservation,
+// followed by the extra columns of the query, if any.
+func scanReservation(
+	row scanner, extra ...interface{}) (Reservation, error) {
+	var reservation Reservation
+
+	if err := row.Scan(append([]interface{}{
+		&reservation.ID,
+		&reservation.ItemID,
+		&reservation.Quantity,
+		&reservation.Owner,
+		&reservation.Status,
+		&reservation.CreatedAt,
+		&reservation.ExpiresAt}, extra...)...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Reservation{}, ErrNotFound
+		}
+		return Reservation{}, err
+	}
+
+	return reservation, nil
+}
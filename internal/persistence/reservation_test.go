@@ -0,0 +1,368 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	sqlMock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+)
+
+func Test_Reserve_Success(t *testing.T) {
+	driver, mock, err := sqlMock.New()
+	if err != nil {
+		t.Fatalf(
+			"unexpected error while creating sqlmock: %s",
+			err)
+	}
+	defer driver.Close()
+
+	storage := SQLDatabase{
+		DB: driver,
+	}
+
+	reservation := Reservation{
+		ItemID:   uuid.New(),
+		Quantity: 2,
+		Owner:    "order-1",
+	}
+	expiresAt := time.Now().Add(5 * time.Minute)
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(), 5*time.Second)
+	defer cancel()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT amount FROM inventory").WithArgs(
+		reservation.ItemID, DefaultTenant).WillReturnRows(
+		sqlMock.NewRows([]string{"amount"}).AddRow(5.0))
+	mock.ExpectQuery("SELECT COALESCE").WithArgs(
+		reservation.ItemID).WillReturnRows(
+		sqlMock.NewRows([]string{"sum"}).AddRow(3.0))
+	mock.ExpectQuery("INSERT INTO reservations").WithArgs(
+		reservation.ItemID, reservation.Quantity,
+		reservation.Owner, 300.0, DefaultTenant).WillReturnRows(
+		sqlMock.NewRows([]string{
+			"id", "status", "created_at", "expires_at"}).AddRow(
+			uuid.New(), ReservationActive, time.Now(), expiresAt))
+	mock.ExpectCommit()
+
+	got, err := storage.Reserve(ctx, reservation, 5*time.Minute)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if got.Status != ReservationActive {
+		t.Errorf("unexpected status: %s", got.Status)
+	}
+
+	if !got.ExpiresAt.Equal(expiresAt) {
+		t.Errorf("unexpected expiry: %s", got.ExpiresAt)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func Test_Reserve_Insufficient_Stock_Returns_Error(t *testing.T) {
+	driver, mock, err := sqlMock.New()
+	if err != nil {
+		t.Fatalf(
+			"unexpected error while creating sqlmock: %s",
+			err)
+	}
+	defer driver.Close()
+
+	storage := SQLDatabase{
+		DB: driver,
+	}
+
+	reservation := Reservation{
+		ItemID:   uuid.New(),
+		Quantity: 3,
+		Owner:    "order-1",
+	}
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(), 5*time.Second)
+	defer cancel()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT amount FROM inventory").WithArgs(
+		reservation.ItemID, DefaultTenant).WillReturnRows(
+		sqlMock.NewRows([]string{"amount"}).AddRow(5.0))
+	mock.ExpectQuery("SELECT COALESCE").WithArgs(
+		reservation.ItemID).WillReturnRows(
+		sqlMock.NewRows([]string{"sum"}).AddRow(3.0))
+	mock.ExpectRollback()
+
+	_, err = storage.Reserve(ctx, reservation, 5*time.Minute)
+	if !errors.Is(err, ErrInsufficientStock) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func Test_CommitReservation_Expired_Returns_Error(t *testing.T) {
+	driver, mock, err := sqlMock.New()
+	if err != nil {
+		t.Fatalf(
+			"unexpected error while creating sqlmock: %s",
+			err)
+	}
+	defer driver.Close()
+
+	storage := SQLDatabase{
+		DB: driver,
+	}
+
+	id := uuid.New()
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(), 5*time.Second)
+	defer cancel()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT (.+) FROM reservations").WithArgs(
+		id.String(), DefaultTenant).WillReturnRows(
+		sqlMock.NewRows([]string{
+			"id", "item_id", "quantity", "owner",
+			"status", "created_at", "expires_at", "live"}).AddRow(
+			id, uuid.New(), 1.0, "order-1", ReservationActive,
+			time.Now().Add(-10*time.Minute),
+			// The clock of the database decides, even if the
+			// clock of the app is behind.
+			time.Now().Add(5*time.Minute), false))
+	mock.ExpectRollback()
+
+	_, err = storage.CommitReservation(ctx, id.String())
+	if !errors.Is(err, ErrReservationInactive) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func Test_CommitReservation_Success(t *testing.T) {
+	driver, mock, err := sqlMock.New()
+	if err != nil {
+		t.Fatalf(
+			"unexpected error while creating sqlmock: %s",
+			err)
+	}
+	defer driver.Close()
+
+	storage := SQLDatabase{
+		DB: driver,
+	}
+
+	id := uuid.New()
+	itemID := uuid.New()
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(), 5*time.Second)
+	defer cancel()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT (.+) FROM reservations").WithArgs(
+		id.String(), DefaultTenant).WillReturnRows(
+		sqlMock.NewRows([]string{
+			"id", "item_id", "quantity", "owner",
+			"status", "created_at", "expires_at", "live"}).AddRow(
+			id, itemID, 2.0, "order-1", ReservationActive,
+			time.Now(), time.Now().Add(5*time.Minute), true))
+	mock.ExpectExec("UPDATE inventory SET amount").WithArgs(
+		2.0, itemID).WillReturnResult(sqlMock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE reservations SET status").WithArgs(
+		ReservationCommitted, id).WillReturnResult(
+		sqlMock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	got, err := storage.CommitReservation(ctx, id.String())
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if got.Status != ReservationCommitted {
+		t.Errorf("unexpected status: %s", got.Status)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func Test_AdjustStock_Success(t *testing.T) {
+	driver, mock, err := sqlMock.New()
+	if err != nil {
+		t.Fatalf(
+			"unexpected error while creating sqlmock: %s",
+			err)
+	}
+	defer driver.Close()
+
+	storage := SQLDatabase{
+		DB: driver,
+	}
+
+	id := uuid.New()
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(), 5*time.Second)
+	defer cancel()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT amount FROM inventory").WithArgs(
+		id.String(), DefaultTenant).WillReturnRows(
+		sqlMock.NewRows([]string{"amount"}).AddRow(5.0))
+	mock.ExpectQuery("SELECT COALESCE").WithArgs(
+		id.String()).WillReturnRows(
+		sqlMock.NewRows([]string{"sum"}).AddRow(2.0))
+	mock.ExpectQuery("UPDATE inventory SET amount").WithArgs(
+		-3.0, id.String(), DefaultTenant).WillReturnRows(
+		sqlMock.NewRows([]string{
+			"id", "name", "unit", "amount", "expires_at", "sku"}).AddRow(
+			id, "milk", "l", 2.0, time.Now(), ""))
+	mock.ExpectCommit()
+
+	got, err := storage.AdjustStock(ctx, id.String(), -3)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if got.Amount != 2 {
+		t.Errorf("unexpected amount: %v", got.Amount)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func Test_AdjustStock_Below_Reserved_Returns_Error(t *testing.T) {
+	driver, mock, err := sqlMock.New()
+	if err != nil {
+		t.Fatalf(
+			"unexpected error while creating sqlmock: %s",
+			err)
+	}
+	defer driver.Close()
+
+	storage := SQLDatabase{
+		DB: driver,
+	}
+
+	id := uuid.New()
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(), 5*time.Second)
+	defer cancel()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT amount FROM inventory").WithArgs(
+		id.String(), DefaultTenant).WillReturnRows(
+		sqlMock.NewRows([]string{"amount"}).AddRow(5.0))
+	mock.ExpectQuery("SELECT COALESCE").WithArgs(
+		id.String()).WillReturnRows(
+		sqlMock.NewRows([]string{"sum"}).AddRow(3.0))
+	mock.ExpectRollback()
+
+	_, err = storage.AdjustStock(ctx, id.String(), -3)
+	if !errors.Is(err, ErrInsufficientStock) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func Test_Stocks_Success(t *testing.T) {
+	driver, mock, err := sqlMock.New()
+	if err != nil {
+		t.Fatalf(
+			"unexpected error while creating sqlmock: %s",
+			err)
+	}
+	defer driver.Close()
+
+	storage := SQLDatabase{
+		DB: driver,
+	}
+
+	id := uuid.New()
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(), 5*time.Second)
+	defer cancel()
+
+	mock.ExpectQuery("LEFT JOIN reservations").WithArgs(
+		DefaultTenant, sqlMock.AnyArg()).WillReturnRows(
+		sqlMock.NewRows([]string{"id", "amount", "reserved"}).
+			AddRow(id, 5.0, 2.0))
+
+	stocks, err := storage.Stocks(ctx, []string{id.String()})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if len(stocks) != 1 || stocks[0].Available != 3 {
+		t.Errorf("unexpected stocks: %+v", stocks)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func Test_ActiveReservations_Success(t *testing.T) {
+	driver, mock, err := sqlMock.New()
+	if err != nil {
+		t.Fatalf(
+			"unexpected error while creating sqlmock: %s",
+			err)
+	}
+	defer driver.Close()
+
+	storage := SQLDatabase{
+		DB: driver,
+	}
+
+	itemID := uuid.New()
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(), 5*time.Second)
+	defer cancel()
+
+	mock.ExpectQuery("FROM reservations").WithArgs(
+		DefaultTenant, sqlMock.AnyArg()).WillReturnRows(
+		sqlMock.NewRows([]string{
+			"id", "item_id", "quantity", "owner", "status",
+			"created_at", "expires_at"}).
+			AddRow(uuid.New(), itemID, 1.0, "order-1",
+				ReservationActive, time.Now(), time.Now().Add(time.Hour)).
+			AddRow(uuid.New(), itemID, 2.0, "order-2",
+				ReservationActive, time.Now(), time.Now().Add(time.Hour)))
+
+	reservations, err := storage.ActiveReservations(
+		ctx, []string{itemID.String()})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if len(reservations) != 2 || reservations[1].Owner != "order-2" {
+		t.Errorf("unexpected reservations: %+v", reservations)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,195 @@
+package persistence
+
+import (
+	"context"
+	"html"
+	"strings"
+	"unicode"
+)
+
+// Markers around the matched words of a search highlight.
+const (
+	HighlightStart = "<mark>"
+	HighlightStop  = "</mark>"
+)
+
+// ts_headline marks the matches with control characters,
+// which survive HTML escaping, and they are replaced with
+// the markers once the name is escaped.
+const (
+	headlineStart = "\x01"
+	headlineStop  = "\x02"
+)
+
+// headlineMarkers replaces the control characters of
+// ts_headline with the markers.
+var headlineMarkers = strings.NewReplacer(
+	headlineStart, HighlightStart, headlineStop, HighlightStop)
+
+// SearchResult is an item matching a search query with
+// its relevance and the name with the matches highlighted.
+// The highlight is HTML, the name in it is escaped.
+type SearchResult struct {
+	Item
+	Rank      float64 `json:"rank"`
+	Highlight string  `json:"highlight"`
+}
+
+// Search finds items by name using full-text search, with
+// trigram similarity as a fallback for typos. The results
+// are ordered by relevance, most relevant first.
+func (s *SQLDatabase) Search(
+	ctx context.Context, query string, limit int) (
+	[]SearchResult, error) {
+	sqlQuery := `SELECT ` + itemColumns + `,
+        ts_rank(to_tsvector('english', name),
+            plainto_tsquery('english', $1)) +
+            word_similarity($1, name) AS rank,
+        ts_headline('english', name, plainto_tsquery('english', $1),
+            'StartSel=' || chr(1) || ', StopSel=' || chr(2) ||
+            ', HighlightAll=true')
+        FROM inventory
+        WHERE tenant_id = $3
+            AND (to_tsvector('english', name) @@ plainto_tsquery('english', $1)
+            OR $1 <% name)
+        ORDER BY rank DESC, name
+        LIMIT $2`
+
+	q, done, err := s.scoped(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done(nil)
+
+	rows, err := q.QueryContext(
+		ctx, sqlQuery, query, limit, TenantFrom(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []SearchResult{}
+
+	for rows.Next() {
+		var result SearchResult
+
+		if err := rows.Scan(
+			&result.ID,
+			&result.Name,
+			&result.Unit,
+			&result.Amount,
+			&result.ExpiresAt,
+			&result.SKU,
+			&result.Rank,
+			&result.Highlight); err != nil {
+			return nil, err
+		}
+
+		// Rows that only matched by similarity have no
+		// full-text match for ts_headline to mark.
+		if strings.Contains(result.Highlight, headlineStart) {
+			result.Highlight = headlineMarkers.Replace(
+				html.EscapeString(result.Highlight))
+		} else {
+			result.Highlight = highlightFuzzy(result.Name, query)
+		}
+
+		results = append(results, result)
+	}
+
+	return results, rows.Err()
+}
+
+// highlightFuzzy marks the words of name that are within a
+// small edit distance of one of the words of the query. The
+// name is HTML escaped.
+func highlightFuzzy(name, query string) string {
+	terms := strings.FieldsFunc(strings.ToLower(query), isSeparator)
+
+	var (
+		highlight strings.Builder
+		word      strings.Builder
+	)
+
+	flush := func() {
+		if word.Len() == 0 {
+			return
+		}
+
+		lower := strings.ToLower(word.String())
+		for _, term := range terms {
+			if levenshtein(lower, term) <= maxTypos(term) {
+				highlight.WriteString(HighlightStart +
+					html.EscapeString(word.String()) + HighlightStop)
+				word.Reset()
+				return
+			}
+		}
+
+		highlight.WriteString(html.EscapeString(word.String()))
+		word.Reset()
+	}
+
+	for _, r := range name {
+		if isSeparator(r) {
+			flush()
+			highlight.WriteString(html.EscapeString(string(r)))
+			continue
+		}
+		word.WriteRune(r)
+	}
+	flush()
+
+	return highlight.String()
+}
+
+func isSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+}
+
+// maxTypos is the edit distance tolerated for a query term.
+func maxTypos(term string) int {
+	switch n := len([]rune(term)); {
+	case n < 4:
+		return 0
+	case n < 8:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// levenshtein returns the edit distance between a and b.
+func levenshtein(a, b string) int {
+	source, target := []rune(a), []rune(b)
+
+	previous := make([]int, len(target)+1)
+	current := make([]int, len(target)+1)
+
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(source); i++ {
+		current[0] = i
+
+		for j := 1; j <= len(target); j++ {
+			cost := 1
+			if source[i-1] == target[j-1] {
+				cost = 0
+			}
+
+			current[j] = previous[j] + 1
+			if current[j-1]+1 < current[j] {
+				current[j] = current[j-1] + 1
+			}
+			if previous[j-1]+cost < current[j] {
+				current[j] = previous[j-1] + cost
+			}
+		}
+
+		previous, current = current, previous
+	}
+
+	return previous[len(target)]
+}
@@ -0,0 +1,115 @@
+package persistence
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	sqlMock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+)
+
+func Test_Search_Highlights_Fuzzy_Matches(t *testing.T) {
+	driver, mock, err := sqlMock.New()
+	if err != nil {
+		t.Fatalf(
+			"unexpected error while creating sqlmock: %s",
+			err)
+	}
+	defer driver.Close()
+
+	storage := SQLDatabase{
+		DB: driver,
+	}
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(), 5*time.Second)
+	defer cancel()
+
+	mock.ExpectQuery("SELECT (.+) FROM inventory").WithArgs(
+		"tomatos", 20, DefaultTenant).WillReturnRows(
+		sqlMock.NewRows([]string{
+			"id", "name", "unit", "amount", "expires_at", "sku",
+			"rank", "ts_headline"}).AddRow(
+			uuid.New(), "Cherry tomatoes", "kg", 1.0, time.Now(), "",
+			0.6, "Cherry tomatoes"))
+
+	results, err := storage.Search(ctx, "tomatos", 20)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	assert.Len(t, results, 1)
+	assert.Equal(t, "Cherry <mark>tomatoes</mark>", results[0].Highlight)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func Test_levenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"tomato", "tomato", 0},
+		{"tomatos", "tomatoes", 1},
+		{"kitten", "sitting", 3},
+		{"", "abc", 3},
+	}
+
+	for _, test := range tests {
+		if got := levenshtein(test.a, test.b); got != test.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d",
+				test.a, test.b, got, test.want)
+		}
+	}
+}
+
+func Test_Search_Escapes_Highlights(t *testing.T) {
+	driver, mock, err := sqlMock.New()
+	if err != nil {
+		t.Fatalf(
+			"unexpected error while creating sqlmock: %s",
+			err)
+	}
+	defer driver.Close()
+
+	storage := SQLDatabase{
+		DB: driver,
+	}
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(), 5*time.Second)
+	defer cancel()
+
+	mock.ExpectQuery("SELECT (.+) FROM inventory").WithArgs(
+		"tomato", 20, DefaultTenant).WillReturnRows(
+		sqlMock.NewRows([]string{
+			"id", "name", "unit", "amount", "expires_at", "sku",
+			"rank", "ts_headline"}).
+			AddRow(
+				uuid.New(), "<b>tomato</b>", "kg", 1.0, time.Now(), "",
+				0.9, "<b>\x01tomato\x02</b>").
+			AddRow(
+				uuid.New(), "<i>tomatos</i>", "kg", 1.0, time.Now(), "",
+				0.5, "<i>tomatos</i>"))
+
+	results, err := storage.Search(ctx, "tomato", 20)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	assert.Len(t, results, 2)
+	assert.Equal(t, "&lt;b&gt;<mark>tomato</mark>&lt;/b&gt;",
+		results[0].Highlight)
+	assert.Equal(t, "&lt;i&gt;<mark>tomatos</mark>&lt;/i&gt;",
+		results[1].Highlight)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -7,21 +7,46 @@ import (
 	"strconv"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+// itemColumns are the columns of the inventory table
+// in the order scanItem expects them.
+const itemColumns = "id, name, unit, amount, expires_at, COALESCE(sku, '')"
+
+// scanner is implemented by *sql.Row and *sql.Rows.
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanItem scans a row selected with itemColumns into an Item.
+func scanItem(row scanner) (Item, error) {
+	var item Item
+
+	err := row.Scan(
+		&item.ID,
+		&item.Name,
+		&item.Unit,
+		&item.Amount,
+		&item.ExpiresAt,
+		&item.SKU)
+
+	return item, err
+}
+
 // Create creates a new item in the database.
 func (s *SQLDatabase) Create(
 	ctx context.Context, item Item) (uuid.UUID, error) {
-	tx, err := s.DB.BeginTx(ctx, nil)
+	tx, err := s.beginTx(ctx)
 	if err != nil {
 		return uuid.Nil, err
 	}
 	defer tx.Rollback()
 
 	query := `INSERT INTO inventory (
-        name, unit, amount, expires_at)
+        name, unit, amount, expires_at, sku, tenant_id)
         VALUES (
-        $1, $2, $3, $4) RETURNING id`
+        $1, $2, $3, $4, NULLIF($5, ''), $6) RETURNING id`
 
 	if err := tx.QueryRowContext(
 		ctx,
@@ -29,7 +54,9 @@ func (s *SQLDatabase) Create(
 		item.Name,
 		item.Unit,
 		item.Amount,
-		item.ExpiresAt).
+		item.ExpiresAt,
+		item.SKU,
+		TenantFrom(ctx)).
 		Scan(&item.ID); err != nil {
 		return uuid.Nil, err
 	}
@@ -44,19 +71,17 @@ func (s *SQLDatabase) Create(
 // Read reads an item from the database based off of an uuid.
 func (s *SQLDatabase) Read(
 	ctx context.Context, uuid string) (Item, error) {
-	var item Item
+	q, done, err := s.scoped(ctx)
+	if err != nil {
+		return Item{}, err
+	}
 
-	query := `SELECT * FROM inventory WHERE id = $1`
+	query := `SELECT ` + itemColumns + ` FROM inventory
+        WHERE id = $1 AND tenant_id = $2`
 
-	if err := s.DB.QueryRowContext(
-		ctx,
-		query,
-		uuid).Scan(
-		&item.ID,
-		&item.Name,
-		&item.Unit,
-		&item.Amount,
-		&item.ExpiresAt); err != nil {
+	item, err := scanItem(q.QueryRowContext(
+		ctx, query, uuid, TenantFrom(ctx)))
+	if err := done(err); err != nil {
 		if err == sql.ErrNoRows {
 			return Item{}, ErrNotFound
 		}
@@ -69,9 +94,15 @@ func (s *SQLDatabase) Read(
 // ReadAll reads all items from the database.
 func (s *SQLDatabase) ReadAll(
 	ctx context.Context) ([]Item, error) {
-	query := `SELECT * FROM inventory`
+	q, done, err := s.scoped(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done(nil)
 
-	rows, err := s.DB.QueryContext(ctx, query)
+	query := `SELECT ` + itemColumns + ` FROM inventory WHERE tenant_id = $1`
+
+	rows, err := q.QueryContext(ctx, query, TenantFrom(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -80,14 +111,8 @@ func (s *SQLDatabase) ReadAll(
 	var items []Item
 
 	for rows.Next() {
-		var item Item
-
-		if err := rows.Scan(
-			&item.ID,
-			&item.Name,
-			&item.Unit,
-			&item.Amount,
-			&item.ExpiresAt); err != nil {
+		item, err := scanItem(rows)
+		if err != nil {
 			if err == sql.ErrNoRows {
 				return []Item{}, ErrNotFound
 			}
@@ -112,12 +137,18 @@ func (s *SQLDatabase) ReadBy(
 	[]Item, error) {
 	var items []Item
 
-	query, args := filterQueryBuilder(filter)
-	if len(args) == 0 {
+	query, args := filterQueryBuilder(ctx, filter)
+	if query == "" {
 		return items, errors.New("empty filter")
 	}
 
-	rows, err := s.DB.QueryContext(
+	q, done, err := s.scoped(ctx)
+	if err != nil {
+		return items, err
+	}
+	defer done(nil)
+
+	rows, err := q.QueryContext(
 		ctx, query, args...)
 	if err != nil {
 		return items, err
@@ -125,13 +156,8 @@ func (s *SQLDatabase) ReadBy(
 	defer rows.Close()
 
 	for rows.Next() {
-		var item Item
-		if err := rows.Scan(
-			&item.ID,
-			&item.Name,
-			&item.Unit,
-			&item.Amount,
-			&item.ExpiresAt); err != nil {
+		item, err := scanItem(rows)
+		if err != nil {
 			if err == sql.ErrNoRows {
 				return []Item{}, ErrNotFound
 			}
@@ -146,34 +172,155 @@ func (s *SQLDatabase) ReadBy(
 	return items, err
 }
 
-// Update updates an item in the database.
+// Iterate calls fn for every item matching the filter while
+// the rows are read from the database, so the result set is
+// never held in memory. An empty filter matches all items.
+// Iteration stops at the first error returned by fn.
+func (s *SQLDatabase) Iterate(
+	ctx context.Context,
+	filter ItemFilter,
+	fn func(Item) error) error {
+	query, args := filterQueryBuilder(ctx, filter)
+	if query == "" {
+		query = `SELECT ` + itemColumns + ` FROM inventory WHERE tenant_id = $1`
+	}
+
+	q, done, err := s.scoped(ctx)
+	if err != nil {
+		return err
+	}
+	defer done(nil)
+
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		item, err := scanItem(rows)
+		if err != nil {
+			return err
+		}
+
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// ReadMany reads the items with the given uuids. Unknown
+// uuids are left out and the order is not kept.
+func (s *SQLDatabase) ReadMany(
+	ctx context.Context, uuids []string) ([]Item, error) {
+	query := `SELECT ` + itemColumns + ` FROM inventory
+        WHERE tenant_id = $1 AND id = ANY($2)`
+
+	return s.readItems(ctx, query, TenantFrom(ctx), pq.Array(uuids))
+}
+
+// ReadPage reads up to limit items matching the filter in
+// the order of their IDs, starting after the item with the
+// uuid after. An empty after starts at the first item. An
+// empty filter matches all items.
+func (s *SQLDatabase) ReadPage(
+	ctx context.Context,
+	filter ItemFilter,
+	after string,
+	limit int) ([]Item, error) {
+	query, args := filterQueryBuilder(ctx, filter)
+	if query == "" {
+		query = `SELECT ` + itemColumns + ` FROM inventory WHERE tenant_id = $1`
+	}
+
+	if after != "" {
+		args = append(args, after)
+		query += " AND id > $" + strconv.Itoa(len(args))
+	}
+
+	args = append(args, limit)
+	query += " ORDER BY id LIMIT $" + strconv.Itoa(len(args))
+
+	return s.readItems(ctx, query, args...)
+}
+
+// readItems reads the items selected by the query.
+func (s *SQLDatabase) readItems(
+	ctx context.Context,
+	query string,
+	args ...interface{}) ([]Item, error) {
+	q, done, err := s.scoped(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done(nil)
+
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []Item{}
+
+	for rows.Next() {
+		item, err := scanItem(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+// Update updates an item in the database. It returns
+// ErrNotFound if the tenant has no item with its ID, and
+// ErrInsufficientStock if the amount is below the quantity
+// held by active reservations.
 func (s *SQLDatabase) Update(
 	ctx context.Context, item Item) (Item, error) {
-	tx, err := s.DB.BeginTx(ctx, nil)
+	tx, err := s.beginTx(ctx)
 	if err != nil {
 		return Item{}, err
 	}
 	defer tx.Rollback()
 
+	if err := checkAmount(
+		ctx, tx, item.ID.String(), item.Amount); err != nil {
+		return Item{}, err
+	}
+
 	query := `UPDATE inventory SET
-        name = $1, unit = $2, amount = $3, expires_at = $4
-        WHERE id = $5`
+        name = $1, unit = $2, amount = $3, expires_at = $4,
+        sku = NULLIF($5, '') WHERE id = $6 AND tenant_id = $7`
 
-	if _, err := tx.ExecContext(
+	result, err := tx.ExecContext(
 		ctx,
 		query,
 		item.Name,
 		item.Unit,
 		item.Amount,
 		item.ExpiresAt,
-		item.ID); err != nil {
-		if err == sql.ErrNoRows {
-			return Item{}, ErrNotFound
-		}
+		item.SKU,
+		item.ID,
+		TenantFrom(ctx))
+	if err != nil {
+		return Item{}, err
+	}
 
+	affected, err := result.RowsAffected()
+	if err != nil {
 		return Item{}, err
 	}
 
+	if affected == 0 {
+		return Item{}, ErrNotFound
+	}
+
 	if err := tx.Commit(); err != nil {
 		return Item{}, err
 	}
@@ -181,28 +328,36 @@ func (s *SQLDatabase) Update(
 	return item, nil
 }
 
-// Delete deletes an item from the database.
+// Delete deletes an item from the database. It returns
+// ErrNotFound if the tenant has no item with the ID.
 func (s *SQLDatabase) Delete(
 	ctx context.Context, uuid string) error {
-	tx, err := s.DB.BeginTx(ctx, nil)
+	tx, err := s.beginTx(ctx)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	query := `DELETE FROM inventory WHERE id = $1`
+	query := `DELETE FROM inventory WHERE id = $1 AND tenant_id = $2`
 
-	if _, err := tx.ExecContext(
+	result, err := tx.ExecContext(
 		ctx,
 		query,
-		uuid); err != nil {
-		if err == sql.ErrNoRows {
-			return ErrNotFound
-		}
+		uuid,
+		TenantFrom(ctx))
+	if err != nil {
+		return err
+	}
 
+	affected, err := result.RowsAffected()
+	if err != nil {
 		return err
 	}
 
+	if affected == 0 {
+		return ErrNotFound
+	}
+
 	if err := tx.Commit(); err != nil {
 		return err
 	}
@@ -210,40 +365,40 @@ func (s *SQLDatabase) Delete(
 	return nil
 }
 
-func filterQueryBuilder(filter ItemFilter) (
+// filterQueryBuilder builds the query of the items of the
+// tenant of ctx matching the filter. The query is empty if
+// the filter is.
+func filterQueryBuilder(ctx context.Context, filter ItemFilter) (
 	query string, args []interface{}) {
-	query = "SELECT * FROM inventory WHERE "
+	args = append(args, TenantFrom(ctx))
+	query = "SELECT " + itemColumns + " FROM inventory WHERE tenant_id = $1"
 
 	if filter.Name != "" {
 		args = append(args, filter.Name)
-		query += "name = $" + strconv.Itoa(len(args))
+		query += " AND name = $" + strconv.Itoa(len(args))
 	}
 
 	if filter.Unit != "" {
-		if len(args) > 0 {
-			query += " AND "
-		}
 		args = append(args, filter.Unit)
-		query += "unit = $" + strconv.Itoa(len(args))
+		query += " AND unit = $" + strconv.Itoa(len(args))
 	}
 
 	if filter.Amount != 0.0 {
-		if len(args) > 0 {
-			query += " AND "
-		}
 		args = append(args, filter.Amount)
-		query += "amount = $" + strconv.Itoa(len(args))
+		query += " AND amount = $" + strconv.Itoa(len(args))
 	}
 
 	if !filter.ExpiresAt.IsZero() {
-		if len(args) > 0 {
-			query += " AND "
-		}
 		args = append(args, filter.ExpiresAt)
-		query += "expires_at = $" + strconv.Itoa(len(args))
+		query += " AND expires_at = $" + strconv.Itoa(len(args))
+	}
+
+	if filter.SKU != "" {
+		args = append(args, filter.SKU)
+		query += " AND sku = $" + strconv.Itoa(len(args))
 	}
 
-	if len(args) == 0 {
+	if len(args) == 1 {
 		query = ""
 	}
 
@@ -43,7 +43,7 @@ func Test_Create_Success(t *testing.T) {
 	mock.ExpectBegin()
 	mock.ExpectQuery("INSERT INTO inventory").WithArgs(
 		item.Name, item.Unit,
-		item.Amount, item.ExpiresAt).WillReturnRows(
+		item.Amount, item.ExpiresAt, item.SKU, DefaultTenant).WillReturnRows(
 		sqlMock.NewRows([]string{"id"}).AddRow(item.ID))
 	mock.ExpectCommit()
 
@@ -85,7 +85,7 @@ func Test_Create_Rollback_And_Error_On_Timeout(t *testing.T) {
 	mock.ExpectBegin()
 	mock.ExpectQuery("INSERT INTO inventory").WithArgs(
 		item.Name, item.Unit,
-		item.Amount, item.ExpiresAt).WillDelayFor(
+		item.Amount, item.ExpiresAt, item.SKU, DefaultTenant).WillDelayFor(
 		1 * time.Second)
 	mock.ExpectRollback()
 
@@ -165,7 +165,7 @@ func Test_Create_Rollback_And_Error_On_Bad_Args(t *testing.T) {
 	mock.ExpectBegin()
 	mock.ExpectQuery("INSERT INTO inventory").WithArgs(
 		item.Name, item.Unit,
-		item.Amount, item.ExpiresAt).WillReturnError(
+		item.Amount, item.ExpiresAt, item.SKU, DefaultTenant).WillReturnError(
 		errors.New("bad arg"))
 	mock.ExpectRollback()
 
@@ -207,7 +207,7 @@ func Test_Create_Commit_Fails_Returns_Error(t *testing.T) {
 	mock.ExpectBegin()
 	mock.ExpectQuery("INSERT INTO inventory").WithArgs(
 		item.Name, item.Unit,
-		item.Amount, item.ExpiresAt).WillReturnRows(
+		item.Amount, item.ExpiresAt, item.SKU, DefaultTenant).WillReturnRows(
 		sqlMock.NewRows([]string{"id"}).AddRow(item.ID))
 	mock.ExpectCommit().WillReturnError(
 		errors.New("commit fails"))
@@ -248,11 +248,11 @@ func Test_Read_Success(t *testing.T) {
 		context.Background(), 5*time.Second)
 	defer cancel()
 
-	mock.ExpectQuery("SELECT * FROM inventory WHERE id = $1").WithArgs(
-		item.ID.String()).WillReturnRows(
+	mock.ExpectQuery("SELECT id, name, unit, amount, expires_at, COALESCE(sku, '') FROM inventory WHERE id = $1 AND tenant_id = $2").WithArgs(
+		item.ID.String(), DefaultTenant).WillReturnRows(
 		sqlMock.NewRows([]string{
-			"id", "name", "unit", "amount", "expires_at"}).AddRow(
-			item.ID, item.Name, item.Unit, item.Amount, item.ExpiresAt))
+			"id", "name", "unit", "amount", "expires_at", "sku"}).AddRow(
+			item.ID, item.Name, item.Unit, item.Amount, item.ExpiresAt, item.SKU))
 
 	_, err = storage.Read(ctx, item.ID.String())
 	if err != nil {
@@ -290,8 +290,8 @@ func Test_Read_Fails_No_Match(t *testing.T) {
 		context.Background(), 5*time.Second)
 	defer cancel()
 
-	mock.ExpectQuery("SELECT * FROM inventory WHERE id = $1").WithArgs(
-		item.ID.String()).WillReturnError(
+	mock.ExpectQuery("SELECT id, name, unit, amount, expires_at, COALESCE(sku, '') FROM inventory WHERE id = $1 AND tenant_id = $2").WithArgs(
+		item.ID.String(), DefaultTenant).WillReturnError(
 		errors.New("no match"))
 
 	_, err = storage.Read(ctx, item.ID.String())
@@ -330,10 +330,11 @@ func Test_ReadAll_Success(t *testing.T) {
 		context.Background(), 5*time.Second)
 	defer cancel()
 
-	mock.ExpectQuery("SELECT * FROM inventory").WillReturnRows(
+	mock.ExpectQuery("SELECT id, name, unit, amount, expires_at, COALESCE(sku, '') FROM inventory WHERE tenant_id = $1").WithArgs(
+		DefaultTenant).WillReturnRows(
 		sqlMock.NewRows([]string{
-			"id", "name", "unit", "amount", "expires_at"}).AddRow(
-			item.ID, item.Name, item.Unit, item.Amount, item.ExpiresAt))
+			"id", "name", "unit", "amount", "expires_at", "sku"}).AddRow(
+			item.ID, item.Name, item.Unit, item.Amount, item.ExpiresAt, item.SKU))
 
 	_, err = storage.ReadAll(ctx)
 	if err != nil {
@@ -363,7 +364,8 @@ func Test_ReadAll_Query_Fails_Returns_Error(t *testing.T) {
 		context.Background(), 5*time.Second)
 	defer cancel()
 
-	mock.ExpectQuery("SELECT * FROM inventory").WillReturnError(
+	mock.ExpectQuery("SELECT id, name, unit, amount, expires_at, COALESCE(sku, '') FROM inventory WHERE tenant_id = $1").WithArgs(
+		DefaultTenant).WillReturnError(
 		errors.New("query fails"))
 
 	_, err = storage.ReadAll(ctx)
@@ -409,11 +411,11 @@ func Test_ReadBy_Success(t *testing.T) {
 	defer cancel()
 
 	mock.ExpectQuery(
-		"SELECT * FROM inventory WHERE name = $1 AND unit = $2 AND amount = $3").WithArgs(
-		filter.Name, filter.Unit, filter.Amount).WillReturnRows(
+		"SELECT id, name, unit, amount, expires_at, COALESCE(sku, '') FROM inventory WHERE tenant_id = $1 AND name = $2 AND unit = $3 AND amount = $4").WithArgs(
+		DefaultTenant, filter.Name, filter.Unit, filter.Amount).WillReturnRows(
 		sqlMock.NewRows([]string{
-			"id", "name", "unit", "amount", "expires_at"}).AddRow(
-			item.ID, item.Name, item.Unit, item.Amount, item.ExpiresAt))
+			"id", "name", "unit", "amount", "expires_at", "sku"}).AddRow(
+			item.ID, item.Name, item.Unit, item.Amount, item.ExpiresAt, item.SKU))
 	_, err = storage.ReadBy(ctx, filter)
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
@@ -474,8 +476,8 @@ func Test_ReadBy_Query_Fails_Returns_Error(t *testing.T) {
 
 	mock.ExpectBegin()
 	mock.ExpectQuery(
-		"SELECT * FROM inventory WHERE name = $1 AND unit = $2 AND amount = $3").WithArgs(
-		filter.Name, filter.Unit, filter.Amount).WillReturnError(
+		"SELECT id, name, unit, amount, expires_at, COALESCE(sku, '') FROM inventory WHERE tenant_id = $1 AND name = $2 AND unit = $3 AND amount = $4").WithArgs(
+		DefaultTenant, filter.Name, filter.Unit, filter.Amount).WillReturnError(
 		errors.New("query failed"))
 	mock.ExpectRollback()
 
@@ -485,6 +487,125 @@ func Test_ReadBy_Query_Fails_Returns_Error(t *testing.T) {
 	}
 }
 
+func Test_Iterate_Empty_Filter_Streams_All_Items(t *testing.T) {
+	driver, mock, err := sqlMock.New(
+		sqlMock.QueryMatcherOption(sqlMock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf(
+			"unexpected error while creating sqlmock: %s",
+			err)
+	}
+	defer driver.Close()
+
+	storage := SQLDatabase{
+		DB: driver,
+	}
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(), 5*time.Second)
+	defer cancel()
+
+	mock.ExpectQuery(
+		"SELECT id, name, unit, amount, expires_at, COALESCE(sku, '') FROM inventory WHERE tenant_id = $1").WithArgs(
+		DefaultTenant).WillReturnRows(
+		sqlMock.NewRows([]string{
+			"id", "name", "unit", "amount", "expires_at", "sku"}).AddRow(
+			uuid.New(), "a", "kg", 1.0, time.Now(), "").AddRow(
+			uuid.New(), "b", "kg", 2.0, time.Now(), "SKU-2"))
+
+	var names []string
+
+	err = storage.Iterate(ctx, ItemFilter{}, func(item Item) error {
+		names = append(names, item.Name)
+		return nil
+	})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	assert.Equal(t, []string{"a", "b"}, names)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func Test_ReadMany_Success(t *testing.T) {
+	driver, mock, err := sqlMock.New()
+	if err != nil {
+		t.Fatalf(
+			"unexpected error while creating sqlmock: %s",
+			err)
+	}
+	defer driver.Close()
+
+	storage := SQLDatabase{
+		DB: driver,
+	}
+
+	ids := []string{uuid.NewString(), uuid.NewString()}
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(), 5*time.Second)
+	defer cancel()
+
+	mock.ExpectQuery("WHERE tenant_id = \\$1 AND id = ANY\\(\\$2\\)").WithArgs(
+		DefaultTenant, sqlMock.AnyArg()).WillReturnRows(
+		sqlMock.NewRows([]string{
+			"id", "name", "unit", "amount", "expires_at", "sku"}).AddRow(
+			ids[1], "b", "kg", 2.0, time.Now(), ""))
+
+	items, err := storage.ReadMany(ctx, ids)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	assert.Len(t, items, 1)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func Test_ReadPage_Continues_After_Cursor(t *testing.T) {
+	driver, mock, err := sqlMock.New(
+		sqlMock.QueryMatcherOption(sqlMock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf(
+			"unexpected error while creating sqlmock: %s",
+			err)
+	}
+	defer driver.Close()
+
+	storage := SQLDatabase{
+		DB: driver,
+	}
+
+	after := uuid.NewString()
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(), 5*time.Second)
+	defer cancel()
+
+	mock.ExpectQuery(
+		"SELECT id, name, unit, amount, expires_at, COALESCE(sku, '') FROM inventory WHERE tenant_id = $1 AND unit = $2 AND id > $3 ORDER BY id LIMIT $4").WithArgs(
+		DefaultTenant, "kg", after, 2).WillReturnRows(
+		sqlMock.NewRows([]string{
+			"id", "name", "unit", "amount", "expires_at", "sku"}).AddRow(
+			uuid.New(), "a", "kg", 1.0, time.Now(), ""))
+
+	items, err := storage.ReadPage(ctx, ItemFilter{Unit: "kg"}, after, 2)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	assert.Len(t, items, 1)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
 func Test_Update_Success(t *testing.T) {
 	driver, mock, err := sqlMock.New(
 		sqlMock.QueryMatcherOption(sqlMock.QueryMatcherEqual))
@@ -513,9 +634,18 @@ func Test_Update_Success(t *testing.T) {
 	defer cancel()
 
 	mock.ExpectBegin()
+	mock.ExpectQuery(
+		"SELECT amount FROM inventory WHERE id = $1 AND tenant_id = $2 FOR UPDATE").WithArgs(
+		item.ID.String(), DefaultTenant).WillReturnRows(
+		sqlMock.NewRows([]string{"amount"}).AddRow(5.0))
+	mock.ExpectQuery(
+		"SELECT COALESCE(SUM(quantity), 0) FROM reservations WHERE item_id = $1 AND status = 'active' AND expires_at > now()").WithArgs(
+		item.ID.String()).WillReturnRows(
+		sqlMock.NewRows([]string{"sum"}).AddRow(0.0))
 	mock.ExpectExec(
-		"UPDATE inventory SET name = $1, unit = $2, amount = $3, expires_at = $4 WHERE id = $5").WithArgs(
-		item.Name, item.Unit, item.Amount, item.ExpiresAt, item.ID).WillReturnResult(
+		"UPDATE inventory SET name = $1, unit = $2, amount = $3, expires_at = $4, sku = NULLIF($5, '') WHERE id = $6 AND tenant_id = $7").WithArgs(
+		item.Name, item.Unit, item.Amount, item.ExpiresAt, item.SKU, item.ID,
+		DefaultTenant).WillReturnResult(
 		sqlMock.NewResult(1, 1))
 	mock.ExpectCommit()
 
@@ -525,6 +655,96 @@ func Test_Update_Success(t *testing.T) {
 	}
 }
 
+func Test_Update_Missing_Returns_ErrNotFound(t *testing.T) {
+	driver, mock, err := sqlMock.New(
+		sqlMock.QueryMatcherOption(sqlMock.QueryMatcherEqual))
+
+	if err != nil {
+		t.Fatalf(
+			"unexpected error while creating sqlmock: %s",
+			err)
+	}
+	defer driver.Close()
+
+	storage := SQLDatabase{
+		DB: driver,
+	}
+
+	item := Item{
+		ID:        uuid.New(),
+		Name:      "test",
+		Unit:      "kg",
+		Amount:    1.1,
+		ExpiresAt: time.Now().Add(5 * time.Minute),
+	}
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(), 5*time.Second)
+	defer cancel()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(
+		"SELECT amount FROM inventory WHERE id = $1 AND tenant_id = $2 FOR UPDATE").WithArgs(
+		item.ID.String(), DefaultTenant).WillReturnRows(
+		sqlMock.NewRows([]string{"amount"}))
+	mock.ExpectRollback()
+
+	if _, err := storage.Update(ctx, item); !errors.Is(err, ErrNotFound) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func Test_Update_Below_Reserved_Returns_ErrInsufficientStock(t *testing.T) {
+	driver, mock, err := sqlMock.New(
+		sqlMock.QueryMatcherOption(sqlMock.QueryMatcherEqual))
+
+	if err != nil {
+		t.Fatalf(
+			"unexpected error while creating sqlmock: %s",
+			err)
+	}
+	defer driver.Close()
+
+	storage := SQLDatabase{
+		DB: driver,
+	}
+
+	item := Item{
+		ID:        uuid.New(),
+		Name:      "test",
+		Unit:      "kg",
+		Amount:    1.1,
+		ExpiresAt: time.Now().Add(5 * time.Minute),
+	}
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(), 5*time.Second)
+	defer cancel()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(
+		"SELECT amount FROM inventory WHERE id = $1 AND tenant_id = $2 FOR UPDATE").WithArgs(
+		item.ID.String(), DefaultTenant).WillReturnRows(
+		sqlMock.NewRows([]string{"amount"}).AddRow(5.0))
+	mock.ExpectQuery(
+		"SELECT COALESCE(SUM(quantity), 0) FROM reservations WHERE item_id = $1 AND status = 'active' AND expires_at > now()").WithArgs(
+		item.ID.String()).WillReturnRows(
+		sqlMock.NewRows([]string{"sum"}).AddRow(2.0))
+	mock.ExpectRollback()
+
+	if _, err := storage.Update(ctx, item); !errors.Is(err, ErrInsufficientStock) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
 func Test_Update_Fails_With_No_ID(t *testing.T) {
 	driver, mock, err := sqlMock.New(
 		sqlMock.QueryMatcherOption(sqlMock.QueryMatcherEqual))
@@ -552,8 +772,8 @@ func Test_Update_Fails_With_No_ID(t *testing.T) {
 	defer cancel()
 
 	mock.ExpectBegin()
-	mock.ExpectExec(
-		"UPDATE inventory SET name = $1, unit = $2, amount = $3, expires_at = $4 WHERE id = $5").WithArgs().WillReturnError(
+	mock.ExpectQuery(
+		"SELECT amount FROM inventory WHERE id = $1 AND tenant_id = $2 FOR UPDATE").WillReturnError(
 		errors.New("no id"))
 	mock.ExpectRollback()
 
@@ -592,8 +812,8 @@ func Test_Delete_Success(t *testing.T) {
 
 	mock.ExpectBegin()
 	mock.ExpectExec(
-		"DELETE FROM inventory WHERE id = $1").WithArgs(
-		item.ID).WillReturnResult(
+		"DELETE FROM inventory WHERE id = $1 AND tenant_id = $2").WithArgs(
+		item.ID, DefaultTenant).WillReturnResult(
 		sqlMock.NewResult(1, 1))
 	mock.ExpectCommit()
 
@@ -602,6 +822,43 @@ func Test_Delete_Success(t *testing.T) {
 	}
 }
 
+func Test_Delete_Missing_Returns_ErrNotFound(t *testing.T) {
+	driver, mock, err := sqlMock.New(
+		sqlMock.QueryMatcherOption(sqlMock.QueryMatcherEqual))
+
+	if err != nil {
+		t.Fatalf(
+			"unexpected error while creating sqlmock: %s",
+			err)
+	}
+	defer driver.Close()
+
+	storage := SQLDatabase{
+		DB: driver,
+	}
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(), 5*time.Second)
+	defer cancel()
+
+	id := uuid.New().String()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(
+		"DELETE FROM inventory WHERE id = $1 AND tenant_id = $2").WithArgs(
+		id, DefaultTenant).WillReturnResult(
+		sqlMock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	if err := storage.Delete(ctx, id); !errors.Is(err, ErrNotFound) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
 func Test_Delete_Returns_Error_On_Fail(t *testing.T) {
 	driver, mock, err := sqlMock.New(
 		sqlMock.QueryMatcherOption(sqlMock.QueryMatcherEqual))
@@ -631,8 +888,8 @@ func Test_Delete_Returns_Error_On_Fail(t *testing.T) {
 
 	mock.ExpectBegin()
 	mock.ExpectExec(
-		"DELETE FROM inventory WHERE id = $1").WithArgs(
-		item.ID).WillReturnError(
+		"DELETE FROM inventory WHERE id = $1 AND tenant_id = $2").WithArgs(
+		item.ID, DefaultTenant).WillReturnError(
 		errors.New("delete failed"))
 	mock.ExpectRollback()
 
@@ -654,39 +911,39 @@ func Test_filterQueryBuilder(t *testing.T) {
 			name:      "empty filter returns empty query",
 			input:     ItemFilter{},
 			wantQuery: "",
-			wantArgs:  nil,
+			wantArgs:  []interface{}{DefaultTenant},
 		},
 		{
 			name: "filter with name returns query with name",
 			input: ItemFilter{
 				Name: "test",
 			},
-			wantQuery: "SELECT * FROM inventory WHERE name = $1",
-			wantArgs:  []interface{}{"test"},
+			wantQuery: "SELECT id, name, unit, amount, expires_at, COALESCE(sku, '') FROM inventory WHERE tenant_id = $1 AND name = $2",
+			wantArgs:  []interface{}{DefaultTenant, "test"},
 		},
 		{
 			name: "filter with unit returns query with unit",
 			input: ItemFilter{
 				Unit: "kg",
 			},
-			wantQuery: "SELECT * FROM inventory WHERE unit = $1",
-			wantArgs:  []interface{}{"kg"},
+			wantQuery: "SELECT id, name, unit, amount, expires_at, COALESCE(sku, '') FROM inventory WHERE tenant_id = $1 AND unit = $2",
+			wantArgs:  []interface{}{DefaultTenant, "kg"},
 		},
 		{
 			name: "filter with amount returns query with amount",
 			input: ItemFilter{
 				Amount: 1.1,
 			},
-			wantQuery: "SELECT * FROM inventory WHERE amount = $1",
-			wantArgs:  []interface{}{1.1},
+			wantQuery: "SELECT id, name, unit, amount, expires_at, COALESCE(sku, '') FROM inventory WHERE tenant_id = $1 AND amount = $2",
+			wantArgs:  []interface{}{DefaultTenant, 1.1},
 		},
 		{
 			name: "filter with expiresat returns query with expiresat",
 			input: ItemFilter{
 				ExpiresAt: expiration,
 			},
-			wantQuery: "SELECT * FROM inventory WHERE expires_at = $1",
-			wantArgs:  []interface{}{expiration},
+			wantQuery: "SELECT id, name, unit, amount, expires_at, COALESCE(sku, '') FROM inventory WHERE tenant_id = $1 AND expires_at = $2",
+			wantArgs:  []interface{}{DefaultTenant, expiration},
 		},
 		{
 			name: "filter with name, amount returns query with name and amount",
@@ -694,8 +951,8 @@ func Test_filterQueryBuilder(t *testing.T) {
 				Name:   "test",
 				Amount: 1.1,
 			},
-			wantQuery: "SELECT * FROM inventory WHERE name = $1 AND amount = $2",
-			wantArgs:  []interface{}{"test", 1.1},
+			wantQuery: "SELECT id, name, unit, amount, expires_at, COALESCE(sku, '') FROM inventory WHERE tenant_id = $1 AND name = $2 AND amount = $3",
+			wantArgs:  []interface{}{DefaultTenant, "test", 1.1},
 		},
 		{
 			name: "filter with unit, expiresat returns query with unit and expiresat",
@@ -703,8 +960,8 @@ func Test_filterQueryBuilder(t *testing.T) {
 				Unit:      "kg",
 				ExpiresAt: expiration,
 			},
-			wantQuery: "SELECT * FROM inventory WHERE unit = $1 AND expires_at = $2",
-			wantArgs:  []interface{}{"kg", expiration},
+			wantQuery: "SELECT id, name, unit, amount, expires_at, COALESCE(sku, '') FROM inventory WHERE tenant_id = $1 AND unit = $2 AND expires_at = $3",
+			wantArgs:  []interface{}{DefaultTenant, "kg", expiration},
 		},
 		{
 			name: "full filter returns expected query",
@@ -714,15 +971,16 @@ func Test_filterQueryBuilder(t *testing.T) {
 				Amount:    1.1,
 				ExpiresAt: expiration,
 			},
-			wantQuery: "SELECT * FROM inventory WHERE name = $1 AND unit = $2 AND amount = $3 AND expires_at = $4",
+			wantQuery: "SELECT id, name, unit, amount, expires_at, COALESCE(sku, '') FROM inventory WHERE tenant_id = $1 AND name = $2 AND unit = $3 AND amount = $4 AND expires_at = $5",
 			wantArgs: []interface{}{
-				"test", "kg", 1.1, expiration},
+				DefaultTenant, "test", "kg", 1.1, expiration},
 		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			gotQuery, gotArgs := filterQueryBuilder(test.input)
+			gotQuery, gotArgs := filterQueryBuilder(
+				context.Background(), test.input)
 
 			if test.wantQuery != gotQuery {
 				t.Errorf("unexpected query: %s", gotQuery)
@@ -0,0 +1,54 @@
+package persistence
+
+import (
+	"context"
+)
+
+// ItemStats counts the items of a tenant.
+type ItemStats struct {
+	Tenant   string
+	Total    int64
+	Expired  int64
+	LowStock int64
+}
+
+// ItemStats counts the items of every tenant. Items whose
+// amount is below lowStock are counted as low on stock,
+// expired items included.
+func (s *SQLDatabase) ItemStats(
+	ctx context.Context, lowStock float64) ([]ItemStats, error) {
+	q, done, err := s.allTenants(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done(nil)
+
+	query := `SELECT tenant_id, count(*),
+        count(*) FILTER (WHERE expires_at <= now()),
+        count(*) FILTER (WHERE amount < $1)
+        FROM inventory GROUP BY tenant_id ORDER BY tenant_id`
+
+	rows, err := q.QueryContext(ctx, query, lowStock)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []ItemStats
+
+	for rows.Next() {
+		var stat ItemStats
+
+		if err := rows.Scan(
+			&stat.Tenant,
+			&stat.Total,
+			&stat.Expired,
+			&stat.LowStock); err != nil {
+			return nil, err
+		}
+
+		stats = append(stats, stat)
+	}
+
+	return stats, rows.Err()
+}
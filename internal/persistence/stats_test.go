@@ -0,0 +1,82 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	sqlMock "github.com/DATA-DOG/go-sqlmock"
+)
+
+func Test_ItemStats_Success(t *testing.T) {
+	driver, mock, err := sqlMock.New()
+	if err != nil {
+		t.Fatalf(
+			"unexpected error while creating sqlmock: %s",
+			err)
+	}
+	defer driver.Close()
+
+	storage := SQLDatabase{
+		DB: driver,
+	}
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(), 5*time.Second)
+	defer cancel()
+
+	mock.ExpectQuery("SELECT tenant_id, count").WithArgs(
+		10.0).WillReturnRows(
+		sqlMock.NewRows([]string{
+			"tenant_id", "count", "expired", "low_stock"}).
+			AddRow("acme", 5, 1, 2).
+			AddRow(DefaultTenant, 3, 0, 0))
+
+	stats, err := storage.ItemStats(ctx, 10)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if len(stats) != 2 {
+		t.Fatalf("unexpected number of stats: %d", len(stats))
+	}
+
+	want := ItemStats{Tenant: "acme", Total: 5, Expired: 1, LowStock: 2}
+	if stats[0] != want {
+		t.Errorf("unexpected stats: %+v", stats[0])
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func Test_ItemStats_Error(t *testing.T) {
+	driver, mock, err := sqlMock.New()
+	if err != nil {
+		t.Fatalf(
+			"unexpected error while creating sqlmock: %s",
+			err)
+	}
+	defer driver.Close()
+
+	storage := SQLDatabase{
+		DB: driver,
+	}
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(), 5*time.Second)
+	defer cancel()
+
+	mock.ExpectQuery("SELECT tenant_id, count").WithArgs(
+		10.0).WillReturnError(errors.New("connection lost"))
+
+	if _, err := storage.ItemStats(ctx, 10); err == nil {
+		t.Errorf("expected an error")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
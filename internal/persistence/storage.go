@@ -3,8 +3,10 @@ package persistence
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"github.com/Salam4nder/inventory/internal/config"
+	"github.com/Salam4nder/inventory/internal/tracing"
 
 	"github.com/google/uuid"
 	// --nolint:staticcheck.
@@ -19,8 +21,13 @@ const (
 )
 
 // Storage is a persistence layer interface
-// with basic CRUD operations.
+// with basic CRUD operations, stock reservations,
+// user accounts, refresh tokens and API keys.
 type Storage interface {
+	UserStorage
+	RefreshTokenStorage
+	APIKeyStorage
+
 	Create(ctx context.Context, item Item) (
 		uuid.UUID, error)
 	Read(ctx context.Context, uuid string) (
@@ -28,16 +35,50 @@ type Storage interface {
 	ReadAll(ctx context.Context) ([]Item, error)
 	ReadBy(ctx context.Context, filter ItemFilter) (
 		[]Item, error)
+	Iterate(ctx context.Context, filter ItemFilter,
+		fn func(Item) error) error
+	ReadMany(ctx context.Context, uuids []string) (
+		[]Item, error)
+	ReadPage(ctx context.Context, filter ItemFilter,
+		after string, limit int) ([]Item, error)
+	Search(ctx context.Context, query string, limit int) (
+		[]SearchResult, error)
 	Update(ctx context.Context, item Item) (
 		Item, error)
 	Delete(ctx context.Context, uuid string) error
+	Batch(ctx context.Context, ops []BatchOperation, atomic bool) (
+		[]BatchResult, error)
 	Ping(ctx context.Context) error
+
+	Reserve(ctx context.Context, reservation Reservation,
+		ttl time.Duration) (
+		Reservation, error)
+	ReadReservation(ctx context.Context, uuid string) (
+		Reservation, error)
+	Available(ctx context.Context, uuid string) (
+		Stock, error)
+	Stocks(ctx context.Context, uuids []string) (
+		[]Stock, error)
+	ActiveReservations(ctx context.Context, itemUUIDs []string) (
+		[]Reservation, error)
+	AdjustStock(ctx context.Context, uuid string, delta float64) (
+		Item, error)
+	CommitReservation(ctx context.Context, uuid string) (
+		Reservation, error)
+	ReleaseReservation(ctx context.Context, uuid string) (
+		Reservation, error)
+	ReleaseExpiredReservations(ctx context.Context) (
+		int64, error)
 }
 
 // SQLDatabase implements the Storage interface.
+// Every call is scoped to the tenant of its context.
 type SQLDatabase struct {
 	DB     *sql.DB
 	Config config.Database
+	// RowLevelSecurity binds the statements on tenant
+	// tables to the tenant in Postgres as well.
+	RowLevelSecurity bool
 }
 
 // Ping checks the connection to the database.
@@ -48,9 +89,10 @@ func (s *SQLDatabase) Ping(ctx context.Context) error {
 // New creates a new SQLDatabase instance from the given
 // config.Database and driver string.
 // Drivers are provided in this package as constants.
+// Every statement is traced.
 func New(
 	dbCfg config.Database, driver string) (*SQLDatabase, error) {
-	db, err := sql.Open(driver, dbCfg.PSQLConn())
+	db, err := tracing.OpenDB(driver, dbCfg.PSQLConn())
 	if err != nil {
 		return nil, err
 	}
@@ -59,5 +101,9 @@ func New(
 		return nil, err
 	}
 
-	return &SQLDatabase{DB: db, Config: dbCfg}, nil
+	return &SQLDatabase{
+		DB:               db,
+		Config:           dbCfg,
+		RowLevelSecurity: dbCfg.RowLevelSecurity,
+	}, nil
 }
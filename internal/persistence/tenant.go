@@ -0,0 +1,135 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+)
+
+// DefaultTenant owns the rows created before tenants were
+// introduced. It is the tenant of contexts without one, so
+// single-tenant deployments never have to set a tenant.
+const DefaultTenant = "default"
+
+// rlsTables are the tables protected by row-level security
+// when it is enabled.
+var rlsTables = []string{"inventory", "reservations"}
+
+type tenantKey struct{}
+
+// WithTenant returns a copy of ctx whose storage
+// calls are scoped to the given tenant.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFrom returns the tenant of ctx, or DefaultTenant
+// if ctx has none.
+func TenantFrom(ctx context.Context) string {
+	if tenant, ok := ctx.Value(tenantKey{}).(string); ok && tenant != "" {
+		return tenant
+	}
+
+	return DefaultTenant
+}
+
+// querier is implemented by *sql.DB and *sql.Tx.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (
+		sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (
+		*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// beginTx starts a transaction. With row-level security
+// the transaction only sees the rows of the tenant of ctx.
+func (s *SQLDatabase) beginTx(ctx context.Context) (*sql.Tx, error) {
+	return s.begin(ctx, "app.tenant_id", TenantFrom(ctx))
+}
+
+// beginTxAllTenants starts a transaction that sees the rows
+// of every tenant, for maintenance across tenants.
+func (s *SQLDatabase) beginTxAllTenants(ctx context.Context) (*sql.Tx, error) {
+	return s.begin(ctx, "app.all_tenants", "on")
+}
+
+func (s *SQLDatabase) begin(
+	ctx context.Context, setting, value string) (*sql.Tx, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if !s.RowLevelSecurity {
+		return tx, nil
+	}
+
+	if _, err := tx.ExecContext(
+		ctx, `SELECT set_config($1, $2, true)`, setting, value); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	return tx, nil
+}
+
+// scoped returns where to run statements on the tables of
+// rlsTables outside of a transaction. Without row-level
+// security that is the database itself, otherwise it is a
+// transaction bound to the tenant of ctx. The returned done
+// ends the transaction: it commits if given no error and
+// returns the error it was given, or the commit error.
+func (s *SQLDatabase) scoped(ctx context.Context) (
+	q querier, done func(error) error, err error) {
+	return s.session(ctx, s.beginTx)
+}
+
+// allTenants is like scoped, for statements on the rows
+// of every tenant.
+func (s *SQLDatabase) allTenants(ctx context.Context) (
+	q querier, done func(error) error, err error) {
+	return s.session(ctx, s.beginTxAllTenants)
+}
+
+func (s *SQLDatabase) session(
+	ctx context.Context,
+	begin func(context.Context) (*sql.Tx, error)) (
+	querier, func(error) error, error) {
+	if !s.RowLevelSecurity {
+		return s.DB, func(err error) error { return err }, nil
+	}
+
+	tx, err := begin(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return tx, func(err error) error {
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		return tx.Commit()
+	}, nil
+}
+
+// ConfigureRowLevelSecurity enables or disables row-level
+// security on the tenant tables to match the configuration.
+// The policies themselves are created by the migrations.
+// Security is forced, so it also applies to the table owner.
+func (s *SQLDatabase) ConfigureRowLevelSecurity(ctx context.Context) error {
+	for _, table := range rlsTables {
+		statement := `ALTER TABLE ` + table +
+			` NO FORCE ROW LEVEL SECURITY, DISABLE ROW LEVEL SECURITY`
+		if s.RowLevelSecurity {
+			statement = `ALTER TABLE ` + table +
+				` ENABLE ROW LEVEL SECURITY, FORCE ROW LEVEL SECURITY`
+		}
+
+		if _, err := s.DB.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,62 @@
+package persistence
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sqlMock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+)
+
+func Test_TenantFrom_Defaults_Without_Tenant(t *testing.T) {
+	if tenant := TenantFrom(context.Background()); tenant != DefaultTenant {
+		t.Errorf("unexpected tenant: %s", tenant)
+	}
+
+	ctx := WithTenant(context.Background(), "acme")
+
+	if tenant := TenantFrom(ctx); tenant != "acme" {
+		t.Errorf("unexpected tenant: %s", tenant)
+	}
+}
+
+func Test_Read_Row_Level_Security_Sets_Tenant(t *testing.T) {
+	driver, mock, err := sqlMock.New()
+	if err != nil {
+		t.Fatalf(
+			"unexpected error while creating sqlmock: %s",
+			err)
+	}
+	defer driver.Close()
+
+	storage := SQLDatabase{
+		DB:               driver,
+		RowLevelSecurity: true,
+	}
+
+	id := uuid.New()
+
+	ctx, cancel := context.WithTimeout(
+		WithTenant(context.Background(), "acme"), 5*time.Second)
+	defer cancel()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs(
+		"app.tenant_id", "acme").WillReturnResult(
+		sqlMock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT (.+) FROM inventory").WithArgs(
+		id.String(), "acme").WillReturnRows(
+		sqlMock.NewRows([]string{
+			"id", "name", "unit", "amount", "expires_at", "sku"}).AddRow(
+			id, "test", "kg", 1.0, time.Now(), ""))
+	mock.ExpectCommit()
+
+	if _, err := storage.Read(ctx, id.String()); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
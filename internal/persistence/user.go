@@ -0,0 +1,167 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// uniqueViolation is the Postgres error code of
+// a unique constraint violation.
+const uniqueViolation = "23505"
+
+// User is an account that can log in to the API.
+// Usernames are unique across tenants, so logins
+// do not have to name the tenant.
+type User struct {
+	ID           uuid.UUID `json:"id"`
+	TenantID     string    `json:"tenant"`
+	Username     string    `json:"username"`
+	PasswordHash string    `json:"-"`
+	Role         string    `json:"role"`
+	Disabled     bool      `json:"disabled"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// UserStorage is the persistence layer of user accounts.
+type UserStorage interface {
+	CreateUser(ctx context.Context, user User) (User, error)
+	ReadUser(ctx context.Context, uuid string) (User, error)
+	ReadUserByUsername(ctx context.Context, username string) (
+		User, error)
+	ReadUsers(ctx context.Context) ([]User, error)
+	SetUserDisabled(ctx context.Context, uuid string, disabled bool) (
+		User, error)
+	SetUserPassword(ctx context.Context, uuid, passwordHash string) (
+		User, error)
+	SetUserRole(ctx context.Context, uuid, role string) (User, error)
+}
+
+// userColumns are the columns scanned by scanUser.
+const userColumns = `id, tenant_id, username, password_hash, role,
+        disabled, created_at, updated_at`
+
+// CreateUser creates a new user in the tenant of ctx. It
+// returns ErrUserExists if the username is already taken.
+func (s *SQLDatabase) CreateUser(
+	ctx context.Context, user User) (User, error) {
+	query := `INSERT INTO users (
+        username, password_hash, role, tenant_id)
+        VALUES (
+        $1, $2, $3, $4) RETURNING ` + userColumns
+
+	user, err := scanUser(s.DB.QueryRowContext(
+		ctx,
+		query,
+		user.Username,
+		user.PasswordHash,
+		user.Role,
+		TenantFrom(ctx)))
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return User{}, ErrUserExists
+		}
+		return User{}, err
+	}
+
+	return user, nil
+}
+
+// ReadUser reads a user based off of an uuid.
+func (s *SQLDatabase) ReadUser(
+	ctx context.Context, uuid string) (User, error) {
+	query := `SELECT ` + userColumns + ` FROM users
+        WHERE id = $1 AND tenant_id = $2`
+
+	return scanUser(s.DB.QueryRowContext(ctx, query, uuid, TenantFrom(ctx)))
+}
+
+// ReadUserByUsername reads a user based off of its username,
+// in any tenant. It is used to log in.
+func (s *SQLDatabase) ReadUserByUsername(
+	ctx context.Context, username string) (User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE username = $1`
+
+	return scanUser(s.DB.QueryRowContext(ctx, query, username))
+}
+
+// ReadUsers reads all users ordered by username.
+func (s *SQLDatabase) ReadUsers(ctx context.Context) ([]User, error) {
+	query := `SELECT ` + userColumns + ` FROM users
+        WHERE tenant_id = $1 ORDER BY username`
+
+	rows, err := s.DB.QueryContext(ctx, query, TenantFrom(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []User{}
+
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+// SetUserDisabled disables or enables a user.
+func (s *SQLDatabase) SetUserDisabled(
+	ctx context.Context, uuid string, disabled bool) (User, error) {
+	query := `UPDATE users SET disabled = $1, updated_at = now()
+        WHERE id = $2 AND tenant_id = $3 RETURNING ` + userColumns
+
+	return scanUser(s.DB.QueryRowContext(
+		ctx, query, disabled, uuid, TenantFrom(ctx)))
+}
+
+// SetUserPassword replaces the password hash of a user.
+func (s *SQLDatabase) SetUserPassword(
+	ctx context.Context, uuid, passwordHash string) (User, error) {
+	query := `UPDATE users SET password_hash = $1, updated_at = now()
+        WHERE id = $2 AND tenant_id = $3 RETURNING ` + userColumns
+
+	return scanUser(s.DB.QueryRowContext(
+		ctx, query, passwordHash, uuid, TenantFrom(ctx)))
+}
+
+// SetUserRole changes the role of a user.
+func (s *SQLDatabase) SetUserRole(
+	ctx context.Context, uuid, role string) (User, error) {
+	query := `UPDATE users SET role = $1, updated_at = now()
+        WHERE id = $2 AND tenant_id = $3 RETURNING ` + userColumns
+
+	return scanUser(s.DB.QueryRowContext(
+		ctx, query, role, uuid, TenantFrom(ctx)))
+}
+
+func scanUser(row scanner) (User, error) {
+	var user User
+
+	if err := row.Scan(
+		&user.ID,
+		&user.TenantID,
+		&user.Username,
+		&user.PasswordHash,
+		&user.Role,
+		&user.Disabled,
+		&user.CreatedAt,
+		&user.UpdatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return User{}, ErrNotFound
+		}
+		return User{}, err
+	}
+
+	return user, nil
+}
@@ -0,0 +1,93 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	sqlMock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+func Test_CreateUser_Success(t *testing.T) {
+	driver, mock, err := sqlMock.New()
+	if err != nil {
+		t.Fatalf(
+			"unexpected error while creating sqlmock: %s",
+			err)
+	}
+	defer driver.Close()
+
+	storage := SQLDatabase{
+		DB: driver,
+	}
+
+	user := User{
+		Username:     "admin",
+		PasswordHash: "hash",
+		Role:         "admin",
+	}
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(), 5*time.Second)
+	defer cancel()
+
+	mock.ExpectQuery("INSERT INTO users").WithArgs(
+		user.Username, user.PasswordHash, user.Role, DefaultTenant).WillReturnRows(
+		sqlMock.NewRows([]string{
+			"id", "tenant_id", "username", "password_hash", "role",
+			"disabled", "created_at", "updated_at"}).AddRow(
+			uuid.New(), DefaultTenant, user.Username, user.PasswordHash, user.Role,
+			false, time.Now(), time.Now()))
+
+	created, err := storage.CreateUser(ctx, user)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if created.ID == uuid.Nil {
+		t.Errorf("expected user to have an id")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func Test_CreateUser_Duplicate_Returns_ErrUserExists(t *testing.T) {
+	driver, mock, err := sqlMock.New()
+	if err != nil {
+		t.Fatalf(
+			"unexpected error while creating sqlmock: %s",
+			err)
+	}
+	defer driver.Close()
+
+	storage := SQLDatabase{
+		DB: driver,
+	}
+
+	user := User{
+		Username:     "admin",
+		PasswordHash: "hash",
+	}
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(), 5*time.Second)
+	defer cancel()
+
+	mock.ExpectQuery("INSERT INTO users").WithArgs(
+		user.Username, user.PasswordHash, user.Role, DefaultTenant).WillReturnError(
+		&pq.Error{Code: uniqueViolation})
+
+	_, err = storage.CreateUser(ctx, user)
+	if !errors.Is(err, ErrUserExists) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,117 @@
+package rpc
+
+import (
+	"errors"
+	"time"
+
+	inventoryv1 "github.com/Salam4nder/inventory/api/inventory/v1"
+	"github.com/Salam4nder/inventory/internal/cache"
+	"github.com/Salam4nder/inventory/internal/persistence"
+
+	"github.com/google/uuid"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// eventTypes maps the item event types to the protobuf enum.
+var eventTypes = map[cache.ItemEventType]inventoryv1.ItemEvent_Type{
+	cache.ItemCreated: inventoryv1.ItemEvent_TYPE_CREATED,
+	cache.ItemUpdated: inventoryv1.ItemEvent_TYPE_UPDATED,
+	cache.ItemDeleted: inventoryv1.ItemEvent_TYPE_DELETED,
+}
+
+// itemToProto converts a persistence.Item to an inventoryv1.Item.
+func itemToProto(item persistence.Item) *inventoryv1.Item {
+	return &inventoryv1.Item{
+		Id:        item.ID.String(),
+		Name:      item.Name,
+		Unit:      item.Unit,
+		Amount:    item.Amount,
+		ExpiresAt: timestamp(item.ExpiresAt),
+		Sku:       item.SKU,
+	}
+}
+
+// eventToProto converts a cache.ItemEvent to an inventoryv1.ItemEvent.
+func eventToProto(event cache.ItemEvent) *inventoryv1.ItemEvent {
+	return &inventoryv1.ItemEvent{
+		Type: eventTypes[event.Type],
+		Item: itemToProto(event.Item),
+	}
+}
+
+// filterFromProto converts an inventoryv1.ItemFilter to a
+// persistence.ItemFilter. Unset fields do not filter.
+func filterFromProto(filter *inventoryv1.ItemFilter) persistence.ItemFilter {
+	return persistence.ItemFilter{
+		Name:      filter.GetName(),
+		Unit:      filter.GetUnit(),
+		Amount:    filter.GetAmount(),
+		ExpiresAt: fromTimestamp(filter.GetExpiresAt()),
+		SKU:       filter.GetSku(),
+	}
+}
+
+// timestamp converts t to a timestamp, the zero time to nil.
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+
+	return timestamppb.New(t)
+}
+
+// fromTimestamp converts ts to a time, nil to the zero time.
+func fromTimestamp(ts *timestamppb.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+
+	return ts.AsTime()
+}
+
+// violations collects the invalid fields of a request.
+type violations []*errdetails.BadRequest_FieldViolation
+
+// add records that the field is invalid.
+func (v *violations) add(field, description string) {
+	*v = append(*v, &errdetails.BadRequest_FieldViolation{
+		Field:       field,
+		Description: description,
+	})
+}
+
+// checkID records an invalid ID field.
+func (v *violations) checkID(field, id string) {
+	if _, err := uuid.Parse(id); err != nil {
+		v.add(field, "must be a UUID")
+	}
+}
+
+// checkItem records the invalid fields of an item with the
+// rules of persistence.ValidateItem, and an invalid expiresAt,
+// the timestamp the item was converted from.
+func (v *violations) checkItem(
+	prefix string,
+	item persistence.Item,
+	expiresAt *timestamppb.Timestamp) {
+	var itemViolations persistence.Violations
+	if errors.As(persistence.ValidateItem(item), &itemViolations) {
+		for _, violation := range itemViolations {
+			v.add(prefix+violation.Field, violation.Description)
+		}
+	}
+	if expiresAt != nil && expiresAt.CheckValid() != nil {
+		v.add(prefix+"expires_at", "must be a valid timestamp")
+	}
+}
+
+// err returns the InvalidArgument status of the
+// violations, or nil if there are none.
+func (v violations) err() error {
+	if len(v) == 0 {
+		return nil
+	}
+
+	return invalid(v)
+}
@@ -0,0 +1,66 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Salam4nder/inventory/internal/authn"
+	"github.com/Salam4nder/inventory/internal/persistence"
+	"github.com/Salam4nder/inventory/pkg/logger"
+
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errorCode maps an error of the storage to a status code.
+type errorCode struct {
+	err  error
+	code codes.Code
+}
+
+// errorCodes maps the errors of the storage and the
+// authenticator to status codes, like the problems of
+// the REST API.
+var errorCodes = []errorCode{
+	{persistence.ErrNotFound, codes.NotFound},
+	{persistence.ErrInsufficientStock, codes.FailedPrecondition},
+	{authn.ErrDenylistUnavailable, codes.Unavailable},
+	{context.DeadlineExceeded, codes.DeadlineExceeded},
+	{context.Canceled, codes.Canceled},
+}
+
+// fail returns the status err maps to. Errors that do not map
+// to a status are internal errors, they are logged and their
+// message is not sent to the client. Errors that already are
+// a status are returned as they are.
+func fail(ctx context.Context, err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	for _, mapping := range errorCodes {
+		if errors.Is(err, mapping.err) {
+			return status.Error(mapping.code, err.Error())
+		}
+	}
+
+	logger.FromContext(ctx).Error(err.Error(), zap.Error(err))
+
+	return status.Error(codes.Internal, "internal error")
+}
+
+// invalid returns an InvalidArgument status that reports
+// the violations per field.
+func invalid(violations []*errdetails.BadRequest_FieldViolation) error {
+	st := status.New(codes.InvalidArgument, "invalid request")
+
+	detailed, err := st.WithDetails(
+		&errdetails.BadRequest{FieldViolations: violations})
+	if err != nil {
+		return st.Err()
+	}
+
+	return detailed.Err()
+}
@@ -0,0 +1,244 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	inventoryv1 "github.com/Salam4nder/inventory/api/inventory/v1"
+	"github.com/Salam4nder/inventory/internal/authn"
+	"github.com/Salam4nder/inventory/internal/persistence"
+	"github.com/Salam4nder/inventory/pkg/auth"
+	"github.com/Salam4nder/inventory/pkg/logger"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Metadata keys of the credentials and the request ID. They
+// match the headers of the REST API.
+const (
+	authorizationMetadata = "authorization"
+	apiKeyMetadata        = "x-api-key"
+	requestIDMetadata     = "x-request-id"
+)
+
+// methodPermissions are the permissions required by the
+// methods of the service. Methods that are not listed
+// are denied.
+var methodPermissions = map[string]auth.Permission{
+	method("CreateItem"):  auth.PermissionItemsWrite,
+	method("GetItem"):     auth.PermissionItemsRead,
+	method("ListItems"):   auth.PermissionItemsRead,
+	method("UpdateItem"):  auth.PermissionItemsWrite,
+	method("DeleteItem"):  auth.PermissionItemsDelete,
+	method("AdjustStock"): auth.PermissionItemsWrite,
+	method("WatchItems"):  auth.PermissionItemsRead,
+}
+
+// method returns the full name of a method of the service.
+func method(name string) string {
+	return "/" + inventoryv1.InventoryService_ServiceDesc.ServiceName +
+		"/" + name
+}
+
+// unaryInterceptor runs unary calls through call.
+func (s *Server) unaryInterceptor(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+	var resp interface{}
+
+	err := s.call(ctx, info.FullMethod, func(ctx context.Context) error {
+		var err error
+		resp, err = handler(ctx, req)
+		return err
+	})
+
+	return resp, err
+}
+
+// streamInterceptor runs streams through call.
+func (s *Server) streamInterceptor(
+	srv interface{},
+	stream grpc.ServerStream,
+	info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler) error {
+	return s.call(stream.Context(), info.FullMethod,
+		func(ctx context.Context) error {
+			return handler(srv, &serverStream{stream, ctx})
+		})
+}
+
+// serverStream is a stream with the context of call.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context returns the context of the stream.
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// call identifies, authenticates and authorizes a call like
+// the middlewares of the REST API do for requests, before it
+// is handled. Calls are unavailable until the database is
+// migrated, and they are rate limited. Panics of the handler are turned into internal
+// errors, and every call is logged once it is finished.
+func (s *Server) call(
+	ctx context.Context,
+	fullMethod string,
+	handler func(context.Context) error) (err error) {
+	start := time.Now()
+	ctx = s.withRequestID(ctx)
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			logger.FromContext(ctx).Error("panic recovered",
+				zap.Any("panic", recovered), zap.Stack("stack"))
+			err = status.Error(codes.Internal, "internal error")
+		}
+
+		logCall(ctx, fullMethod, time.Since(start), err)
+	}()
+
+	if !s.migrated.Load() {
+		return status.Error(codes.Unavailable,
+			"database migrations are not done")
+	}
+
+	ctx, err = s.authenticate(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := s.rateLimit(ctx); err != nil {
+		return err
+	}
+
+	if err := authorize(ctx, fullMethod); err != nil {
+		return err
+	}
+
+	return handler(ctx)
+}
+
+// withRequestID returns a context that carries a logger with
+// the request ID and the trace ID. The x-request-id of the
+// client is kept if it is valid, otherwise a new ID is
+// generated. The ID is sent back in the response header.
+func (s *Server) withRequestID(ctx context.Context) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+	id := logger.RequestID(first(md, requestIDMetadata))
+
+	// The header cannot be set outside of a call,
+	// such as in tests of the interceptors.
+	_ = grpc.SetHeader(ctx, metadata.Pairs(requestIDMetadata, id))
+
+	return logger.WithRequestID(ctx, s.logger, id)
+}
+
+// authenticate checks the API key in the x-api-key metadata,
+// or else the bearer token in the authorization metadata.
+// The returned context carries the claims of the caller, is
+// scoped to its tenant, and its logger identifies the caller.
+func (s *Server) authenticate(
+	ctx context.Context) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	var (
+		claims *auth.Claims
+		err    error
+	)
+
+	if key := first(md, apiKeyMetadata); key != "" {
+		claims, err = s.authn.APIKey(ctx, key)
+		switch {
+		case errors.Is(err, authn.ErrInvalidAPIKey):
+			return ctx, status.Error(codes.Unauthenticated, err.Error())
+		case err != nil:
+			return ctx, fail(ctx, err)
+		}
+	} else {
+		token, err := auth.ParseBearer(first(md, authorizationMetadata))
+		if err != nil {
+			return ctx, status.Error(codes.Unauthenticated, err.Error())
+		}
+
+		claims, err = s.authn.Token(ctx, token)
+		switch {
+		case errors.Is(err, authn.ErrDenylistUnavailable):
+			return ctx, fail(ctx, err)
+		case err != nil:
+			return ctx, status.Error(
+				codes.Unauthenticated, authn.Describe(err))
+		}
+	}
+
+	ctx = persistence.WithTenant(
+		auth.NewContext(ctx, claims), claims.Tenant)
+
+	return logger.NewContext(ctx, logger.FromContext(ctx).With(
+		zap.String("subject", claims.Subject),
+		zap.String("username", claims.Username),
+		zap.String("token_id", claims.ID))), nil
+}
+
+// authorize only lets calls through whose claims grant
+// the permission of the method.
+func authorize(ctx context.Context, fullMethod string) error {
+	permission, ok := methodPermissions[fullMethod]
+	if !ok {
+		return status.Error(codes.PermissionDenied, "method is not allowed")
+	}
+
+	claims, ok := auth.FromContext(ctx)
+	if !ok || !claims.HasPermission(permission) {
+		return status.Error(codes.PermissionDenied,
+			"missing permission "+string(permission))
+	}
+
+	return nil
+}
+
+// logCall logs a finished call. Server errors are logged as
+// errors and client errors as warnings.
+func logCall(
+	ctx context.Context,
+	fullMethod string,
+	latency time.Duration,
+	err error) {
+	code := status.Code(err)
+
+	fields := []zap.Field{
+		zap.String("method", fullMethod),
+		zap.String("code", code.String()),
+		zap.Duration("latency", latency),
+	}
+
+	log := logger.FromContext(ctx)
+
+	switch code {
+	case codes.OK:
+		log.Info("call", fields...)
+	case codes.Internal, codes.Unknown, codes.DataLoss,
+		codes.Unavailable, codes.Unimplemented:
+		log.Error("call", fields...)
+	default:
+		log.Warn("call", fields...)
+	}
+}
+
+// first returns the first value of the metadata key.
+func first(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+
+	return ""
+}
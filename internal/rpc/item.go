@@ -0,0 +1,244 @@
+package rpc
+
+import (
+	"context"
+	"time"
+
+	inventoryv1 "github.com/Salam4nder/inventory/api/inventory/v1"
+	"github.com/Salam4nder/inventory/internal/cache"
+	"github.com/Salam4nder/inventory/internal/persistence"
+	"github.com/Salam4nder/inventory/pkg/logger"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// CreateItem implements inventoryv1.InventoryServiceServer.
+func (s *Server) CreateItem(
+	ctx context.Context,
+	req *inventoryv1.CreateItemRequest) (*inventoryv1.Item, error) {
+	item := persistence.Item{
+		Name:      req.GetName(),
+		Unit:      req.GetUnit(),
+		Amount:    req.GetAmount(),
+		ExpiresAt: fromTimestamp(req.GetExpiresAt()),
+		SKU:       req.GetSku(),
+	}
+
+	var v violations
+	v.checkItem("", item, req.GetExpiresAt())
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	id, err := s.storage.Create(ctx, item)
+	if err != nil {
+		return nil, fail(ctx, err)
+	}
+	item.ID = id
+
+	s.cacheItem(ctx, item)
+	s.publish(ctx, cache.ItemCreated, item)
+
+	return itemToProto(item), nil
+}
+
+// GetItem implements inventoryv1.InventoryServiceServer.
+func (s *Server) GetItem(
+	ctx context.Context,
+	req *inventoryv1.GetItemRequest) (*inventoryv1.Item, error) {
+	var v violations
+	v.checkID("id", req.GetId())
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	cachedItem, err := s.cache.Get(ctx, req.GetId())
+	if err == nil {
+		return itemToProto(cachedItem), nil
+	}
+
+	item, err := s.storage.Read(ctx, req.GetId())
+	if err != nil {
+		return nil, fail(ctx, err)
+	}
+
+	return itemToProto(item), nil
+}
+
+// ListItems implements inventoryv1.InventoryServiceServer.
+// The items are sent while they are read from the database,
+// so the whole inventory is never held in memory.
+func (s *Server) ListItems(
+	req *inventoryv1.ListItemsRequest,
+	stream inventoryv1.InventoryService_ListItemsServer) error {
+	ctx := stream.Context()
+
+	err := s.storage.Iterate(ctx, filterFromProto(req.GetFilter()),
+		func(item persistence.Item) error {
+			return stream.Send(itemToProto(item))
+		})
+	if err != nil {
+		return fail(ctx, err)
+	}
+
+	return nil
+}
+
+// UpdateItem implements inventoryv1.InventoryServiceServer.
+func (s *Server) UpdateItem(
+	ctx context.Context,
+	req *inventoryv1.UpdateItemRequest) (*inventoryv1.Item, error) {
+	item := persistence.Item{
+		Name:      req.GetItem().GetName(),
+		Unit:      req.GetItem().GetUnit(),
+		Amount:    req.GetItem().GetAmount(),
+		ExpiresAt: fromTimestamp(req.GetItem().GetExpiresAt()),
+		SKU:       req.GetItem().GetSku(),
+	}
+
+	var v violations
+	if req.GetItem() == nil {
+		v.add("item", "is required")
+	} else {
+		v.checkID("item.id", req.GetItem().GetId())
+		v.checkItem("item.", item, req.GetItem().GetExpiresAt())
+	}
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	item.ID = uuid.MustParse(req.GetItem().GetId())
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	updatedItem, err := s.storage.Update(ctx, item)
+	if err != nil {
+		return nil, fail(ctx, err)
+	}
+
+	s.cacheItem(ctx, updatedItem)
+	s.publish(ctx, cache.ItemUpdated, updatedItem)
+
+	return itemToProto(updatedItem), nil
+}
+
+// DeleteItem implements inventoryv1.InventoryServiceServer.
+func (s *Server) DeleteItem(
+	ctx context.Context,
+	req *inventoryv1.DeleteItemRequest) (
+	*inventoryv1.DeleteItemResponse, error) {
+	var v violations
+	v.checkID("id", req.GetId())
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if err := s.storage.Delete(ctx, req.GetId()); err != nil {
+		return nil, fail(ctx, err)
+	}
+
+	if err := s.cache.Delete(ctx, req.GetId()); err != nil {
+		logger.FromContext(ctx).Info(err.Error(), zap.Error(err))
+	}
+
+	s.publish(ctx, cache.ItemDeleted,
+		persistence.Item{ID: uuid.MustParse(req.GetId())})
+
+	logger.FromContext(ctx).Info("item deleted",
+		zap.String("item", req.GetId()))
+
+	return &inventoryv1.DeleteItemResponse{}, nil
+}
+
+// AdjustStock implements inventoryv1.InventoryServiceServer.
+func (s *Server) AdjustStock(
+	ctx context.Context,
+	req *inventoryv1.AdjustStockRequest) (*inventoryv1.Item, error) {
+	var v violations
+	v.checkID("id", req.GetId())
+	if req.GetDelta() == 0 {
+		v.add("delta", "must not be zero")
+	}
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	item, err := s.storage.AdjustStock(ctx, req.GetId(), req.GetDelta())
+	if err != nil {
+		return nil, fail(ctx, err)
+	}
+
+	s.cacheItem(ctx, item)
+	s.publish(ctx, cache.ItemUpdated, item)
+
+	return itemToProto(item), nil
+}
+
+// WatchItems implements inventoryv1.InventoryServiceServer.
+// The stream ends with UNAVAILABLE if the events cannot be
+// received from the cache.
+func (s *Server) WatchItems(
+	_ *inventoryv1.WatchItemsRequest,
+	stream inventoryv1.InventoryService_WatchItemsServer) error {
+	ctx := stream.Context()
+
+	events, err := s.cache.WatchItemEvents(ctx)
+	if err != nil {
+		logger.FromContext(ctx).Error(err.Error(), zap.Error(err))
+		return status.Error(codes.Unavailable, "item events are unavailable")
+	}
+
+	for event := range events {
+		if err := stream.Send(eventToProto(event)); err != nil {
+			return err
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return fail(ctx, err)
+	}
+
+	return status.Error(codes.Unavailable, "item events ended")
+}
+
+// cacheItem caches the item for GetItem and the
+// REST API. Failures are logged, the cache is optional.
+func (s *Server) cacheItem(ctx context.Context, item persistence.Item) {
+	if err := s.cache.Set(
+		ctx,
+		item.ID.String(),
+		item,
+		time.Minute*20); err != nil {
+		logger.FromContext(ctx).Error(err.Error(), zap.Error(err))
+	}
+}
+
+// publish passes the change of the item on to the watchers.
+// Failures are logged, the change was made regardless.
+func (s *Server) publish(
+	ctx context.Context,
+	eventType cache.ItemEventType,
+	item persistence.Item) {
+	if err := s.cache.PublishItemEvent(ctx, cache.ItemEvent{
+		Type: eventType,
+		Item: item,
+	}); err != nil {
+		logger.FromContext(ctx).Error(err.Error(), zap.Error(err))
+	}
+}
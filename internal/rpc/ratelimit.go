@@ -0,0 +1,67 @@
+package rpc
+
+import (
+	"context"
+	"time"
+
+	"github.com/Salam4nder/inventory/pkg/auth"
+	"github.com/Salam4nder/inventory/pkg/logger"
+
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// rateLimit counts the call against the bucket the client
+// shares with its requests to the /api routes of the REST
+// API. Exceeding the limit fails with ResourceExhausted and
+// tells when to retry. Calls are let through if the limiter
+// cannot be reached, like requests of the REST API.
+// It must run after authenticate.
+func (s *Server) rateLimit(ctx context.Context) error {
+	limit := s.config.RateLimitAPI
+	if !limit.Enabled() {
+		return nil
+	}
+
+	limitCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+
+	result, err := s.cache.Allow(
+		limitCtx, "api:"+rateLimitClient(ctx), limit)
+	if err != nil {
+		logger.FromContext(ctx).Error(err.Error(), zap.Error(err))
+		return nil
+	}
+
+	if result.Allowed {
+		return nil
+	}
+
+	st := status.New(codes.ResourceExhausted, "rate limit exceeded")
+
+	detailed, err := st.WithDetails(&errdetails.RetryInfo{
+		RetryDelay: durationpb.New(result.RetryAfter),
+	})
+	if err != nil {
+		return st.Err()
+	}
+
+	return detailed.Err()
+}
+
+// rateLimitClient identifies the client of a call by its
+// API key or user, with the same keys as the REST API.
+func rateLimitClient(ctx context.Context) string {
+	claims, _ := auth.FromContext(ctx)
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	if first(md, apiKeyMetadata) != "" {
+		return "apikey:" + claims.Subject
+	}
+
+	return "user:" + claims.Subject
+}
@@ -0,0 +1,100 @@
+package rpc
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+
+	inventoryv1 "github.com/Salam4nder/inventory/api/inventory/v1"
+	"github.com/Salam4nder/inventory/internal/authn"
+	"github.com/Salam4nder/inventory/internal/cache"
+	"github.com/Salam4nder/inventory/internal/config"
+	"github.com/Salam4nder/inventory/internal/persistence"
+	"github.com/Salam4nder/inventory/internal/tracing"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+// Server is the gRPC API of the inventory. It serves the
+// items like the REST API and shares its authentication.
+type Server struct {
+	inventoryv1.UnimplementedInventoryServiceServer
+
+	grpc    *grpc.Server
+	config  config.Server
+	storage persistence.Storage
+	cache   cache.Service
+	authn   *authn.Authenticator
+	logger  *zap.Logger
+
+	// migrated is set once the database is migrated.
+	migrated atomic.Bool
+}
+
+// New creates a new instance of the gRPC server.
+func New(
+	cfg config.Server,
+	store persistence.Storage,
+	cache cache.Service,
+	authn *authn.Authenticator,
+	log *zap.Logger) *Server {
+	s := &Server{
+		config:  cfg,
+		storage: store,
+		cache:   cache,
+		authn:   authn,
+		logger:  log,
+	}
+
+	s.grpc = grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			tracing.GRPCUnary(), s.unaryInterceptor),
+		grpc.ChainStreamInterceptor(
+			tracing.GRPCStream(), s.streamInterceptor))
+	inventoryv1.RegisterInventoryServiceServer(s.grpc, s)
+
+	return s
+}
+
+// MigrationsDone marks the database migrations as finished.
+// Calls fail with Unavailable before.
+func (s *Server) MigrationsDone() {
+	s.migrated.Store(true)
+}
+
+// ListenAndServe serves the API on the configured
+// address until the server is shut down.
+func (s *Server) ListenAndServe() error {
+	listener, err := net.Listen("tcp", s.config.GRPCAddr())
+	if err != nil {
+		return err
+	}
+
+	return s.Serve(listener)
+}
+
+// Serve serves the API on the listener until the
+// server is shut down.
+func (s *Server) Serve(listener net.Listener) error {
+	return s.grpc.Serve(listener)
+}
+
+// Shutdown stops the server once the running calls are
+// finished. Calls still running when ctx is done, such as
+// watch streams, are cancelled.
+func (s *Server) Shutdown(ctx context.Context) {
+	done := make(chan struct{})
+
+	go func() {
+		s.grpc.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		s.grpc.Stop()
+		<-done
+	}
+}
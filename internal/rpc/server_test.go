@@ -0,0 +1,510 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	inventoryv1 "github.com/Salam4nder/inventory/api/inventory/v1"
+	"github.com/Salam4nder/inventory/internal/authn"
+	"github.com/Salam4nder/inventory/internal/cache"
+	"github.com/Salam4nder/inventory/internal/config"
+	"github.com/Salam4nder/inventory/internal/persistence"
+	"github.com/Salam4nder/inventory/pkg/auth"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const testAPIKey = "inv_test"
+
+// storage keeps items in memory and records the tenant of
+// the last call.
+type storage struct {
+	persistence.Storage
+
+	items  map[uuid.UUID]persistence.Item
+	tenant string
+}
+
+func (s *storage) Create(
+	ctx context.Context, item persistence.Item) (uuid.UUID, error) {
+	s.tenant = persistence.TenantFrom(ctx)
+	item.ID = uuid.New()
+	s.items[item.ID] = item
+
+	return item.ID, nil
+}
+
+func (s *storage) Read(
+	ctx context.Context, id string) (persistence.Item, error) {
+	s.tenant = persistence.TenantFrom(ctx)
+
+	item, ok := s.items[uuid.MustParse(id)]
+	if !ok {
+		return persistence.Item{}, persistence.ErrNotFound
+	}
+
+	return item, nil
+}
+
+func (s *storage) Iterate(
+	ctx context.Context,
+	filter persistence.ItemFilter,
+	fn func(persistence.Item) error) error {
+	for _, item := range s.items {
+		if filter.Name != "" && filter.Name != item.Name {
+			continue
+		}
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *storage) Delete(ctx context.Context, id string) error {
+	if _, ok := s.items[uuid.MustParse(id)]; !ok {
+		return persistence.ErrNotFound
+	}
+
+	delete(s.items, uuid.MustParse(id))
+
+	return nil
+}
+
+func (s *storage) AdjustStock(
+	ctx context.Context, id string, delta float64) (persistence.Item, error) {
+	item, ok := s.items[uuid.MustParse(id)]
+	if !ok {
+		return persistence.Item{}, persistence.ErrNotFound
+	}
+
+	if item.Amount+delta < 0 {
+		return persistence.Item{}, persistence.ErrInsufficientStock
+	}
+
+	item.Amount += delta
+	s.items[item.ID] = item
+
+	return item, nil
+}
+
+func (s *storage) UseAPIKey(
+	ctx context.Context, keyHash string) (persistence.APIKey, error) {
+	if keyHash != auth.HashAPIKey(testAPIKey) {
+		return persistence.APIKey{}, persistence.ErrNotFound
+	}
+
+	return persistence.APIKey{
+		ID:       uuid.New(),
+		TenantID: "acme",
+		Name:     "reader",
+		Scopes:   []string{string(auth.PermissionItemsRead)},
+	}, nil
+}
+
+// ReadUserByUsername finds no user, the test key was
+// created by a user of the OIDC provider.
+func (s *storage) ReadUserByUsername(
+	ctx context.Context, username string) (persistence.User, error) {
+	return persistence.User{}, persistence.ErrNotFound
+}
+
+// events is a cache that is always missed and passes the
+// item events on through a channel.
+type events struct {
+	cache.Service
+
+	events chan cache.ItemEvent
+	// calls counts the calls per rate limit key.
+	calls map[string]int
+}
+
+func (e *events) Allow(
+	ctx context.Context,
+	key string,
+	limit config.RateLimit) (cache.RateLimitResult, error) {
+	if e.calls == nil {
+		e.calls = make(map[string]int)
+	}
+	e.calls[key]++
+
+	return cache.RateLimitResult{
+		Allowed:    e.calls[key] <= limit.Requests,
+		RetryAfter: time.Minute,
+	}, nil
+}
+
+func (e *events) Get(
+	ctx context.Context, id string) (persistence.Item, error) {
+	return persistence.Item{}, errors.New("cache miss")
+}
+
+func (e *events) Set(context.Context, string,
+	persistence.Item, time.Duration) error {
+	return nil
+}
+
+func (e *events) Delete(context.Context, string) error {
+	return nil
+}
+
+func (e *events) TokenRevoked(
+	context.Context, string, string, time.Time) (bool, error) {
+	return false, nil
+}
+
+func (e *events) PublishItemEvent(
+	ctx context.Context, event cache.ItemEvent) error {
+	e.events <- event
+	return nil
+}
+
+func (e *events) WatchItemEvents(
+	ctx context.Context) (<-chan cache.ItemEvent, error) {
+	watched := make(chan cache.ItemEvent)
+
+	go func() {
+		defer close(watched)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event := <-e.events:
+				select {
+				case watched <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return watched, nil
+}
+
+type fixture struct {
+	server  *Server
+	client  inventoryv1.InventoryServiceClient
+	storage *storage
+	keys    *auth.KeySet
+}
+
+// setup returns a fixture whose database is migrated.
+func setup(t *testing.T) *fixture {
+	t.Helper()
+
+	f := setupPending(t, config.Server{})
+	f.server.MigrationsDone()
+
+	return f
+}
+
+// setupPending returns a fixture whose database is
+// not migrated yet.
+func setupPending(t *testing.T, cfg config.Server) *fixture {
+	t.Helper()
+
+	keys, err := auth.NewHMACKeySet("secret")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	store := &storage{items: make(map[uuid.UUID]persistence.Item)}
+	cache := &events{events: make(chan cache.ItemEvent, 10)}
+
+	server := New(cfg, store, cache,
+		authn.New(keys, nil, store, cache), zap.NewNop())
+
+	listener := bufconn.Listen(1 << 20)
+	go server.Serve(listener)
+	t.Cleanup(func() { server.Shutdown(context.Background()) })
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(
+			func(ctx context.Context, _ string) (net.Conn, error) {
+				return listener.DialContext(ctx)
+			}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return &fixture{
+		server:  server,
+		client:  inventoryv1.NewInventoryServiceClient(conn),
+		storage: store,
+		keys:    keys,
+	}
+}
+
+// as returns a context with the token of a user of the
+// acme tenant with the given role.
+func (f *fixture) as(t *testing.T, role auth.Role) context.Context {
+	t.Helper()
+
+	token, err := f.keys.NewJWT(time.Minute, auth.Claims{
+		Username:    "user",
+		Tenant:      "acme",
+		Roles:       []auth.Role{role},
+		Permissions: role.Permissions(),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	return metadata.AppendToOutgoingContext(
+		context.Background(), "authorization", "Bearer "+token)
+}
+
+func expectCode(t *testing.T, err error, code codes.Code) {
+	t.Helper()
+
+	if got := status.Code(err); got != code {
+		t.Fatalf("unexpected code %s, want %s: %v", got, code, err)
+	}
+}
+
+func createItemRequest() *inventoryv1.CreateItemRequest {
+	return &inventoryv1.CreateItemRequest{
+		Name:      "flour",
+		Unit:      "kg",
+		Amount:    10,
+		ExpiresAt: timestamppb.New(time.Now().Add(time.Hour)),
+	}
+}
+
+func TestAuthentication(t *testing.T) {
+	f := setup(t)
+
+	tests := []struct {
+		name string
+		md   metadata.MD
+		code codes.Code
+	}{
+		{"no credentials", metadata.MD{}, codes.Unauthenticated},
+		{"malformed token", metadata.Pairs(
+			"authorization", "Bearer token"), codes.Unauthenticated},
+		{"not a bearer token", metadata.Pairs(
+			"authorization", "Basic dXNlcg=="), codes.Unauthenticated},
+		{"unknown api key", metadata.Pairs(
+			"x-api-key", "inv_unknown"), codes.Unauthenticated},
+		{"api key", metadata.Pairs(
+			"x-api-key", testAPIKey), codes.NotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := metadata.NewOutgoingContext(context.Background(), test.md)
+
+			_, err := f.client.GetItem(ctx, &inventoryv1.GetItemRequest{
+				Id: uuid.NewString(),
+			})
+			expectCode(t, err, test.code)
+		})
+	}
+
+	if f.storage.tenant != "acme" {
+		t.Errorf("call is not scoped to the tenant of the key: %q",
+			f.storage.tenant)
+	}
+}
+
+func TestMigrations_Pending(t *testing.T) {
+	f := setupPending(t, config.Server{})
+
+	_, err := f.client.GetItem(f.as(t, auth.RoleViewer),
+		&inventoryv1.GetItemRequest{Id: uuid.NewString()})
+	expectCode(t, err, codes.Unavailable)
+
+	f.server.MigrationsDone()
+
+	_, err = f.client.GetItem(f.as(t, auth.RoleViewer),
+		&inventoryv1.GetItemRequest{Id: uuid.NewString()})
+	expectCode(t, err, codes.NotFound)
+}
+
+func TestRateLimit(t *testing.T) {
+	f := setupPending(t, config.Server{
+		RateLimitAPI: config.RateLimit{Requests: 1, Period: time.Minute},
+	})
+	f.server.MigrationsDone()
+
+	ctx := f.as(t, auth.RoleViewer)
+	request := &inventoryv1.GetItemRequest{Id: uuid.NewString()}
+
+	_, err := f.client.GetItem(ctx, request)
+	expectCode(t, err, codes.NotFound)
+
+	_, err = f.client.GetItem(ctx, request)
+	expectCode(t, err, codes.ResourceExhausted)
+
+	var retry *errdetails.RetryInfo
+	for _, detail := range status.Convert(err).Details() {
+		if info, ok := detail.(*errdetails.RetryInfo); ok {
+			retry = info
+		}
+	}
+
+	if retry == nil || retry.GetRetryDelay().AsDuration() != time.Minute {
+		t.Errorf("unexpected retry info: %v", retry)
+	}
+}
+
+func TestAuthorization(t *testing.T) {
+	f := setup(t)
+
+	_, err := f.client.CreateItem(f.as(t, auth.RoleViewer), createItemRequest())
+	expectCode(t, err, codes.PermissionDenied)
+
+	_, err = f.client.CreateItem(f.as(t, auth.RoleOperator), createItemRequest())
+	expectCode(t, err, codes.OK)
+
+	_, err = f.client.DeleteItem(f.as(t, auth.RoleOperator),
+		&inventoryv1.DeleteItemRequest{Id: uuid.NewString()})
+	expectCode(t, err, codes.PermissionDenied)
+}
+
+func TestCreateItem_Invalid(t *testing.T) {
+	f := setup(t)
+
+	_, err := f.client.CreateItem(f.as(t, auth.RoleAdmin),
+		&inventoryv1.CreateItemRequest{Unit: "kg", Amount: -1})
+	expectCode(t, err, codes.InvalidArgument)
+
+	var fields []string
+	for _, detail := range status.Convert(err).Details() {
+		if badRequest, ok := detail.(*errdetails.BadRequest); ok {
+			for _, violation := range badRequest.GetFieldViolations() {
+				fields = append(fields, violation.GetField())
+			}
+		}
+	}
+
+	want := []string{"name", "amount", "expires_at"}
+	if len(fields) != len(want) {
+		t.Fatalf("unexpected violations: %v", fields)
+	}
+	for idx := range want {
+		if fields[idx] != want[idx] {
+			t.Errorf("unexpected violations: %v", fields)
+		}
+	}
+}
+
+func TestItems(t *testing.T) {
+	f := setup(t)
+	ctx := f.as(t, auth.RoleAdmin)
+
+	created, err := f.client.CreateItem(ctx, createItemRequest())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if f.storage.tenant != "acme" {
+		t.Errorf("call is not scoped to the tenant of the token: %q",
+			f.storage.tenant)
+	}
+
+	item, err := f.client.GetItem(ctx,
+		&inventoryv1.GetItemRequest{Id: created.GetId()})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if item.GetName() != "flour" || item.GetAmount() != 10 {
+		t.Errorf("unexpected item: %v", item)
+	}
+
+	stream, err := f.client.ListItems(ctx, &inventoryv1.ListItemsRequest{
+		Filter: &inventoryv1.ItemFilter{Name: "flour"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var listed int
+	for {
+		_, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		listed++
+	}
+
+	if listed != 1 {
+		t.Errorf("unexpected number of items: %d", listed)
+	}
+
+	adjusted, err := f.client.AdjustStock(ctx, &inventoryv1.AdjustStockRequest{
+		Id: created.GetId(), Delta: -4,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if adjusted.GetAmount() != 6 {
+		t.Errorf("unexpected amount: %v", adjusted.GetAmount())
+	}
+
+	_, err = f.client.AdjustStock(ctx, &inventoryv1.AdjustStockRequest{
+		Id: created.GetId(), Delta: -7,
+	})
+	expectCode(t, err, codes.FailedPrecondition)
+
+	_, err = f.client.DeleteItem(ctx,
+		&inventoryv1.DeleteItemRequest{Id: created.GetId()})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err = f.client.GetItem(ctx,
+		&inventoryv1.GetItemRequest{Id: created.GetId()})
+	expectCode(t, err, codes.NotFound)
+}
+
+func TestWatchItems(t *testing.T) {
+	f := setup(t)
+	ctx := f.as(t, auth.RoleAdmin)
+
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := f.client.WatchItems(watchCtx, &inventoryv1.WatchItemsRequest{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	created, err := f.client.CreateItem(ctx, createItemRequest())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	event, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if event.GetType() != inventoryv1.ItemEvent_TYPE_CREATED ||
+		event.GetItem().GetId() != created.GetId() {
+		t.Errorf("unexpected event: %v", event)
+	}
+}
@@ -0,0 +1,18 @@
+package tracing
+
+import (
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+)
+
+// GRPCUnary returns an interceptor that continues the trace
+// of the caller and starts a server span for every unary call.
+func GRPCUnary() grpc.UnaryServerInterceptor {
+	return otelgrpc.UnaryServerInterceptor()
+}
+
+// GRPCStream returns an interceptor that continues the trace
+// of the caller and starts a server span for every stream.
+func GRPCStream() grpc.StreamServerInterceptor {
+	return otelgrpc.StreamServerInterceptor()
+}
@@ -0,0 +1,81 @@
+package tracing
+
+import (
+	"context"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RedisHook traces every Redis command and pipeline.
+// Only the command names are recorded, not their
+// arguments, which may hold tokens or cached items.
+type RedisHook struct{}
+
+var _ redis.Hook = RedisHook{}
+
+// BeforeProcess implements redis.Hook.
+func (RedisHook) BeforeProcess(
+	ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	ctx, _ = tracer().Start(ctx, "redis "+cmd.Name(),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemRedis,
+			semconv.DBOperation(cmd.Name())))
+
+	return ctx, nil
+}
+
+// AfterProcess implements redis.Hook.
+func (RedisHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	endRedisSpan(ctx, cmd.Err())
+	return nil
+}
+
+// BeforeProcessPipeline implements redis.Hook.
+func (RedisHook) BeforeProcessPipeline(
+	ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	names := make([]string, len(cmds))
+	for idx, cmd := range cmds {
+		names[idx] = cmd.Name()
+	}
+
+	ctx, _ = tracer().Start(ctx, "redis pipeline",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemRedis,
+			semconv.DBOperation(strings.Join(names, " "))))
+
+	return ctx, nil
+}
+
+// AfterProcessPipeline implements redis.Hook.
+func (RedisHook) AfterProcessPipeline(
+	ctx context.Context, cmds []redis.Cmder) error {
+	var err error
+	for _, cmd := range cmds {
+		if cmd.Err() != nil {
+			err = cmd.Err()
+			break
+		}
+	}
+
+	endRedisSpan(ctx, err)
+	return nil
+}
+
+// endRedisSpan ends the span of a command. A missing
+// key is an expected outcome, not an error.
+func endRedisSpan(ctx context.Context, err error) {
+	span := trace.SpanFromContext(ctx)
+
+	if err != nil && err != redis.Nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
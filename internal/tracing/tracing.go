@@ -0,0 +1,93 @@
+package tracing
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/Salam4nder/inventory/internal/config"
+
+	"github.com/XSAM/otelsql"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentation names the tracer of this application.
+const instrumentation = "github.com/Salam4nder/inventory"
+
+// Setup installs the global tracer provider and the W3C
+// trace context propagator. Spans are exported to the
+// configured OTLP endpoint, without an endpoint only the
+// propagator is installed and no spans are recorded.
+// The returned function flushes and stops the exporter.
+func Setup(
+	ctx context.Context,
+	cfg config.Tracing) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{}))
+
+	if cfg.Endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(cfg.Endpoint)}
+	if cfg.Insecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := NewProvider(cfg, sdktrace.WithBatcher(exporter))
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// NewProvider returns a tracer provider for the service
+// that samples by the configured ratio. Spans are passed
+// to the given span processors, tests use a syncer with
+// an in-memory exporter.
+func NewProvider(
+	cfg config.Tracing,
+	opts ...sdktrace.TracerProviderOption) *sdktrace.TracerProvider {
+	opts = append([]sdktrace.TracerProviderOption{
+		sdktrace.WithSampler(sdktrace.ParentBased(
+			sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+		sdktrace.WithResource(resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceName(cfg.ServiceName))),
+	}, opts...)
+
+	return sdktrace.NewTracerProvider(opts...)
+}
+
+// tracer returns the tracer of the global provider. It is
+// looked up on every use, so the provider can be installed
+// after the instrumented components were created.
+func tracer() trace.Tracer {
+	return otel.Tracer(instrumentation)
+}
+
+// OpenDB opens a database whose queries, statements and
+// transactions are traced.
+func OpenDB(driver, dataSource string) (*sql.DB, error) {
+	system := semconv.DBSystemKey.String(driver)
+	if driver == "postgres" {
+		system = semconv.DBSystemPostgreSQL
+	}
+
+	return otelsql.Open(driver, dataSource,
+		otelsql.WithAttributes(system),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{
+			OmitConnResetSession: true,
+			OmitConnectorConnect: true,
+			OmitRows:             true,
+		}))
+}
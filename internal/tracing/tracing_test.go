@@ -0,0 +1,190 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Salam4nder/inventory/internal/config"
+
+	sqlMock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+// setup installs a provider that records spans in memory.
+func setup(t *testing.T, ratio float64) *tracetest.InMemoryExporter {
+	t.Helper()
+
+	if _, err := Setup(context.Background(), config.Tracing{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := NewProvider(
+		config.Tracing{ServiceName: "inventory", SampleRatio: ratio},
+		sdktrace.WithSyncer(exporter))
+
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	return exporter
+}
+
+func serve(handler gin.HandlerFunc, req *http.Request) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(Gin())
+	router.GET("/api/item/:uuid", handler)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	return w
+}
+
+func TestGin_Traceparent(t *testing.T) {
+	exporter := setup(t, 0)
+
+	var handlerSpan trace.SpanContext
+
+	req := httptest.NewRequest(http.MethodGet, "/api/item/42", nil)
+	req.Header.Set("traceparent", traceparent)
+
+	serve(func(c *gin.Context) {
+		handlerSpan = trace.SpanContextFromContext(c.Request.Context())
+		c.Status(http.StatusInternalServerError)
+	}, req)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("unexpected number of spans: %d", len(spans))
+	}
+
+	span := spans[0]
+
+	if span.Name != "GET /api/item/:uuid" {
+		t.Errorf("unexpected name: %s", span.Name)
+	}
+
+	// The parent was sampled, so the span is recorded even
+	// though no new traces are sampled.
+	if got := span.SpanContext.TraceID().String(); got !=
+		"4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("unexpected trace id: %s", got)
+	}
+
+	if span.Parent.SpanID().String() != "00f067aa0ba902b7" {
+		t.Errorf("unexpected parent: %s", span.Parent.SpanID())
+	}
+
+	if handlerSpan.SpanID() != span.SpanContext.SpanID() {
+		t.Errorf("handler does not see the request span")
+	}
+
+	if span.Status.Code != codes.Error {
+		t.Errorf("unexpected status: %v", span.Status.Code)
+	}
+}
+
+func TestGin_SampleRatio(t *testing.T) {
+	exporter := setup(t, 0)
+
+	serve(func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	}, httptest.NewRequest(http.MethodGet, "/api/item/42", nil))
+
+	if spans := exporter.GetSpans(); len(spans) != 0 {
+		t.Errorf("unexpected number of spans: %d", len(spans))
+	}
+}
+
+func TestRedisHook(t *testing.T) {
+	exporter := setup(t, 1)
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "parent")
+
+	hook := RedisHook{}
+
+	get := redis.NewStringCmd(ctx, "get", "tenant:default:item:42")
+	get.SetErr(redis.Nil)
+	cmdCtx, _ := hook.BeforeProcess(ctx, get)
+	hook.AfterProcess(cmdCtx, get)
+
+	set := redis.NewStatusCmd(ctx, "set", "key", "value")
+	set.SetErr(errors.New("connection refused"))
+	cmdCtx, _ = hook.BeforeProcess(ctx, set)
+	hook.AfterProcess(cmdCtx, set)
+
+	parent.End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 3 {
+		t.Fatalf("unexpected number of spans: %d", len(spans))
+	}
+
+	if spans[0].Name != "redis get" || spans[0].Status.Code == codes.Error {
+		t.Errorf("a missing key must not fail the span: %+v", spans[0])
+	}
+
+	if spans[1].Name != "redis set" || spans[1].Status.Code != codes.Error {
+		t.Errorf("unexpected span: %+v", spans[1])
+	}
+
+	if spans[0].Parent.SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("command span is not a child of the caller")
+	}
+}
+
+func TestOpenDB(t *testing.T) {
+	exporter := setup(t, 1)
+
+	dsn := "tracing_test"
+
+	_, mock, err := sqlMock.NewWithDSN(dsn)
+	if err != nil {
+		t.Fatalf(
+			"unexpected error while creating sqlmock: %s",
+			err)
+	}
+
+	db, err := OpenDB("sqlmock", dsn)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec("DELETE FROM inventory").WillReturnResult(
+		sqlMock.NewResult(0, 1))
+
+	if _, err := db.ExecContext(
+		context.Background(), "DELETE FROM inventory"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var found bool
+	for _, span := range exporter.GetSpans() {
+		if span.Name == "sql.conn.exec" {
+			found = true
+		}
+	}
+
+	if !found {
+		t.Errorf("no span for the statement: %+v", exporter.GetSpans())
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
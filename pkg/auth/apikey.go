@@ -0,0 +1,29 @@
+package auth
+
+// apiKeyPrefix marks API keys, so leaked keys are easy
+// to recognise in logs and by secret scanners.
+const apiKeyPrefix = "inv_"
+
+// apiKeyDisplayLength is the number of leading characters
+// of a key that are stored in plain text to identify it.
+const apiKeyDisplayLength = 12
+
+// NewAPIKey returns a new random API key, the prefix that
+// identifies it in listings and the hash of it that is
+// stored server-side.
+func NewAPIKey() (key, prefix, hash string, err error) {
+	token, _, err := NewRefreshToken()
+	if err != nil {
+		return "", "", "", err
+	}
+
+	key = apiKeyPrefix + token
+
+	return key, key[:apiKeyDisplayLength], HashAPIKey(key), nil
+}
+
+// HashAPIKey returns the hex encoded SHA-256 hash of an
+// API key. Keys are random, so a fast hash is enough.
+func HashAPIKey(key string) string {
+	return HashRefreshToken(key)
+}
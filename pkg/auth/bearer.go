@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+)
+
+// Errors of ParseBearer.
+var (
+	// ErrNoCredentials is returned when a request has
+	// no Authorization header.
+	ErrNoCredentials = errors.New("authorization header is missing")
+	// ErrMalformedAuthorization is returned when the
+	// Authorization header is not a bearer token.
+	ErrMalformedAuthorization = errors.New("authorization header is malformed")
+)
+
+// ParseBearer returns the token of a "Bearer <token>"
+// Authorization header value (RFC 6750). The scheme is case
+// insensitive and the token must be a valid b64token.
+func ParseBearer(header string) (string, error) {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return "", ErrNoCredentials
+	}
+
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", ErrMalformedAuthorization
+	}
+
+	token = strings.TrimLeft(token, " ")
+	if !isB64Token(token) {
+		return "", ErrMalformedAuthorization
+	}
+
+	return token, nil
+}
+
+// isB64Token reports whether token matches
+// 1*( ALPHA / DIGIT / "-" / "." / "_" / "~" / "+" / "/" ) *"=".
+func isB64Token(token string) bool {
+	body := strings.TrimRight(token, "=")
+	if body == "" {
+		return false
+	}
+
+	for _, r := range body {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case strings.ContainsRune("-._~+/", r):
+		default:
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+)
+
+func Test_ParseBearer(t *testing.T) {
+	tests := []struct {
+		header    string
+		wantToken string
+		wantErr   error
+	}{
+		{"Bearer abc.def-ghi_jkl", "abc.def-ghi_jkl", nil},
+		{"bearer abc==", "abc==", nil},
+		{"BEARER   abc", "abc", nil},
+		{"", "", ErrNoCredentials},
+		{"abc.def.ghi", "", ErrMalformedAuthorization},
+		{"Basic dXNlcjpwYXNz", "", ErrMalformedAuthorization},
+		{"Bearer", "", ErrMalformedAuthorization},
+		{"Bearer ", "", ErrMalformedAuthorization},
+		{"Bearer abc def", "", ErrMalformedAuthorization},
+		{"Bearer ===", "", ErrMalformedAuthorization},
+	}
+
+	for _, test := range tests {
+		token, err := ParseBearer(test.header)
+		if !errors.Is(err, test.wantErr) || token != test.wantToken {
+			t.Errorf("ParseBearer(%q) = %q, %v, want %q, %v",
+				test.header, token, err, test.wantToken, test.wantErr)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package auth
+
+import "context"
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx that carries the claims
+// of the authenticated caller.
+func NewContext(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, contextKey{}, claims)
+}
+
+// FromContext returns the claims carried by ctx, if any.
+func FromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(contextKey{}).(*Claims)
+	return claims, ok
+}
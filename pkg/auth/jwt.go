@@ -1,25 +1,69 @@
 package auth
 
 import (
-	"fmt"
-	"log"
+	"errors"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
 )
 
-// NewJWT creates a new JWT token and signs it with the given secret.
+func init() {
+	// Issue times are kept to the millisecond, so a token
+	// issued right after the tokens of its subject were
+	// revoked is not taken for one of them.
+	jwt.TimePrecision = time.Millisecond
+}
+
+// Claims are the JWT claims issued by the service.
+// The subject is the ID of the authenticated user and
+// the ID is a unique token ID (jti).
+type Claims struct {
+	jwt.RegisteredClaims
+	Username    string       `json:"username,omitempty"`
+	Tenant      string       `json:"tenant,omitempty"`
+	Roles       []Role       `json:"roles,omitempty"`
+	Permissions []Permission `json:"permissions,omitempty"`
+}
+
+// HasPermission reports whether the claims grant
+// the given permission.
+func (c *Claims) HasPermission(permission Permission) bool {
+	for _, p := range c.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NewJWT creates a new JWT token with the given claims and
+// signs it with the signing key of the set. The expiry and
+// issue time of the claims are set by NewJWT, as is the token
+// ID if the claims do not have one.
 // The signed token is returned as a string.
-func NewJWT(secret string, expiry time.Duration) (string, error) {
-	expiration := &jwt.NumericDate{
-		Time: time.Now().Add(expiry),
+func (s *KeySet) NewJWT(
+	expiry time.Duration, claims Claims) (string, error) {
+	now := time.Now()
+	claims.IssuedAt = jwt.NewNumericDate(now)
+	claims.ExpiresAt = jwt.NewNumericDate(now.Add(expiry))
+	if claims.ID == "" {
+		claims.ID = uuid.NewString()
 	}
-	claims := jwt.RegisteredClaims{
-		ExpiresAt: expiration,
+	if s.issuer != "" {
+		claims.Issuer = s.issuer
+	}
+	if s.audience != "" {
+		claims.Audience = jwt.ClaimStrings{s.audience}
+	}
+
+	token := jwt.NewWithClaims(s.signing.Method, claims)
+	if s.signing.ID != "" {
+		token.Header["kid"] = s.signing.ID
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	signedToken, err := token.SignedString([]byte(secret))
+	signedToken, err := token.SignedString(s.signing.private)
 	if err != nil {
 		return "", err
 	}
@@ -27,24 +71,30 @@ func NewJWT(secret string, expiry time.Duration) (string, error) {
 	return signedToken, nil
 }
 
-// ValidateJWT validates the given JWT token.
-// Returns an error if the token is invalid or expired.
-func ValidateJWT(tokenString, secret string) error {
-	token, err := jwt.Parse(
-		tokenString, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf(
-					"unexpected signing method: %v", token.Header["alg"])
-			}
+// ValidateJWT validates the given JWT token against the keys
+// of the set and returns its claims.
+// Returns an error if the token is invalid or expired. The
+// errors match the jwt.ErrToken errors with errors.Is.
+func (s *KeySet) ValidateJWT(tokenString string) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, s.keyFunc)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(*Claims)
+	if !ok || !token.Valid {
+		return nil, errors.New("invalid token")
+	}
 
-			return []byte(secret), nil
-		})
+	if s.issuer != "" && !claims.VerifyIssuer(s.issuer, true) {
+		return nil, jwt.NewValidationError(
+			"token has invalid issuer", jwt.ValidationErrorIssuer)
+	}
 
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		log.Printf("claims: %v", claims)
-	} else {
-		return err
+	if s.audience != "" && !claims.VerifyAudience(s.audience, true) {
+		return nil, jwt.NewValidationError(
+			"token has invalid audience", jwt.ValidationErrorAudience)
 	}
 
-	return nil
+	return claims, nil
 }
@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func Test_JWT_Round_Trip(t *testing.T) {
+	keys, err := NewHMACKeySet("secret")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	token, err := keys.NewJWT(time.Minute, Claims{
+		Username: "alice",
+		Tenant:   "acme",
+		Roles:    []Role{RoleOperator},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	claims, err := keys.ValidateJWT(token)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if claims.ID == "" {
+		t.Errorf("expected a token ID")
+	}
+
+	if claims.Tenant != "acme" || claims.Username != "alice" {
+		t.Errorf("unexpected claims: %+v", claims)
+	}
+
+	other, _ := NewHMACKeySet("other")
+	if _, err := other.ValidateJWT(token); err == nil {
+		t.Errorf("expected error for wrong secret")
+	}
+}
+
+func Test_Claims_Context(t *testing.T) {
+	if _, ok := FromContext(context.Background()); ok {
+		t.Errorf("unexpected claims in empty context")
+	}
+
+	claims := &Claims{Username: "alice"}
+
+	got, ok := FromContext(NewContext(context.Background(), claims))
+	if !ok || got != claims {
+		t.Errorf("unexpected claims: %+v", got)
+	}
+}
+
+func Test_ValidateJWT_Errors(t *testing.T) {
+	keys, err := NewHMACKeySet("secret")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	keys.SetAudience("inventory", "inventory-api")
+
+	other, _ := NewHMACKeySet("secret")
+	other.SetAudience("inventory", "other-api")
+
+	expired, _ := keys.NewJWT(-time.Minute, Claims{})
+	wrongAudience, _ := other.NewJWT(time.Minute, Claims{})
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{"malformed", "not-a-token", jwt.ErrTokenMalformed},
+		{"expired", expired, jwt.ErrTokenExpired},
+		{"wrong audience", wrongAudience, jwt.ErrTokenInvalidAudience},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := keys.ValidateJWT(test.token); !errors.Is(err, test.wantErr) {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func writeKey(t *testing.T, dir, kid string, key interface{}) {
+	t.Helper()
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+
+	if err := os.WriteFile(
+		filepath.Join(dir, kid+".pem"), data, 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func Test_KeySet_Rotation(t *testing.T) {
+	dir := t.TempDir()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	writeKey(t, dir, "2026-01", rsaKey)
+
+	old, err := LoadKeySet(dir, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	oldToken, err := old.NewJWT(time.Minute, Claims{Username: "alice"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	writeKey(t, dir, "2026-02", edKey)
+
+	rotated, err := LoadKeySet(dir, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if kid := rotated.SigningKey().ID; kid != "2026-02" {
+		t.Errorf("unexpected signing key: %s", kid)
+	}
+
+	newToken, err := rotated.NewJWT(time.Minute, Claims{Username: "bob"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, token := range []string{oldToken, newToken} {
+		if _, err := rotated.ValidateJWT(token); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+
+	if _, err := old.ValidateJWT(newToken); err == nil {
+		t.Errorf("expected error for unknown kid")
+	}
+
+	jwks := rotated.JWKS()
+	if len(jwks.Keys) != 2 ||
+		jwks.Keys[0].KeyType != "RSA" || jwks.Keys[0].Algorithm != "RS256" ||
+		jwks.Keys[1].KeyType != "OKP" || jwks.Keys[1].Algorithm != "EdDSA" {
+		t.Errorf("unexpected JWKS: %+v", jwks)
+	}
+}
+
+func Test_KeySet_Rejects_Algorithm_Confusion(t *testing.T) {
+	dir := t.TempDir()
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	writeKey(t, dir, "ed", edKey)
+
+	keys, err := LoadKeySet(dir, "ed")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// An HS256 token signed with the public key as the
+	// secret must not verify against the Ed25519 key.
+	forger := &KeySet{signing: &Key{
+		ID:      "ed",
+		Method:  jwt.SigningMethodHS256,
+		private: []byte(edKey.Public().(ed25519.PublicKey)),
+	}}
+
+	token, err := forger.NewJWT(time.Minute, Claims{Username: "mallory"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := keys.ValidateJWT(token); err == nil {
+		t.Errorf("expected error for HS256 token")
+	}
+
+	if _, err := LoadKeySet(dir, "missing"); err == nil {
+		t.Errorf("expected error for missing signing key")
+	}
+}
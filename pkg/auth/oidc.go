@@ -0,0 +1,407 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// ErrNoTenant is returned for provider tokens without
+// the tenant claim. They cannot be scoped to a tenant.
+var ErrNoTenant = errors.New("token has no tenant")
+
+// jwksRefreshInterval limits how often the JWKS of an issuer
+// is fetched again when a token names an unknown key.
+const jwksRefreshInterval = time.Minute
+
+// OIDCConfig configures the validation of tokens issued
+// by an external OpenID Connect provider.
+type OIDCConfig struct {
+	// Issuer is the issuer URL, the discovery document is
+	// served below it.
+	Issuer string
+	// Audience must be one of the audiences of a token.
+	Audience string
+	// GroupsClaim is the claim that lists the groups of
+	// the user, "groups" if empty.
+	GroupsClaim string
+	// GroupRoles maps provider groups to roles.
+	GroupRoles map[string]Role
+	// TenantClaim is the claim that names the tenant of
+	// the user, "tenant" if empty. It is required.
+	TenantClaim string
+	// Client fetches the discovery document and the JWKS,
+	// http.DefaultClient if nil.
+	Client *http.Client
+}
+
+// OIDCVerifier validates tokens of an OpenID Connect provider
+// against the keys it publishes. Keys are fetched again when
+// a token is signed with a key that is not known yet.
+type OIDCVerifier struct {
+	config  OIDCConfig
+	jwksURI string
+
+	mu        sync.RWMutex
+	keys      map[string]*Key
+	fetchedAt time.Time
+}
+
+type discoveryDocument struct {
+	Issuer  string `json:"issuer"`
+	JWKSURI string `json:"jwks_uri"`
+}
+
+// NewOIDCVerifier fetches the discovery document and the
+// keys of the configured issuer.
+func NewOIDCVerifier(
+	ctx context.Context, config OIDCConfig) (*OIDCVerifier, error) {
+	if config.Audience == "" {
+		return nil, errors.New("oidc audience is empty")
+	}
+	if config.GroupsClaim == "" {
+		config.GroupsClaim = "groups"
+	}
+	if config.TenantClaim == "" {
+		config.TenantClaim = "tenant"
+	}
+	if config.Client == nil {
+		config.Client = http.DefaultClient
+	}
+
+	var discovery discoveryDocument
+
+	if err := getJSON(ctx, config.Client,
+		strings.TrimSuffix(config.Issuer, "/")+
+			"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, fmt.Errorf("oidc discovery: %w", err)
+	}
+
+	if discovery.Issuer != config.Issuer {
+		return nil, fmt.Errorf(
+			"oidc discovery: issuer %q does not match %q",
+			discovery.Issuer, config.Issuer)
+	}
+
+	if discovery.JWKSURI == "" {
+		return nil, errors.New("oidc discovery: jwks_uri is missing")
+	}
+
+	verifier := &OIDCVerifier{
+		config:  config,
+		jwksURI: discovery.JWKSURI,
+	}
+
+	if err := verifier.refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	return verifier, nil
+}
+
+// Issued reports whether the token claims to be issued by the
+// provider. The signature is not checked.
+func (v *OIDCVerifier) Issued(tokenString string) bool {
+	var claims jwt.RegisteredClaims
+
+	if _, _, err := new(jwt.Parser).ParseUnverified(
+		tokenString, &claims); err != nil {
+		return false
+	}
+
+	return claims.Issuer == v.config.Issuer
+}
+
+// ValidateJWT validates a token of the provider and returns
+// its claims. The groups of the token are mapped to roles,
+// groups without a role are ignored. Tokens without the
+// tenant claim are rejected with ErrNoTenant.
+func (v *OIDCVerifier) ValidateJWT(
+	ctx context.Context, tokenString string) (*Claims, error) {
+	mapClaims := jwt.MapClaims{}
+
+	token, err := jwt.ParseWithClaims(
+		tokenString, mapClaims, func(token *jwt.Token) (interface{}, error) {
+			return v.keyFunc(ctx, token)
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	if !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+
+	if !mapClaims.VerifyIssuer(v.config.Issuer, true) {
+		return nil, jwt.NewValidationError(
+			"token has invalid issuer", jwt.ValidationErrorIssuer)
+	}
+
+	if !mapClaims.VerifyAudience(v.config.Audience, true) {
+		return nil, jwt.NewValidationError(
+			"token has invalid audience", jwt.ValidationErrorAudience)
+	}
+
+	claims := v.claims(mapClaims)
+	if claims.Tenant == "" {
+		return nil, ErrNoTenant
+	}
+
+	return claims, nil
+}
+
+// claims converts the provider claims to our claims.
+func (v *OIDCVerifier) claims(mapClaims jwt.MapClaims) *Claims {
+	claims := &Claims{}
+	claims.Issuer = v.config.Issuer
+	claims.Subject, _ = mapClaims["sub"].(string)
+	claims.ID, _ = mapClaims["jti"].(string)
+	claims.Tenant, _ = mapClaims[v.config.TenantClaim].(string)
+	claims.Audience = jwt.ClaimStrings{v.config.Audience}
+
+	if exp, ok := mapClaims["exp"].(float64); ok {
+		claims.ExpiresAt = jwt.NewNumericDate(time.Unix(int64(exp), 0))
+	}
+	if iat, ok := mapClaims["iat"].(float64); ok {
+		claims.IssuedAt = jwt.NewNumericDate(time.Unix(int64(iat), 0))
+	}
+
+	for _, name := range []string{"preferred_username", "email", "sub"} {
+		if username, _ := mapClaims[name].(string); username != "" {
+			claims.Username = username
+			break
+		}
+	}
+
+	var groups []string
+
+	switch value := mapClaims[v.config.GroupsClaim].(type) {
+	case string:
+		groups = []string{value}
+	case []interface{}:
+		for _, group := range value {
+			if group, ok := group.(string); ok {
+				groups = append(groups, group)
+			}
+		}
+	}
+
+	seen := make(map[Permission]bool)
+
+	for _, group := range groups {
+		role, ok := v.config.GroupRoles[group]
+		if !ok {
+			continue
+		}
+
+		claims.Roles = append(claims.Roles, role)
+
+		for _, permission := range role.Permissions() {
+			if !seen[permission] {
+				seen[permission] = true
+				claims.Permissions = append(claims.Permissions, permission)
+			}
+		}
+	}
+
+	return claims
+}
+
+func (v *OIDCVerifier) keyFunc(
+	ctx context.Context, token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	v.mu.RLock()
+	key, ok := v.keys[kid]
+	stale := time.Since(v.fetchedAt) > jwksRefreshInterval
+	v.mu.RUnlock()
+
+	// The provider may have rotated its keys.
+	if !ok && stale {
+		if err := v.refresh(ctx); err != nil {
+			return nil, err
+		}
+
+		v.mu.RLock()
+		key, ok = v.keys[kid]
+		v.mu.RUnlock()
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf(
+			"unexpected signing method: %v", token.Header["alg"])
+	}
+
+	return key.public, nil
+}
+
+// refresh fetches the JWKS of the provider. Keys that
+// cannot be used are skipped.
+func (v *OIDCVerifier) refresh(ctx context.Context) error {
+	var jwks JWKS
+
+	if err := getJSON(ctx, v.config.Client, v.jwksURI, &jwks); err != nil {
+		return fmt.Errorf("oidc jwks: %w", err)
+	}
+
+	keys := make(map[string]*Key, len(jwks.Keys))
+
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		key, err := jwk.Key()
+		if err != nil {
+			continue
+		}
+
+		keys[key.ID] = key
+	}
+
+	v.mu.Lock()
+	v.keys = keys
+	v.fetchedAt = time.Now()
+	v.mu.Unlock()
+
+	return nil
+}
+
+// Key returns the public key of the JWK.
+func (j JWK) Key() (*Key, error) {
+	key := &Key{ID: j.KeyID}
+
+	switch j.KeyType {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(j.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(j.E)
+		if err != nil {
+			return nil, err
+		}
+
+		key.public = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+		// RSA keys may be used with any RS or PS algorithm.
+		switch method := jwt.GetSigningMethod(j.Algorithm).(type) {
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+			key.Method = method
+		default:
+			key.Method = jwt.SigningMethodRS256
+		}
+	case "EC":
+		var curve elliptic.Curve
+
+		switch j.Curve {
+		case "P-256":
+			curve, key.Method = elliptic.P256(), jwt.SigningMethodES256
+		case "P-384":
+			curve, key.Method = elliptic.P384(), jwt.SigningMethodES384
+		case "P-521":
+			curve, key.Method = elliptic.P521(), jwt.SigningMethodES512
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", j.Curve)
+		}
+
+		x, err := base64.RawURLEncoding.DecodeString(j.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(j.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		key.public = &ecdsa.PublicKey{
+			Curve: curve,
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}
+	case "OKP":
+		if j.Curve != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", j.Curve)
+		}
+
+		x, err := base64.RawURLEncoding.DecodeString(j.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key size")
+		}
+
+		key.public = ed25519.PublicKey(x)
+		key.Method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", j.KeyType)
+	}
+
+	return key, nil
+}
+
+// ParseGroupRoles parses a comma separated list of
+// group=role pairs, such as "admins=admin,staff=viewer".
+func ParseGroupRoles(value string) (map[string]Role, error) {
+	groupRoles := make(map[string]Role)
+
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		group, role, ok := strings.Cut(pair, "=")
+		if !ok || group == "" {
+			return nil, fmt.Errorf("invalid group mapping %q", pair)
+		}
+
+		if !Role(role).Valid() {
+			return nil, fmt.Errorf("unknown role %q", role)
+		}
+
+		groupRoles[group] = Role(role)
+	}
+
+	return groupRoles, nil
+}
+
+func getJSON(
+	ctx context.Context, client *http.Client, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,190 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// testIssuer is a stand-in OIDC provider serving a
+// discovery document and the JWKS of its keys.
+type testIssuer struct {
+	server *httptest.Server
+	keys   map[string]*rsa.PrivateKey
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	t.Helper()
+
+	issuer := &testIssuer{keys: map[string]*rsa.PrivateKey{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration",
+		func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(discoveryDocument{
+				Issuer:  issuer.server.URL,
+				JWKSURI: issuer.server.URL + "/keys",
+			})
+		})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		jwks := JWKS{}
+		for kid, key := range issuer.keys {
+			jwks.Keys = append(jwks.Keys, JWK{
+				KeyType:   "RSA",
+				KeyID:     kid,
+				Use:       "sig",
+				Algorithm: "RS256",
+				N: base64.RawURLEncoding.EncodeToString(
+					key.N.Bytes()),
+				E: base64.RawURLEncoding.EncodeToString(
+					big.NewInt(int64(key.E)).Bytes()),
+			})
+		}
+		_ = json.NewEncoder(w).Encode(jwks)
+	})
+
+	issuer.server = httptest.NewServer(mux)
+	t.Cleanup(issuer.server.Close)
+
+	issuer.addKey(t, "first")
+
+	return issuer
+}
+
+func (i *testIssuer) addKey(t *testing.T, kid string) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	i.keys[kid] = key
+}
+
+func (i *testIssuer) token(
+	t *testing.T, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+
+	signed, err := token.SignedString(i.keys[kid])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	return signed
+}
+
+func Test_OIDCVerifier(t *testing.T) {
+	issuer := newTestIssuer(t)
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(), 5*time.Second)
+	defer cancel()
+
+	verifier, err := NewOIDCVerifier(ctx, OIDCConfig{
+		Issuer:   issuer.server.URL,
+		Audience: "inventory",
+		GroupRoles: map[string]Role{
+			"warehouse": RoleOperator,
+			"it":        RoleAdmin,
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	exp := time.Now().Add(time.Minute).Unix()
+
+	valid := jwt.MapClaims{
+		"iss":                issuer.server.URL,
+		"aud":                []string{"inventory", "other"},
+		"sub":                "user-1",
+		"preferred_username": "alice",
+		"groups":             []string{"warehouse", "unmapped"},
+		"tenant":             "acme",
+		"exp":                exp,
+	}
+
+	token := issuer.token(t, "first", valid)
+
+	if !verifier.Issued(token) {
+		t.Errorf("expected token to be issued by the provider")
+	}
+
+	claims, err := verifier.ValidateJWT(ctx, token)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if claims.Subject != "user-1" || claims.Username != "alice" ||
+		claims.Tenant != "acme" {
+		t.Errorf("unexpected claims: %+v", claims)
+	}
+
+	if !claims.HasPermission(PermissionItemsWrite) ||
+		claims.HasPermission(PermissionItemsDelete) {
+		t.Errorf("unexpected permissions: %v", claims.Permissions)
+	}
+
+	tests := []struct {
+		name   string
+		claims jwt.MapClaims
+	}{
+		{"wrong audience", jwt.MapClaims{
+			"iss": issuer.server.URL, "aud": "other", "exp": exp}},
+		{"wrong issuer", jwt.MapClaims{
+			"iss": "https://evil.example", "aud": "inventory", "exp": exp}},
+		{"expired", jwt.MapClaims{
+			"iss": issuer.server.URL, "aud": "inventory",
+			"exp": time.Now().Add(-time.Minute).Unix()}},
+		{"no tenant", jwt.MapClaims{
+			"iss": issuer.server.URL, "aud": "inventory", "exp": exp}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := verifier.ValidateJWT(
+				ctx, issuer.token(t, "first", test.claims)); err == nil {
+				t.Errorf("expected error")
+			}
+		})
+	}
+
+	// Keys the provider rotates in are fetched on first use.
+	issuer.addKey(t, "second")
+	verifier.fetchedAt = time.Time{}
+
+	if _, err := verifier.ValidateJWT(
+		ctx, issuer.token(t, "second", valid)); err != nil {
+		t.Errorf("unexpected error after rotation: %v", err)
+	}
+}
+
+func Test_ParseGroupRoles(t *testing.T) {
+	groupRoles, err := ParseGroupRoles("admins=admin, staff=viewer")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if groupRoles["admins"] != RoleAdmin || groupRoles["staff"] != RoleViewer {
+		t.Errorf("unexpected mapping: %v", groupRoles)
+	}
+
+	for _, value := range []string{"admins", "admins=root", "=admin"} {
+		if _, err := ParseGroupRoles(value); err == nil {
+			t.Errorf("expected error for %q", value)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Length limits of passwords. bcrypt ignores everything
+// after 72 bytes. The user requests of the HTTP API bind
+// with the same limits.
+const (
+	MinPasswordLength = 8
+	MaxPasswordLength = 72
+)
+
+// ErrPasswordLength is returned for passwords
+// outside of the length limits.
+var ErrPasswordLength = fmt.Errorf(
+	"password must be %d to %d characters long",
+	MinPasswordLength, MaxPasswordLength)
+
+// ValidatePassword returns ErrPasswordLength if the
+// password is too short or too long.
+func ValidatePassword(password string) error {
+	if utf8.RuneCountInString(password) < MinPasswordLength ||
+		len(password) > MaxPasswordLength {
+		return ErrPasswordLength
+	}
+
+	return nil
+}
+
+// HashPassword returns the bcrypt hash of the given password.
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword(
+		[]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+// ComparePassword compares a bcrypt hash with a password.
+// Returns an error if they do not match.
+func ComparePassword(hash, password string) error {
+	return bcrypt.CompareHashAndPassword(
+		[]byte(hash), []byte(password))
+}
@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func Test_ValidatePassword(t *testing.T) {
+	tests := []struct {
+		password string
+		valid    bool
+	}{
+		{"", false},
+		{"short", false},
+		{"12345678", true},
+		{strings.Repeat("a", 72), true},
+		{strings.Repeat("a", 73), false},
+		{"pässwört", true},
+	}
+
+	for _, test := range tests {
+		err := ValidatePassword(test.password)
+		if test.valid && err != nil {
+			t.Errorf("ValidatePassword(%q): unexpected error: %v",
+				test.password, err)
+		}
+		if !test.valid && !errors.Is(err, ErrPasswordLength) {
+			t.Errorf("ValidatePassword(%q) = %v, want %v",
+				test.password, err, ErrPasswordLength)
+		}
+	}
+}
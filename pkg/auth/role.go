@@ -0,0 +1,72 @@
+package auth
+
+// Role is the role of a user. Every role grants a fixed
+// set of permissions.
+type Role string
+
+// Roles, from least to most privileged. Admins manage
+// their own tenant, super admins every tenant.
+const (
+	RoleViewer     Role = "viewer"
+	RoleOperator   Role = "operator"
+	RoleAdmin      Role = "admin"
+	RoleSuperAdmin Role = "superadmin"
+)
+
+// Permission allows a kind of operation on the API.
+type Permission string
+
+// Permissions granted by the roles.
+const (
+	PermissionItemsRead     Permission = "items:read"
+	PermissionItemsWrite    Permission = "items:write"
+	PermissionItemsDelete   Permission = "items:delete"
+	PermissionUsersManage   Permission = "users:manage"
+	PermissionTenantsManage Permission = "tenants:manage"
+)
+
+var rolePermissions = map[Role][]Permission{
+	RoleViewer: {
+		PermissionItemsRead,
+	},
+	RoleOperator: {
+		PermissionItemsRead,
+		PermissionItemsWrite,
+	},
+	RoleAdmin: {
+		PermissionItemsRead,
+		PermissionItemsWrite,
+		PermissionItemsDelete,
+		PermissionUsersManage,
+	},
+	RoleSuperAdmin: {
+		PermissionItemsRead,
+		PermissionItemsWrite,
+		PermissionItemsDelete,
+		PermissionUsersManage,
+		PermissionTenantsManage,
+	},
+}
+
+// Permissions returns the permissions granted by the role.
+// Unknown roles grant no permissions.
+func (r Role) Permissions() []Permission {
+	return rolePermissions[r]
+}
+
+// Grants reports whether the role grants the permission.
+func (r Role) Grants(permission Permission) bool {
+	for _, p := range rolePermissions[r] {
+		if p == permission {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Valid reports whether r is a known role.
+func (r Role) Valid() bool {
+	_, ok := rolePermissions[r]
+	return ok
+}
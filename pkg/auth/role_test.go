@@ -0,0 +1,40 @@
+package auth
+
+import "testing"
+
+func Test_Claims_HasPermission(t *testing.T) {
+	tests := []struct {
+		role       Role
+		permission Permission
+		want       bool
+	}{
+		{RoleViewer, PermissionItemsRead, true},
+		{RoleViewer, PermissionItemsWrite, false},
+		{RoleViewer, PermissionItemsDelete, false},
+		{RoleOperator, PermissionItemsWrite, true},
+		{RoleOperator, PermissionItemsDelete, false},
+		{RoleOperator, PermissionUsersManage, false},
+		{RoleAdmin, PermissionItemsDelete, true},
+		{RoleAdmin, PermissionUsersManage, true},
+		{RoleAdmin, PermissionTenantsManage, false},
+		{RoleSuperAdmin, PermissionTenantsManage, true},
+		{Role("unknown"), PermissionItemsRead, false},
+	}
+
+	for _, test := range tests {
+		claims := Claims{
+			Roles:       []Role{test.role},
+			Permissions: test.role.Permissions(),
+		}
+
+		if got := claims.HasPermission(test.permission); got != test.want {
+			t.Errorf("%s has %s = %v, want %v",
+				test.role, test.permission, got, test.want)
+		}
+
+		if got := test.role.Grants(test.permission); got != test.want {
+			t.Errorf("%s grants %s = %v, want %v",
+				test.role, test.permission, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package logger
+
+import (
+	"context"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestFromContext(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+
+	ctx := NewContext(context.Background(),
+		zap.New(core).With(zap.String("request_id", "abc")))
+
+	FromContext(ctx).Info("hello")
+
+	entries := logs.All()
+	if len(entries) != 1 {
+		t.Fatalf("unexpected number of entries: %d", len(entries))
+	}
+
+	if id := entries[0].ContextMap()["request_id"]; id != "abc" {
+		t.Errorf("unexpected request id: %v", id)
+	}
+
+	if FromContext(context.Background()) == nil {
+		t.Errorf("expected a logger without one in the context")
+	}
+}
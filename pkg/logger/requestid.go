@@ -0,0 +1,48 @@
+package logger
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// validRequestID matches the request IDs accepted from
+// clients and proxies, anything else is replaced.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+type requestIDKey struct{}
+
+// RequestID returns the request ID sent by a client or
+// proxy if it is valid, and a new one otherwise.
+func RequestID(id string) string {
+	if !validRequestID.MatchString(id) {
+		return uuid.NewString()
+	}
+
+	return id
+}
+
+// WithRequestID returns a copy of ctx that carries the request
+// ID, along with a logger that adds it and the trace ID of ctx
+// to every line.
+func WithRequestID(
+	ctx context.Context, logger *zap.Logger, id string) context.Context {
+	fields := []zap.Field{zap.String("request_id", id)}
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		fields = append(fields,
+			zap.String("trace_id", span.TraceID().String()))
+	}
+
+	ctx = context.WithValue(ctx, requestIDKey{}, id)
+
+	return NewContext(ctx, logger.With(fields...))
+}
+
+// RequestIDFrom returns the request ID carried by ctx.
+func RequestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
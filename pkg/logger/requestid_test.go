@@ -0,0 +1,60 @@
+package logger
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		id   string
+		keep bool
+	}{
+		{"abc-123", true},
+		{"trace.span:1_2", true},
+		{strings.Repeat("a", 128), true},
+		{"", false},
+		{strings.Repeat("a", 129), false},
+		{"with space", false},
+		{"line\nbreak", false},
+	}
+
+	for _, test := range tests {
+		got := RequestID(test.id)
+
+		if test.keep && got != test.id {
+			t.Errorf("RequestID(%q) = %q, want it kept", test.id, got)
+		}
+
+		if !test.keep {
+			if _, err := uuid.Parse(got); err != nil {
+				t.Errorf("RequestID(%q) = %q, want a new ID", test.id, got)
+			}
+		}
+	}
+}
+
+func TestWithRequestID(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+
+	ctx := WithRequestID(context.Background(), zap.New(core), "abc")
+
+	if id := RequestIDFrom(ctx); id != "abc" {
+		t.Errorf("unexpected request id: %q", id)
+	}
+
+	FromContext(ctx).Info("hello")
+
+	if id := logs.All()[0].ContextMap()["request_id"]; id != "abc" {
+		t.Errorf("unexpected logged request id: %v", id)
+	}
+
+	if id := RequestIDFrom(context.Background()); id != "" {
+		t.Errorf("unexpected request id without one: %q", id)
+	}
+}
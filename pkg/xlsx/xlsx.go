@@ -0,0 +1,143 @@
+// Package xlsx writes single sheet XLSX workbooks row by
+// row, without holding the sheet in memory.
+package xlsx
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const header = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n"
+
+// Writer streams rows into the first sheet of a workbook.
+type Writer struct {
+	archive *zip.Writer
+	sheet   io.Writer
+	rows    int
+}
+
+// NewWriter writes the workbook parts that precede the
+// sheet data to w and returns a Writer for the rows.
+func NewWriter(w io.Writer, sheetName string) (*Writer, error) {
+	var name strings.Builder
+	if err := xml.EscapeText(&name, []byte(sheetName)); err != nil {
+		return nil, err
+	}
+
+	parts := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+			`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+			`<Default Extension="xml" ContentType="application/xml"/>` +
+			`<Override PartName="/xl/workbook.xml" ` +
+			`ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+			`<Override PartName="/xl/worksheets/sheet1.xml" ` +
+			`ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+			`</Types>`},
+		{"_rels/.rels", `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" ` +
+			`Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" ` +
+			`Target="xl/workbook.xml"/></Relationships>`},
+		{"xl/workbook.xml", `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+			`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets><sheet name="` + name.String() + `" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+		{"xl/_rels/workbook.xml.rels", `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" ` +
+			`Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" ` +
+			`Target="worksheets/sheet1.xml"/></Relationships>`},
+	}
+
+	archive := zip.NewWriter(w)
+
+	for _, part := range parts {
+		file, err := archive.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(
+			file, header+part.content); err != nil {
+			return nil, err
+		}
+	}
+
+	sheet, err := archive.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := io.WriteString(sheet, header+
+		`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`+
+		`<sheetData>`); err != nil {
+		return nil, err
+	}
+
+	return &Writer{archive: archive, sheet: sheet}, nil
+}
+
+// WriteRow appends a row to the sheet. Numbers are written
+// as numeric cells, times as RFC 3339 strings and everything
+// else as inline strings.
+func (w *Writer) WriteRow(cells ...interface{}) error {
+	w.rows++
+
+	var row strings.Builder
+
+	fmt.Fprintf(&row, `<row r="%d">`, w.rows)
+
+	for idx, cell := range cells {
+		ref := column(idx) + strconv.Itoa(w.rows)
+
+		switch value := cell.(type) {
+		case float64:
+			fmt.Fprintf(&row, `<c r="%s"><v>%s</v></c>`,
+				ref, strconv.FormatFloat(value, 'g', -1, 64))
+		case int:
+			fmt.Fprintf(&row, `<c r="%s"><v>%d</v></c>`, ref, value)
+		case time.Time:
+			writeString(&row, ref, value.Format(time.RFC3339))
+		default:
+			writeString(&row, ref, fmt.Sprint(value))
+		}
+	}
+
+	row.WriteString(`</row>`)
+
+	_, err := io.WriteString(w.sheet, row.String())
+	return err
+}
+
+// Close finishes the sheet and the archive. It does not
+// close the underlying writer.
+func (w *Writer) Close() error {
+	if _, err := io.WriteString(
+		w.sheet, `</sheetData></worksheet>`); err != nil {
+		return err
+	}
+
+	return w.archive.Close()
+}
+
+func writeString(row *strings.Builder, ref, value string) {
+	fmt.Fprintf(row, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">`, ref)
+	// strings.Builder never returns an error.
+	_ = xml.EscapeText(row, []byte(value))
+	row.WriteString(`</t></is></c>`)
+}
+
+// column returns the spreadsheet name of the zero based
+// column index, e.g. A, Z, AA.
+func column(idx int) string {
+	name := ""
+	for idx++; idx > 0; idx = (idx - 1) / 26 {
+		name = string(rune('A'+(idx-1)%26)) + name
+	}
+
+	return name
+}
@@ -0,0 +1,82 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func Test_Writer_Writes_Valid_Archive(t *testing.T) {
+	var buffer bytes.Buffer
+
+	writer, err := NewWriter(&buffer, "Inventory")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := writer.WriteRow("name", "amount"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := writer.WriteRow("<tomato & co>", 1.5); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	archive, err := zip.NewReader(
+		bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var sheet string
+
+	for _, file := range archive.File {
+		if file.Name != "xl/worksheets/sheet1.xml" {
+			continue
+		}
+
+		reader, err := file.Open()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		content, err := io.ReadAll(reader)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		sheet = string(content)
+	}
+
+	for _, want := range []string{
+		`<c r="A2" t="inlineStr"><is><t xml:space="preserve">&lt;tomato &amp; co&gt;</t></is></c>`,
+		`<c r="B2"><v>1.5</v></c>`,
+		`</sheetData></worksheet>`,
+	} {
+		if !strings.Contains(sheet, want) {
+			t.Errorf("sheet does not contain %s", want)
+		}
+	}
+}
+
+func Test_column(t *testing.T) {
+	tests := []struct {
+		input int
+		want  string
+	}{
+		{0, "A"},
+		{25, "Z"},
+		{26, "AA"},
+		{701, "ZZ"},
+		{702, "AAA"},
+	}
+
+	for _, test := range tests {
+		if got := column(test.input); got != test.want {
+			t.Errorf("column(%d) = %s, want %s",
+				test.input, got, test.want)
+		}
+	}
+}